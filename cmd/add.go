@@ -11,37 +11,60 @@ var addCmd = &cobra.Command{
 	Use:     "add [url]...",
 	Aliases: []string{"get"},
 	Short:   "Add a new download to the running Surge instance",
-	Long:    `Add one or more URLs to the download queue of a running Surge instance.`,
+	Long: `Add one or more URLs to the download queue of a running Surge instance.
+
+URLs may contain ranges such as [001-120], [a-z] or [0-100:10], and lists
+such as {a,b,c}. Each URL they generate is queued as its own download, and
+--name can build file names from the matched values with #1, #2...
+
+With --if-modified, a file that already exists is downloaded again only if
+it changed on the server, and then replaces the local copy instead of being
+saved as name(1).ext.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Initialize Global State (needed for config/paths)
 		initializeGlobalState()
 
 		batchFile, _ := cmd.Flags().GetString("batch")
 		output, _ := cmd.Flags().GetString("output")
+		name, _ := cmd.Flags().GetString("name")
+		rangeFlag, _ := cmd.Flags().GetString("range")
+		headFlag, _ := cmd.Flags().GetString("head")
+		seeds, _ := cmd.Flags().GetStringArray("seed")
+		zsync, _ := cmd.Flags().GetString("zsync")
+		ifModified, _ := cmd.Flags().GetBool("if-modified")
+		globoff, _ := cmd.Flags().GetBool("globoff")
+		wait, _ := cmd.Flags().GetBool("wait")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
 
-		// Collect URLs
-		var urls []string
-
-		// 1. URLs from args
-		urls = append(urls, args...)
-
-		// 2. URLs from batch file
-		if batchFile != "" {
-			fileUrls, err := readURLsFromFile(batchFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
-				os.Exit(1)
+		opts, err := parseRangeFlags(rangeFlag, headFlag)
+		if err == nil {
+			err = parseDeltaFlags(&opts, seeds, zsync)
+		}
+		if err == nil && ifModified {
+			if opts.RangeStart > 0 || opts.RangeLength > 0 {
+				err = fmt.Errorf("--if-modified cannot be combined with --range or --head")
 			}
-			urls = append(urls, fileUrls...)
+			opts.IfModified = true
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 
-		if len(urls) == 0 {
+		// Collect downloads from args and the batch file
+		entries, err := collectEntries(args, name, batchFile, globoff)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(entries) == 0 {
 			_ = cmd.Help()
 			return
 		}
 
 		// Check if Surge is running
-		port := readActivePort()
+		port := daemonPort()
 		if port == 0 {
 			fmt.Println("Error: Surge is not running.")
 			fmt.Println("Use 'surge <url>' to start Surge with a download.")
@@ -49,11 +72,20 @@ var addCmd = &cobra.Command{
 		}
 
 		// Send downloads to server
-		count := processDownloads(urls, output, port)
+		ids := processEntries(entries, output, port, opts)
+
+		if !wait {
+			if len(ids) > 0 {
+				fmt.Printf("Successfully added %d downloads.\n", len(ids))
+			}
+			return
+		}
 
-		if count > 0 {
-			fmt.Printf("Successfully added %d downloads.\n", count)
+		code := runWait(localService(port), ids, true, timeout)
+		if code == 0 && len(ids) < len(entries) {
+			code = waitExitFailed
 		}
+		os.Exit(code)
 	},
 }
 
@@ -61,4 +93,13 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 	addCmd.Flags().StringP("batch", "b", "", "File containing URLs to download (one per line)")
 	addCmd.Flags().StringP("output", "o", "", "Output directory")
+	addCmd.Flags().String("range", "", "Download only bytes START-END of the file (inclusive, e.g. 1GB-1.5GB)")
+	addCmd.Flags().String("name", "", "Output file name; #1, #2... are replaced by the values matched by URL patterns")
+	addCmd.Flags().String("head", "", "Download only the first SIZE bytes of the file (e.g. 100MB)")
+	addCmd.Flags().StringArray("seed", nil, "Older local copy (file or directory) to reuse matching blocks from (repeatable)")
+	addCmd.Flags().Bool("wait", false, "Wait for the downloads to finish and print their paths, as surge wait does")
+	addCmd.Flags().Duration("timeout", 0, "With --wait, give up after this long (e.g. 30m)")
+	addCmd.Flags().Bool("if-modified", false, "Only download if the remote file changed, replacing the local copy (like wget -N)")
+	addCmd.Flags().Bool("globoff", false, "Take URLs literally instead of expanding [] and {} patterns")
+	addCmd.Flags().String("zsync", "", "zsync control file or block hash list for --seed (URL or path; default: URL + \".zsync\")")
 }
@@ -0,0 +1,766 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// apiPrefix is the root of the versioned REST API
+const apiPrefix = "/api/v1"
+
+// Page size limits for list endpoints
+const (
+	apiDefaultLimit = 100
+	apiMaxLimit     = 1000
+)
+
+// Error codes returned in apiError.Code
+const (
+	errCodeBadRequest       = "bad_request"
+	errCodeUnauthorized     = "unauthorized"
+	errCodeForbidden        = "forbidden"
+	errCodeNotFound         = "not_found"
+	errCodeMethodNotAllowed = "method_not_allowed"
+	errCodeConflict         = "conflict"
+	errCodeTooManyRequests  = "too_many_requests"
+	errCodeInternal         = "internal_error"
+)
+
+// apiError is the body of every /api/v1 error response
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiPage wraps a page of list results
+type apiPage struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// apiQueued is returned when a download is added
+type apiQueued struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // "queued" or "pending_approval"
+}
+
+// apiStreamLink is a signed link for playing one download
+type apiStreamLink struct {
+	URL       string `json:"url"` // Path and query, relative to the daemon's address
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// apiServer holds what the /api/v1 handlers need
+type apiServer struct {
+	defaultOutputDir string
+	service          core.DownloadService
+	pool             *download.WorkerPool // Tells queued downloads from running ones; nil if not local
+	streamKey        string               // Signs stream links; the daemon's root token
+}
+
+// apiParam documents a path or query parameter
+type apiParam struct {
+	name, in, kind, description string
+}
+
+// apiRoute is one operation of the REST API. The route table drives both
+// request routing and the OpenAPI document, so the two cannot drift apart.
+type apiRoute struct {
+	method   string
+	path     string // Relative to apiPrefix, with {id} placeholders
+	summary  string
+	params   []apiParam
+	body     interface{} // Request body example value, used for its schema
+	status   int         // Success status
+	response interface{} // Response example value, used for its schema
+	handle   func(s *apiServer, w http.ResponseWriter, r *http.Request)
+}
+
+var idParam = apiParam{"id", "path", "string", "Download ID"}
+
+var pageParams = []apiParam{
+	{"limit", "query", "integer", "Maximum number of items to return (default 100, max 1000)"},
+	{"offset", "query", "integer", "Number of items to skip"},
+	{"q", "query", "string", "Case-insensitive substring matched against the filename and URL"},
+}
+
+// apiRoutes returns the REST API route table
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			method: http.MethodGet, path: "/downloads", summary: "List downloads",
+			params: append([]apiParam{{"status", "query", "string", "Comma-separated statuses to include (queued, downloading, paused, completed, error)"}}, pageParams...),
+			status: http.StatusOK, response: apiPage{Items: []types.DownloadStatus{}},
+			handle: (*apiServer).listDownloads,
+		},
+		{
+			method: http.MethodPost, path: "/downloads", summary: "Add a download",
+			body:   DownloadRequest{},
+			status: http.StatusCreated, response: apiQueued{},
+			handle: (*apiServer).addDownload,
+		},
+		{
+			method: http.MethodGet, path: "/downloads/{id}", summary: "Get a download",
+			params: []apiParam{idParam},
+			status: http.StatusOK, response: types.DownloadStatus{},
+			handle: (*apiServer).getDownload,
+		},
+		{
+			method: http.MethodDelete, path: "/downloads/{id}", summary: "Cancel and remove a download",
+			params: []apiParam{idParam},
+			status: http.StatusNoContent,
+			handle: (*apiServer).deleteDownload,
+		},
+		{
+			method: http.MethodPost, path: "/downloads/{id}/pause", summary: "Pause a download",
+			params: []apiParam{idParam},
+			status: http.StatusOK, response: types.DownloadStatus{},
+			handle: (*apiServer).pauseDownload,
+		},
+		{
+			method: http.MethodPost, path: "/downloads/{id}/resume", summary: "Resume a download",
+			params: []apiParam{idParam},
+			status: http.StatusOK, response: types.DownloadStatus{},
+			handle: (*apiServer).resumeDownload,
+		},
+		{
+			method: http.MethodGet, path: "/downloads/{id}/stream-link", summary: "Create a short-lived link for playing a download",
+			params: []apiParam{idParam, {"expires", "query", "string", "Link lifetime such as 30m or 6h (default 1h, at most 7d)"}},
+			status: http.StatusOK, response: apiStreamLink{},
+			handle: (*apiServer).createStreamLink,
+		},
+		{
+			method: http.MethodGet, path: "/history", summary: "List completed downloads, newest first",
+			params: append([]apiParam{
+				{"since", "query", "integer", "Only entries completed at or after this Unix time"},
+				{"until", "query", "integer", "Only entries completed before this Unix time"},
+			}, pageParams...),
+			status: http.StatusOK, response: apiPage{Items: []types.DownloadEntry{}},
+			handle: (*apiServer).listHistory,
+		},
+		{
+			method: http.MethodGet, path: "/audit", summary: "List audit log entries, newest first (full scope)",
+			params: append([]apiParam{
+				{"since", "query", "integer", "Only entries at or after this Unix time"},
+				{"until", "query", "integer", "Only entries before this Unix time"},
+				{"actor", "query", "string", "Token name"},
+				{"addr", "query", "string", "Client IP address"},
+				{"action", "query", "string", "add, pause, resume, delete or lockout"},
+			}, pageParams...),
+			status: http.StatusOK, response: apiPage{Items: []state.AuditEntry{}},
+			handle: (*apiServer).listAudit,
+		},
+		{
+			method: http.MethodPost, path: "/settings/reload", summary: "Reload settings from disk after settings.json changed",
+			status: http.StatusNoContent,
+			handle: (*apiServer).reloadSettings,
+		},
+		{
+			method: http.MethodGet, path: "/queue", summary: "Export running and waiting downloads, with their request headers",
+			status: http.StatusOK, response: []queueJob{},
+			handle: (*apiServer).exportQueue,
+		},
+		{
+			method: http.MethodPost, path: "/queue", summary: "Import one download from a surge export",
+			body:   apiImportRequest{},
+			status: http.StatusOK, response: importResult{},
+			handle: (*apiServer).importQueue,
+		},
+		{
+			method: http.MethodGet, path: "/openapi.json", summary: "This OpenAPI document",
+			status: http.StatusOK, response: map[string]interface{}{},
+			handle: func(_ *apiServer, w http.ResponseWriter, _ *http.Request) {
+				writeJSON(w, http.StatusOK, openAPISpec())
+			},
+		},
+	}
+}
+
+// registerAPI adds the /api/v1 routes to mux
+func registerAPI(mux *http.ServeMux, s *apiServer) {
+	byPath := make(map[string][]apiRoute)
+	var paths []string
+	for _, route := range apiRoutes() {
+		if _, ok := byPath[route.path]; !ok {
+			paths = append(paths, route.path)
+		}
+		byPath[route.path] = append(byPath[route.path], route)
+	}
+
+	for _, path := range paths {
+		routes := byPath[path]
+		// Methods are matched here rather than in the pattern so a wrong
+		// method gets a JSON error instead of the mux's plain-text one
+		mux.HandleFunc(apiPrefix+path, func(w http.ResponseWriter, r *http.Request) {
+			var allowed []string
+			for _, route := range routes {
+				if route.method == r.Method {
+					route.handle(s, w, r)
+					return
+				}
+				allowed = append(allowed, route.method)
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		})
+	}
+
+	mux.HandleFunc(apiPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "No such endpoint")
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Error: apiErrorDetail{Code: code, Message: message}})
+}
+
+// errorCodeFor maps an HTTP status to an API error code
+func errorCodeFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return errCodeBadRequest
+	case http.StatusUnauthorized:
+		return errCodeUnauthorized
+	case http.StatusNotFound:
+		return errCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return errCodeMethodNotAllowed
+	case http.StatusForbidden:
+		return errCodeForbidden
+	case http.StatusConflict:
+		return errCodeConflict
+	case http.StatusTooManyRequests:
+		return errCodeTooManyRequests
+	default:
+		return errCodeInternal
+	}
+}
+
+// pageParamsFrom parses limit and offset from the query string
+func pageParamsFrom(r *http.Request) (limit, offset int, err error) {
+	limit = apiDefaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		limit = min(limit, apiMaxLimit)
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// paginate returns the page of items selected by limit and offset
+func paginate[T any](items []T, limit, offset int) apiPage {
+	// offset may be as large as MaxInt, so never add it to limit
+	start := min(offset, len(items))
+	page := items[start : start+min(limit, len(items)-start)]
+	if page == nil {
+		page = []T{}
+	}
+	return apiPage{Items: page, Total: len(items), Limit: limit, Offset: offset}
+}
+
+// matchesQuery reports whether q is a case-insensitive substring of any field
+func matchesQuery(q string, fields ...string) bool {
+	if q == "" {
+		return true
+	}
+	q = strings.ToLower(q)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup fetches a download by the {id} path value, writing a 404 if missing
+func (s *apiServer) lookup(w http.ResponseWriter, r *http.Request) (*types.DownloadStatus, bool) {
+	status, err := s.service.GetStatus(r.PathValue("id"))
+	if err != nil || status == nil {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "Download not found")
+		return nil, false
+	}
+	return status, true
+}
+
+func (s *apiServer) listDownloads(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParamsFrom(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	statuses, err := listStatuses(s.service, s.pool)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Failed to list downloads: "+err.Error())
+		return
+	}
+
+	wanted := make(map[string]bool)
+	for _, st := range strings.Split(r.URL.Query().Get("status"), ",") {
+		if st = strings.TrimSpace(st); st != "" {
+			wanted[st] = true
+		}
+	}
+	q := r.URL.Query().Get("q")
+
+	filtered := make([]types.DownloadStatus, 0, len(statuses))
+	for _, st := range statuses {
+		if len(wanted) > 0 && !wanted[st.Status] {
+			continue
+		}
+		if !matchesQuery(q, st.Filename, st.URL) {
+			continue
+		}
+		filtered = append(filtered, st)
+	}
+	// Oldest first, so pages stay stable as downloads are added
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].AddedAt < filtered[j].AddedAt })
+
+	writeJSON(w, http.StatusOK, paginate(filtered, limit, offset))
+}
+
+func (s *apiServer) addDownload(w http.ResponseWriter, r *http.Request) {
+	var req DownloadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	status, resp, err := queueDownload(req, s.defaultOutputDir, s.service, clientFrom(r.Context()))
+	if err != nil {
+		writeAPIError(w, status, errorCodeFor(status), err.Error())
+		return
+	}
+
+	switch status {
+	case http.StatusConflict:
+		writeAPIError(w, status, errCodeConflict, resp["message"])
+	case http.StatusAccepted:
+		writeJSON(w, status, apiQueued{ID: resp["id"], Status: resp["status"]})
+	default:
+		w.Header().Set("Location", apiPrefix+"/downloads/"+resp["id"])
+		writeJSON(w, http.StatusCreated, apiQueued{ID: resp["id"], Status: resp["status"]})
+	}
+}
+
+func (s *apiServer) getDownload(w http.ResponseWriter, r *http.Request) {
+	if status, ok := s.lookup(w, r); ok {
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+func (s *apiServer) createStreamLink(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	ttl := streamLinkTTL
+	if v := r.URL.Query().Get("expires"); v != "" {
+		d, err := parseExpiry(v)
+		if err != nil || d > streamLinkMaxTTL {
+			writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, fmt.Sprintf("invalid expires %q (at most 7d)", v))
+			return
+		}
+		ttl = d
+	}
+	if s.streamKey == "" {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Stream links are not available")
+		return
+	}
+	expires := time.Now().Add(ttl).Unix()
+	writeJSON(w, http.StatusOK, apiStreamLink{URL: signedStreamPath(s.streamKey, status.ID, expires), ExpiresAt: expires})
+}
+
+func (s *apiServer) deleteDownload(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.lookup(w, r); !ok {
+		return
+	}
+	if err := auditedChange(s.service, clientFrom(r.Context()), auditDelete, r.PathValue("id"), s.service.Delete); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *apiServer) pauseDownload(w http.ResponseWriter, r *http.Request) {
+	s.changeState(w, r, auditPause, s.service.Pause)
+}
+
+func (s *apiServer) resumeDownload(w http.ResponseWriter, r *http.Request) {
+	s.changeState(w, r, auditResume, s.service.Resume)
+}
+
+// settingsReloader is a service that caches settings
+type settingsReloader interface {
+	ReloadSettings() error
+}
+
+func (s *apiServer) reloadSettings(w http.ResponseWriter, r *http.Request) {
+	reloader, ok := s.service.(settingsReloader)
+	if !ok {
+		writeAPIError(w, http.StatusNotImplemented, errCodeInternal, "This service does not cache settings")
+		return
+	}
+	if err := reloader.ReloadSettings(); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Failed to reload settings: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queueExporter is a service that can list the configs of its unpaused downloads
+type queueExporter interface {
+	ActiveConfigs() []types.DownloadConfig
+}
+
+func (s *apiServer) exportQueue(w http.ResponseWriter, r *http.Request) {
+	exporter, ok := s.service.(queueExporter)
+	if !ok {
+		writeAPIError(w, http.StatusNotImplemented, errCodeInternal, "This service cannot export its queue")
+		return
+	}
+	jobs := []queueJob{}
+	for _, cfg := range exporter.ActiveConfigs() {
+		job := jobFromConfig(cfg)
+		if status, err := s.service.GetStatus(cfg.ID); err == nil {
+			job.Status = status.Status
+		}
+		jobs = append(jobs, job)
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// apiImportRequest is one job from a surge export, as sent by surge import
+type apiImportRequest struct {
+	Job   queueJob `json:"job"`
+	Dir   string   `json:"dir,omitempty"`   // Replaces the exported directory
+	Fresh bool     `json:"fresh,omitempty"` // Ignore saved progress
+}
+
+func (s *apiServer) importQueue(w http.ResponseWriter, r *http.Request) {
+	var req apiImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	job := req.Job
+	if job.URL == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "URL is required")
+		return
+	}
+	if strings.Contains(job.Filename, "..") || strings.ContainsAny(job.Filename, `/\`) {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "Invalid filename")
+		return
+	}
+	dir := req.Dir
+	if dir == "" {
+		dir = job.Dir
+	}
+	if !filepath.IsAbs(dir) || strings.Contains(dir, "..") {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "The destination directory must be an absolute path")
+		return
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Failed to load settings: "+err.Error())
+		return
+	}
+	client := clientFrom(r.Context())
+	if !dirAllowed(dir, settings.General.AllowedDownloadDirs) || !client.allowsDir(dir) {
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, fmt.Sprintf("%s is outside the allowed download directories", dir))
+		return
+	}
+
+	res, err := importQueueJob(s.service, job, dir, req.Fresh)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+		return
+	}
+	if !res.Skipped {
+		recordAudit(client, auditAdd, res.ID, job.URL, dir+" (import)")
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// changeState applies a pause or resume and returns the updated download
+func (s *apiServer) changeState(w http.ResponseWriter, r *http.Request, action string, apply func(string) error) {
+	if _, ok := s.lookup(w, r); !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if err := auditedChange(s.service, clientFrom(r.Context()), action, id, apply); err != nil {
+		// The download exists, so a failure means it is in the wrong state
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	}
+	if status, ok := s.lookup(w, r); ok {
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+func (s *apiServer) listHistory(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParamsFrom(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	var since, until int64
+	for name, dest := range map[string]*int64{"since": &since, "until": &until} {
+		if raw := r.URL.Query().Get(name); raw != "" {
+			if *dest, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, name+" must be a Unix timestamp")
+				return
+			}
+		}
+	}
+
+	history, err := s.service.History()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Failed to retrieve history: "+err.Error())
+		return
+	}
+
+	q := r.URL.Query().Get("q")
+	filtered := make([]types.DownloadEntry, 0, len(history))
+	for _, e := range history {
+		if since > 0 && e.CompletedAt < since {
+			continue
+		}
+		if until > 0 && e.CompletedAt >= until {
+			continue
+		}
+		if !matchesQuery(q, e.Filename, e.URL) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].CompletedAt > filtered[j].CompletedAt })
+
+	writeJSON(w, http.StatusOK, paginate(filtered, limit, offset))
+}
+
+// openAPISpec builds the OpenAPI 3 document for the route table
+func openAPISpec() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": schemaOf(reflect.TypeOf(apiError{})),
+	}
+	ref := func(v interface{}) map[string]interface{} {
+		t := reflect.TypeOf(v)
+		if t.Kind() == reflect.Map {
+			return map[string]interface{}{"type": "object"}
+		}
+		if t == reflect.TypeOf(apiPage{}) {
+			// Describe the page with its concrete item type
+			items := reflect.TypeOf(v.(apiPage).Items).Elem()
+			schema := schemaOf(t)
+			schema["properties"].(map[string]interface{})["items"] = map[string]interface{}{
+				"type": "array", "items": refTo(items, schemas),
+			}
+			return schema
+		}
+		return refTo(t, schemas)
+	}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+
+	paths := make(map[string]interface{})
+	for _, route := range apiRoutes() {
+		op := map[string]interface{}{
+			"summary":     route.summary,
+			"operationId": operationID(route),
+		}
+
+		var params []map[string]interface{}
+		for _, p := range route.params {
+			params = append(params, map[string]interface{}{
+				"name": p.name, "in": p.in, "required": p.in == "path",
+				"description": p.description, "schema": map[string]interface{}{"type": p.kind},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if route.body != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": ref(route.body)},
+				},
+			}
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(route.status)}
+		if route.response != nil {
+			success["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": ref(route.response)},
+			}
+		}
+		op["responses"] = map[string]interface{}{
+			strconv.Itoa(route.status): success,
+			"default":                  errorResponse,
+		}
+
+		item, _ := paths[route.path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Surge API",
+			"version": Version,
+		},
+		"servers":  []map[string]string{{"url": apiPrefix}},
+		"security": []map[string][]string{{"bearerAuth": {}}},
+		"paths":    paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// operationID derives a stable operation name such as "getDownloadsId"
+func operationID(route apiRoute) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.method))
+	for _, part := range strings.FieldsFunc(route.path, func(r rune) bool { return r == '/' || r == '.' }) {
+		part = strings.Trim(part, "{}")
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// refTo registers the schema for a named struct type and returns a reference to it
+func refTo(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t.Kind() != reflect.Struct {
+		return schemaOf(t)
+	}
+	name := t.Name()
+	if _, ok := schemas[name]; !ok {
+		schemas[name] = schemaOf(t)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaOf derives a JSON schema from a Go type using its json tags
+func schemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = schemaOf(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": props}
+		if required != nil {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (s *apiServer) listAudit(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParamsFrom(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	filter := state.AuditFilter{Actor: q.Get("actor"), Addr: q.Get("addr"), Action: q.Get("action")}
+	for name, dest := range map[string]*int64{"since": &filter.Since, "until": &filter.Until} {
+		if raw := q.Get(name); raw != "" {
+			if *dest, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, name+" must be a Unix timestamp")
+				return
+			}
+		}
+	}
+
+	entries, err := state.ListAudit(filter)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Failed to read audit log: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, paginate(entries, limit, offset))
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	setupIsolatedCmdState(t)
+
+	entries := []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/alpha.iso", Filename: "alpha.iso", DestPath: "/tmp/alpha.iso", Status: "completed", CompletedAt: 100},
+		{ID: "b", URL: "https://example.com/beta.iso", Filename: "beta.iso", DestPath: "/tmp/beta.iso", Status: "completed", CompletedAt: 300},
+		{ID: "c", URL: "https://example.com/gamma.zip", Filename: "gamma.zip", DestPath: "/tmp/gamma.zip", Status: "paused"},
+	}
+	for _, e := range entries {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("Failed to seed download: %v", err)
+		}
+	}
+
+	pool := download.NewWorkerPool(nil, 1)
+	svc := core.NewLocalDownloadService(pool)
+	mux := http.NewServeMux()
+	registerAPI(mux, &apiServer{defaultOutputDir: t.TempDir(), service: svc, pool: pool, streamKey: "secret"})
+	server := httptest.NewServer(authMiddleware("secret", newAuthThrottle(), mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func apiCall(t *testing.T, server *httptest.Server, method, path, body string, out interface{}) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, server.URL+apiPrefix+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestAPI_ListQueuedThroughPool(t *testing.T) {
+	setupIsolatedCmdState(t)
+	pool := download.NewWorkerPool(nil, 1)
+	running, waiting := queueBehindStalledDownload(t, pool)
+
+	mux := http.NewServeMux()
+	registerAPI(mux, &apiServer{defaultOutputDir: t.TempDir(), service: core.NewLocalDownloadService(pool), pool: pool, streamKey: "secret"})
+	server := httptest.NewServer(authMiddleware("secret", newAuthThrottle(), mux))
+	t.Cleanup(server.Close)
+
+	for status, want := range map[string]string{"queued": waiting, "downloading": running} {
+		var page struct {
+			Items []types.DownloadStatus `json:"items"`
+		}
+		resp := apiCall(t, server, http.MethodGet, "/downloads?status="+status, "", &page)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status=%s: status %d", status, resp.StatusCode)
+		}
+		if len(page.Items) != 1 || page.Items[0].ID != want || page.Items[0].Status != status {
+			t.Errorf("status=%s: got %+v, want only %s", status, page.Items, want)
+		}
+	}
+}
+
+func TestAPI_ListFilteringAndPagination(t *testing.T) {
+	server := newTestAPI(t)
+
+	tests := []struct {
+		query   string
+		wantIDs []string
+		total   int
+	}{
+		{"", []string{"a", "b", "c"}, 3},
+		{"?status=paused", []string{"c"}, 1},
+		{"?status=completed,paused&q=ISO", []string{"a", "b"}, 2},
+		{"?limit=1&offset=1", []string{"b"}, 3},
+		{"?offset=10", []string{}, 3},
+		{"?offset=9223372036854775807", []string{}, 3},
+	}
+	for _, tt := range tests {
+		var page struct {
+			Items []types.DownloadStatus `json:"items"`
+			Total int                    `json:"total"`
+		}
+		resp := apiCall(t, server, http.MethodGet, "/downloads"+tt.query, "", &page)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.query, resp.StatusCode)
+		}
+		ids := []string{}
+		for _, s := range page.Items {
+			ids = append(ids, s.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") || page.Total != tt.total {
+			t.Errorf("%s: got %v (total %d), want %v (total %d)", tt.query, ids, page.Total, tt.wantIDs, tt.total)
+		}
+	}
+
+	var apiErr apiError
+	resp := apiCall(t, server, http.MethodGet, "/downloads?limit=0", "", &apiErr)
+	if resp.StatusCode != http.StatusBadRequest || apiErr.Error.Code != errCodeBadRequest {
+		t.Errorf("Expected bad_request, got %d %+v", resp.StatusCode, apiErr)
+	}
+
+	var history struct {
+		Items []types.DownloadEntry `json:"items"`
+	}
+	apiCall(t, server, http.MethodGet, "/history?since=50", "", &history)
+	if len(history.Items) != 2 || history.Items[0].ID != "b" {
+		t.Errorf("Expected newest-first history, got %+v", history.Items)
+	}
+	apiCall(t, server, http.MethodGet, "/history?until=200", "", &history)
+	if len(history.Items) != 1 || history.Items[0].ID != "a" {
+		t.Errorf("Expected only alpha before 200, got %+v", history.Items)
+	}
+}
+
+func TestAPI_ResourcesAndErrors(t *testing.T) {
+	server := newTestAPI(t)
+
+	var status types.DownloadStatus
+	if resp := apiCall(t, server, http.MethodGet, "/downloads/c", "", &status); resp.StatusCode != http.StatusOK || status.Filename != "gamma.zip" {
+		t.Errorf("GET download: %d %+v", resp.StatusCode, status)
+	}
+
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+		wantCode           string
+	}{
+		{http.MethodGet, "/downloads/missing", "", http.StatusNotFound, errCodeNotFound},
+		{http.MethodPost, "/downloads/missing/pause", "", http.StatusNotFound, errCodeNotFound},
+		{http.MethodPost, "/downloads", "{", http.StatusBadRequest, errCodeBadRequest},
+		{http.MethodPost, "/downloads", `{"filename":"x"}`, http.StatusBadRequest, errCodeBadRequest},
+		{http.MethodPut, "/downloads", "", http.StatusMethodNotAllowed, errCodeMethodNotAllowed},
+		{http.MethodGet, "/nope", "", http.StatusNotFound, errCodeNotFound},
+	}
+	for _, tt := range tests {
+		var apiErr apiError
+		resp := apiCall(t, server, tt.method, tt.path, tt.body, &apiErr)
+		if resp.StatusCode != tt.wantStatus || apiErr.Error.Code != tt.wantCode || apiErr.Error.Message == "" {
+			t.Errorf("%s %s: got %d %+v, want %d %s", tt.method, tt.path, resp.StatusCode, apiErr, tt.wantStatus, tt.wantCode)
+		}
+		if tt.wantStatus == http.StatusMethodNotAllowed && resp.Header.Get("Allow") != "GET, POST" {
+			t.Errorf("Unexpected Allow header %q", resp.Header.Get("Allow"))
+		}
+	}
+
+	if resp := apiCall(t, server, http.MethodDelete, "/downloads/c", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE: status %d", resp.StatusCode)
+	}
+	if resp := apiCall(t, server, http.MethodGet, "/downloads/c", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Deleted download still found: %d", resp.StatusCode)
+	}
+
+	// Unauthenticated API requests get a JSON error too
+	resp, err := http.Get(server.URL + apiPrefix + "/downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var apiErr apiError
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || resp.StatusCode != http.StatusUnauthorized || apiErr.Error.Code != errCodeUnauthorized {
+		t.Errorf("Expected JSON 401, got %d %+v (%v)", resp.StatusCode, apiErr, err)
+	}
+}
+
+func TestAPI_StreamLink(t *testing.T) {
+	server := newTestAPI(t)
+
+	var link apiStreamLink
+	if resp := apiCall(t, server, http.MethodGet, "/downloads/a/stream-link?expires=10m", "", &link); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(link.URL, "/stream/a?") || strings.Contains(link.URL, "secret") {
+		t.Errorf("Unexpected link %q", link.URL)
+	}
+	if d := time.Until(time.Unix(link.ExpiresAt, 0)); d <= 9*time.Minute || d > 10*time.Minute {
+		t.Errorf("Link expires in %v, want 10m", d)
+	}
+
+	// The link authenticates without a token; the test mux has no /stream/ handler
+	resp, err := http.Get(server.URL + link.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Signed link: status %d, want 404 from the mux", resp.StatusCode)
+	}
+
+	if resp := apiCall(t, server, http.MethodGet, "/downloads/a/stream-link?expires=30d", "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Overlong expiry: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestAPI_OpenAPISpec(t *testing.T) {
+	server := newTestAPI(t)
+
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	apiCall(t, server, http.MethodGet, "/openapi.json", "", &spec)
+
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("Unexpected version %q", spec.OpenAPI)
+	}
+	for _, route := range apiRoutes() {
+		if _, ok := spec.Paths[route.path][strings.ToLower(route.method)]; !ok {
+			t.Errorf("Spec is missing %s %s", route.method, route.path)
+		}
+	}
+	if op := spec.Paths["/downloads/{id}"]["get"]; op["operationId"] != "getDownloadsId" {
+		t.Errorf("Unexpected operationId %v", op["operationId"])
+	}
+	props, _ := spec.Components.Schemas["DownloadStatus"]["properties"].(map[string]interface{})
+	if _, ok := props["total_size"]; !ok {
+		t.Errorf("DownloadStatus schema missing json fields: %v", props)
+	}
+	if _, ok := spec.Components.Schemas["DownloadRequest"]; !ok {
+		t.Error("Request body schema not registered")
+	}
+}
+
+func TestAPI_UnreadableSettingsRejectAdds(t *testing.T) {
+	server := newTestAPI(t)
+	dir := t.TempDir()
+
+	// With the settings unreadable, the allowed directories are unknown
+	if err := os.MkdirAll(filepath.Dir(config.GetSettingsPath()), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.GetSettingsPath(), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := map[string]string{
+		"/downloads": `{"url":"https://example.com/a.bin","path":"` + dir + `"}`,
+		"/queue":     `{"job":{"id":"new","url":"https://example.com/a.bin"},"dir":"` + dir + `"}`,
+	}
+	for path, body := range requests {
+		var apiErr apiError
+		resp := apiCall(t, server, http.MethodPost, path, body, &apiErr)
+		if resp.StatusCode != http.StatusInternalServerError || apiErr.Error.Code != errCodeInternal {
+			t.Errorf("POST %s: got %d %+v, want 500", path, resp.StatusCode, apiErr)
+		}
+	}
+}
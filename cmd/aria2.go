@@ -0,0 +1,581 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// aria2Version is reported by aria2.getVersion. Clients use it for feature checks,
+// so it names the aria2 release whose RPC behaviour is emulated.
+const aria2Version = "1.37.0"
+
+// JSON-RPC error codes. aria2 reports its own failures with code 1.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcAria2Error     = 1
+)
+
+// aria2Ignored lists aria2 options that Surge accepts but manages itself
+// (connection counts and splitting are chosen per download automatically)
+var aria2Ignored = map[string]bool{
+	"split":                     true,
+	"max-connection-per-server": true,
+	"min-split-size":            true,
+	"continue":                  true,
+	"allow-overwrite":           true,
+	"auto-file-renaming":        true,
+}
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// aria2RPC serves the aria2 JSON-RPC subset on top of a DownloadService
+type aria2RPC struct {
+	token            string
+	throttle         *authThrottle
+	defaultOutputDir string
+	service          core.DownloadService
+	pool             *download.WorkerPool
+}
+
+// handleAria2 serves POST /jsonrpc, accepting single calls and batches. Calls
+// authenticate with the API token, either as a Bearer header or as aria2's
+// "token:<secret>" first parameter.
+func handleAria2(w http.ResponseWriter, r *http.Request, rpc *aria2RPC) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "Parse error"}})
+		return
+	}
+	caller := aria2Caller{addr: clientIP(r)}
+	if wait := rpc.throttle.lockedFor(caller.addr); wait > 0 {
+		writeLockedOut(w, r, wait)
+		return
+	}
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		if client := resolveToken(strings.TrimPrefix(authHeader, "Bearer "), rpc.token); client != nil {
+			caller.client = client.from(caller.addr)
+		} else {
+			authFailed(rpc.throttle, caller.addr)
+		}
+	}
+
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "Parse error"}})
+			return
+		}
+		responses := make([]rpcResponse, len(reqs))
+		for i, req := range reqs {
+			responses[i] = rpc.call(req, caller)
+		}
+		writeRPC(w, responses)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcInvalidRequest, "Invalid Request"}})
+		return
+	}
+	writeRPC(w, rpc.call(req, caller))
+}
+
+func writeRPC(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json-rpc")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
+	}
+}
+
+// call authenticates and dispatches one request
+func (rpc *aria2RPC) call(req rpcRequest, caller aria2Caller) rpcResponse {
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	if req.Method == "" {
+		resp.Error = &rpcError{rpcInvalidRequest, "Invalid Request"}
+		return resp
+	}
+
+	result, err := rpc.dispatch(req.Method, req.Params, caller)
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{rpcAria2Error, err.Error()}
+		}
+		resp.Error = rerr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// aria2Caller is who sent an RPC request: the client named by its
+// Authorization header, if any, and its address
+type aria2Caller struct {
+	client *apiClient
+	addr   string
+}
+
+// authenticate strips aria2's "token:<secret>" parameter, if any, and returns
+// the client it identifies, falling back to the Authorization header's client.
+// Batches and multicalls carry a token per call, so the lockout is checked
+// before each one rather than once per HTTP request.
+func (rpc *aria2RPC) authenticate(params []json.RawMessage, caller aria2Caller) ([]json.RawMessage, *apiClient, error) {
+	if len(params) > 0 {
+		var first string
+		if json.Unmarshal(params[0], &first) == nil && strings.HasPrefix(first, "token:") {
+			if rpc.throttle.lockedFor(caller.addr) > 0 {
+				return nil, nil, &rpcError{rpcAria2Error, "Too many failed authentication attempts; try again later"}
+			}
+			client := resolveToken(strings.TrimPrefix(first, "token:"), rpc.token)
+			if client == nil {
+				authFailed(rpc.throttle, caller.addr)
+				return nil, nil, &rpcError{rpcAria2Error, "Unauthorized"}
+			}
+			return params[1:], client.from(caller.addr), nil
+		}
+	}
+	if caller.client == nil {
+		return nil, nil, &rpcError{rpcAria2Error, "Unauthorized"}
+	}
+	return params, caller.client, nil
+}
+
+// aria2MethodScope returns the token scope an aria2 method needs
+func aria2MethodScope(method string) string {
+	switch method {
+	case "aria2.addUri":
+		return scopeAdd
+	case "aria2.tellStatus", "aria2.tellActive", "aria2.tellWaiting", "aria2.tellStopped",
+		"aria2.getGlobalStat", "aria2.getGlobalOption", "aria2.getVersion":
+		return scopeRead
+	default:
+		return scopeFull
+	}
+}
+
+func (rpc *aria2RPC) dispatch(method string, params []json.RawMessage, caller aria2Caller) (interface{}, error) {
+	// Introspection needs no token, as in aria2
+	if method == "system.listMethods" {
+		return aria2Methods, nil
+	}
+
+	if method == "system.multicall" {
+		return rpc.multicall(params, caller)
+	}
+
+	params, client, err := rpc.authenticate(params, caller)
+	if err != nil {
+		return nil, err
+	}
+	if !client.allows(aria2MethodScope(method)) {
+		return nil, &rpcError{rpcAria2Error, "Forbidden: token scope does not allow " + method}
+	}
+
+	switch method {
+	case "aria2.addUri":
+		return rpc.addURI(params, client)
+	case "aria2.tellStatus":
+		var gid string
+		var keys []string
+		if err := bindParams(params, &gid, &keys); err != nil {
+			return nil, err
+		}
+		status, err := rpc.status(gid)
+		if err != nil {
+			return nil, err
+		}
+		return filterKeys(aria2Status(status), keys), nil
+	case "aria2.tellActive":
+		var keys []string
+		if err := bindParams(params, &keys); err != nil {
+			return nil, err
+		}
+		return rpc.tell(func(s string) bool { return s == "active" }, 0, -1, keys)
+	case "aria2.tellWaiting", "aria2.tellStopped":
+		var offset, num int
+		var keys []string
+		if err := bindParams(params, &offset, &num, &keys); err != nil {
+			return nil, err
+		}
+		match := func(s string) bool { return s == "waiting" || s == "paused" }
+		if method == "aria2.tellStopped" {
+			match = func(s string) bool { return s == "complete" || s == "error" }
+		}
+		return rpc.tell(match, offset, num, keys)
+	case "aria2.pause", "aria2.forcePause", "aria2.unpause", "aria2.remove", "aria2.forceRemove", "aria2.removeDownloadResult":
+		var gid string
+		if err := bindParams(params, &gid); err != nil {
+			return nil, err
+		}
+		if gid == "" {
+			return nil, &rpcError{rpcInvalidParams, "gid is required"}
+		}
+		switch method {
+		case "aria2.pause", "aria2.forcePause":
+			err = auditedChange(rpc.service, client, auditPause, gid, rpc.service.Pause)
+		case "aria2.unpause":
+			err = auditedChange(rpc.service, client, auditResume, gid, rpc.service.Resume)
+		default:
+			err = auditedChange(rpc.service, client, auditDelete, gid, rpc.service.Delete)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if method == "aria2.removeDownloadResult" {
+			return "OK", nil
+		}
+		return gid, nil
+	case "aria2.getGlobalStat":
+		return rpc.globalStat()
+	case "aria2.changeOption":
+		var gid string
+		var options map[string]interface{}
+		if err := bindParams(params, &gid, &options); err != nil {
+			return nil, err
+		}
+		if _, err := rpc.status(gid); err != nil {
+			return nil, err
+		}
+		// Surge fixes a download's options when it is added, so reporting
+		// success here would tell the frontend a change took effect when it did not
+		if len(options) > 0 {
+			return nil, &rpcError{rpcAria2Error, "Options cannot be changed after a download is added"}
+		}
+		return "OK", nil
+	case "aria2.changeGlobalOption":
+		var options map[string]interface{}
+		if err := bindParams(params, &options); err != nil {
+			return nil, err
+		}
+		return rpc.changeGlobalOption(options)
+	case "aria2.getGlobalOption":
+		limit := 0
+		if rpc.pool != nil {
+			limit = rpc.pool.MaxDownloads()
+		}
+		return map[string]string{
+			"dir":                      rpc.defaultOutputDir,
+			"max-concurrent-downloads": strconv.Itoa(limit),
+		}, nil
+	case "aria2.getVersion":
+		return map[string]interface{}{"version": aria2Version, "enabledFeatures": []string{"HTTPS"}}, nil
+	default:
+		return nil, &rpcError{rpcMethodNotFound, "Method not found"}
+	}
+}
+
+// aria2Methods is returned by system.listMethods
+var aria2Methods = []string{
+	"aria2.addUri", "aria2.tellStatus", "aria2.tellActive", "aria2.tellWaiting", "aria2.tellStopped",
+	"aria2.pause", "aria2.forcePause", "aria2.unpause", "aria2.remove", "aria2.forceRemove",
+	"aria2.removeDownloadResult", "aria2.getGlobalStat", "aria2.changeOption", "aria2.changeGlobalOption",
+	"aria2.getGlobalOption", "aria2.getVersion", "system.multicall", "system.listMethods",
+}
+
+// multicall runs system.multicall: one array of {methodName, params} structs,
+// answered with [result] or an error struct per call
+func (rpc *aria2RPC) multicall(params []json.RawMessage, caller aria2Caller) (interface{}, error) {
+	var calls []struct {
+		MethodName string            `json:"methodName"`
+		Params     []json.RawMessage `json:"params"`
+	}
+	if err := bindParams(params, &calls); err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(calls))
+	for i, c := range calls {
+		if c.MethodName == "system.multicall" {
+			results[i] = &rpcError{rpcAria2Error, "Recursive system.multicall forbidden"}
+			continue
+		}
+		result, err := rpc.dispatch(c.MethodName, c.Params, caller)
+		if err != nil {
+			var rerr *rpcError
+			if !errors.As(err, &rerr) {
+				rerr = &rpcError{rpcAria2Error, err.Error()}
+			}
+			results[i] = rerr
+			continue
+		}
+		results[i] = []interface{}{result}
+	}
+	return results, nil
+}
+
+// addURI queues a download. All URIs must point to the same file: the first is
+// the primary URL and the rest are used as mirrors.
+func (rpc *aria2RPC) addURI(params []json.RawMessage, client *apiClient) (interface{}, error) {
+	var uris []string
+	var options map[string]interface{}
+	var position *int
+	if err := bindParams(params, &uris, &options, &position); err != nil {
+		return nil, err
+	}
+	if len(uris) == 0 {
+		return nil, &rpcError{rpcInvalidParams, "No URI to download"}
+	}
+
+	req := DownloadRequest{
+		URL:          uris[0],
+		Mirrors:      uris[1:],
+		SkipApproval: true, // RPC clients cannot answer a TUI prompt
+	}
+	for key, value := range options {
+		switch key {
+		case "dir":
+			req.Path, _ = value.(string)
+		case "out":
+			req.Filename, _ = value.(string)
+		case "header":
+			// A single header string or a list of them
+			var lines []string
+			switch v := value.(type) {
+			case string:
+				lines = []string{v}
+			case []interface{}:
+				for _, h := range v {
+					if s, ok := h.(string); ok {
+						lines = append(lines, s)
+					}
+				}
+			}
+			headers, err := parseHeaderFlags(lines)
+			if err != nil {
+				return nil, err
+			}
+			req.Headers = headers
+		default:
+			if !aria2Ignored[key] {
+				utils.Debug("aria2.addUri: ignoring unsupported option %s", key)
+			}
+		}
+	}
+
+	_, resp, err := queueDownload(req, rpc.defaultOutputDir, rpc.service, client)
+	if err != nil {
+		return nil, err
+	}
+	if resp["status"] != "queued" {
+		return nil, errors.New(resp["message"])
+	}
+
+	gid := resp["id"]
+	if position != nil && rpc.pool != nil {
+		rpc.pool.Reorder(gid, *position)
+	}
+	return gid, nil
+}
+
+func (rpc *aria2RPC) changeGlobalOption(options map[string]interface{}) (interface{}, error) {
+	for key, value := range options {
+		if key != "max-concurrent-downloads" {
+			return nil, fmt.Errorf("option %s cannot be changed", key)
+		}
+		s, _ := value.(string)
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for max-concurrent-downloads: %v", value)
+		}
+		if err := config.CheckRange("max_concurrent_downloads", float64(n)); err != nil {
+			return nil, err
+		}
+		if rpc.pool == nil {
+			return nil, errors.New("queue is not available")
+		}
+		rpc.pool.SetMaxDownloads(n)
+	}
+	return "OK", nil
+}
+
+// status looks up one download by GID (Surge's download ID)
+func (rpc *aria2RPC) status(gid string) (*types.DownloadStatus, error) {
+	if gid == "" {
+		return nil, &rpcError{rpcInvalidParams, "gid is required"}
+	}
+	status, err := rpc.service.GetStatus(gid)
+	if err != nil || status == nil {
+		return nil, fmt.Errorf("GID %s is not found", gid)
+	}
+	return status, nil
+}
+
+// tell lists downloads whose aria2 status matches, paginated like aria2:
+// a negative offset counts from the end, and num < 0 means all
+func (rpc *aria2RPC) tell(match func(string) bool, offset, num int, keys []string) (interface{}, error) {
+	statuses, err := listStatuses(rpc.service, rpc.pool)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []map[string]interface{}
+	for i := range statuses {
+		if s := aria2Status(&statuses[i]); match(s["status"].(string)) {
+			matched = append(matched, s)
+		}
+	}
+
+	if offset < 0 {
+		offset = max(0, len(matched)+offset)
+	}
+	offset = min(offset, len(matched))
+	end := len(matched)
+	if num >= 0 {
+		end = offset + min(num, end-offset)
+	}
+
+	result := make([]map[string]interface{}, 0, end-offset)
+	for _, s := range matched[offset:end] {
+		result = append(result, filterKeys(s, keys))
+	}
+	return result, nil
+}
+
+func (rpc *aria2RPC) globalStat() (interface{}, error) {
+	statuses, err := listStatuses(rpc.service, rpc.pool)
+	if err != nil {
+		return nil, err
+	}
+
+	var speed int64
+	counts := make(map[string]int)
+	for i := range statuses {
+		s := aria2Status(&statuses[i])
+		counts[s["status"].(string)]++
+		speed += int64(statuses[i].Speed * 1024 * 1024)
+	}
+	stopped := counts["complete"] + counts["error"]
+	return map[string]string{
+		"downloadSpeed":   strconv.FormatInt(speed, 10),
+		"uploadSpeed":     "0",
+		"numActive":       strconv.Itoa(counts["active"]),
+		"numWaiting":      strconv.Itoa(counts["waiting"] + counts["paused"]),
+		"numStopped":      strconv.Itoa(stopped),
+		"numStoppedTotal": strconv.Itoa(stopped),
+	}, nil
+}
+
+// aria2Status converts a download status into aria2's status struct, where
+// numbers are decimal strings
+func aria2Status(s *types.DownloadStatus) map[string]interface{} {
+	status := "active"
+	switch s.Status {
+	case "queued":
+		status = "waiting"
+	case "paused", "pausing":
+		status = "paused"
+	case "completed":
+		status = "complete"
+	case "error":
+		status = "error"
+	}
+
+	path := s.DestPath
+	if path == "" {
+		path = s.Filename
+	}
+	dir := ""
+	if s.DestPath != "" {
+		dir = filepath.Dir(s.DestPath)
+	}
+	speed := strconv.FormatInt(int64(s.Speed*1024*1024), 10)
+	if status != "active" {
+		speed = "0"
+	}
+
+	result := map[string]interface{}{
+		"gid":             s.ID,
+		"status":          status,
+		"totalLength":     strconv.FormatInt(s.TotalSize, 10),
+		"completedLength": strconv.FormatInt(s.Downloaded, 10),
+		"uploadLength":    "0",
+		"downloadSpeed":   speed,
+		"uploadSpeed":     "0",
+		"connections":     strconv.Itoa(s.Connections),
+		"numPieces":       "1",
+		"pieceLength":     strconv.FormatInt(s.TotalSize, 10),
+		"dir":             dir,
+		"files": []map[string]interface{}{{
+			"index":           "1",
+			"path":            path,
+			"length":          strconv.FormatInt(s.TotalSize, 10),
+			"completedLength": strconv.FormatInt(s.Downloaded, 10),
+			"selected":        "true",
+			"uris":            []map[string]string{{"uri": s.URL, "status": "used"}},
+		}},
+	}
+	if status == "error" {
+		result["errorCode"] = "1"
+		result["errorMessage"] = s.Error
+	}
+	return result
+}
+
+// filterKeys keeps only keys in s, as aria2 does when a key list is given
+func filterKeys(s map[string]interface{}, keys []string) map[string]interface{} {
+	if len(keys) == 0 {
+		return s
+	}
+	filtered := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := s[k]; ok {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// bindParams decodes positional params into dest; missing trailing params keep their zero value
+func bindParams(params []json.RawMessage, dest ...interface{}) error {
+	for i, p := range params {
+		if i >= len(dest) {
+			break
+		}
+		if err := json.Unmarshal(p, dest[i]); err != nil {
+			return &rpcError{rpcInvalidParams, fmt.Sprintf("invalid parameter %d: %v", i+1, err)}
+		}
+	}
+	return nil
+}
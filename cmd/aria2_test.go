@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+type rpcResult struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func newTestAria2(t *testing.T) (*httptest.Server, *download.WorkerPool) {
+	t.Helper()
+	setupIsolatedCmdState(t)
+
+	ch := make(chan any, 100)
+	pool := download.NewWorkerPool(ch, 2)
+	svc := core.NewLocalDownloadServiceWithInput(pool, ch)
+	t.Cleanup(func() { _ = svc.Shutdown() })
+
+	// queueDownload checks duplicates against the global pool
+	oldPool := GlobalPool
+	GlobalPool = pool
+	t.Cleanup(func() { GlobalPool = oldPool })
+
+	rpc := &aria2RPC{token: "secret", defaultOutputDir: t.TempDir(), service: svc, pool: pool}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleAria2(w, r, rpc)
+	}))
+	t.Cleanup(server.Close)
+	return server, pool
+}
+
+func callRPC(t *testing.T, url, body string) rpcResult {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result rpcResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return result
+}
+
+func TestAria2_AuthAndErrors(t *testing.T) {
+	server, _ := newTestAria2(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"missing token", `{"jsonrpc":"2.0","id":"1","method":"aria2.getGlobalStat","params":[]}`, rpcAria2Error},
+		{"wrong token", `{"jsonrpc":"2.0","id":"1","method":"aria2.getGlobalStat","params":["token:nope"]}`, rpcAria2Error},
+		{"unknown method", `{"jsonrpc":"2.0","id":"1","method":"aria2.shutdown","params":["token:secret"]}`, rpcMethodNotFound},
+		{"bad params", `{"jsonrpc":"2.0","id":"1","method":"aria2.addUri","params":["token:secret","not-a-list"]}`, rpcInvalidParams},
+		{"no uris", `{"jsonrpc":"2.0","id":"1","method":"aria2.addUri","params":["token:secret",[]]}`, rpcInvalidParams},
+		{"unknown gid", `{"jsonrpc":"2.0","id":"1","method":"aria2.tellStatus","params":["token:secret","nope"]}`, rpcAria2Error},
+		{"unsupported option", `{"jsonrpc":"2.0","id":"1","method":"aria2.changeGlobalOption","params":["token:secret",{"max-overall-download-limit":"1M"}]}`, rpcAria2Error},
+		{"limit out of range", `{"jsonrpc":"2.0","id":"1","method":"aria2.changeGlobalOption","params":["token:secret",{"max-concurrent-downloads":"11"}]}`, rpcAria2Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := callRPC(t, server.URL, tt.body)
+			if res.Error == nil || res.Error.Code != tt.wantCode {
+				t.Errorf("Expected error code %d, got %+v", tt.wantCode, res.Error)
+			}
+		})
+	}
+
+	// A Bearer header works in place of the token parameter
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"aria2.getVersion"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var res rpcResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || res.Error != nil || string(res.ID) != "7" {
+		t.Errorf("Bearer auth failed: %+v (%v)", res, err)
+	}
+}
+
+func TestAria2_DownloadLifecycle(t *testing.T) {
+	server, pool := newTestAria2(t)
+	mock := testutil.NewMockServerT(t, testutil.WithFileSize(64*1024), testutil.WithRangeSupport(true))
+
+	res := callRPC(t, server.URL, `{"jsonrpc":"2.0","id":"add","method":"aria2.addUri","params":["token:secret",["`+mock.URL()+`"],{"out":"file.bin","split":"16"}]}`)
+	var gid string
+	if res.Error != nil || json.Unmarshal(res.Result, &gid) != nil || gid == "" {
+		t.Fatalf("addUri failed: %+v", res)
+	}
+
+	// Wait for the download to show up as stopped
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		res = callRPC(t, server.URL, `{"jsonrpc":"2.0","id":"s","method":"aria2.tellStopped","params":["token:secret",0,10,["gid","status","totalLength","files"]]}`)
+		var stopped []map[string]interface{}
+		if err := json.Unmarshal(res.Result, &stopped); err != nil {
+			t.Fatalf("tellStopped failed: %+v", res)
+		}
+		if len(stopped) == 1 {
+			s := stopped[0]
+			if s["gid"] != gid || s["status"] != "complete" || s["totalLength"] != "65536" {
+				t.Fatalf("Unexpected status: %v", s)
+			}
+			if _, ok := s["dir"]; ok {
+				t.Error("Keys filter not applied")
+			}
+			files := s["files"].([]interface{})
+			if path := files[0].(map[string]interface{})["path"].(string); !strings.HasSuffix(path, "file.bin") {
+				t.Errorf("Unexpected file path %q", path)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Download did not complete")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// A huge num must not overflow the page end
+	res = callRPC(t, server.URL, `{"jsonrpc":"2.0","id":"s","method":"aria2.tellStopped","params":["token:secret",0,9223372036854775807,["gid"]]}`)
+	var all []map[string]interface{}
+	if err := json.Unmarshal(res.Result, &all); err != nil || len(all) != 1 {
+		t.Errorf("tellStopped with a huge num = %+v", res)
+	}
+
+	// Options are fixed once a download is added
+	res = callRPC(t, server.URL, `{"jsonrpc":"2.0","id":"c","method":"aria2.changeOption","params":["token:secret","`+gid+`",{"split":"4"}]}`)
+	if res.Error == nil || res.Error.Code != rpcAria2Error {
+		t.Errorf("Expected changeOption to fail with an aria2 error, got %+v", res)
+	}
+
+	// Batched calls, and a multicall with per-call tokens
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"aria2.getGlobalStat","params":["token:secret"]},` +
+		`{"jsonrpc":"2.0","id":2,"method":"system.multicall","params":[[` +
+		`{"methodName":"aria2.changeGlobalOption","params":["token:secret",{"max-concurrent-downloads":"4"}]},` +
+		`{"methodName":"aria2.tellActive","params":["token:wrong"]}]]}]`
+	resp, err := http.Post(server.URL, "application/json", bytes.NewBufferString(batch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var results []rpcResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil || len(results) != 2 {
+		t.Fatalf("Bad batch response: %v %v", results, err)
+	}
+
+	var stat map[string]string
+	if err := json.Unmarshal(results[0].Result, &stat); err != nil || stat["numStopped"] != "1" || stat["numActive"] != "0" {
+		t.Errorf("Unexpected global stat: %v (%v)", stat, err)
+	}
+
+	var multi []json.RawMessage
+	if err := json.Unmarshal(results[1].Result, &multi); err != nil || len(multi) != 2 {
+		t.Fatalf("Bad multicall result: %s", results[1].Result)
+	}
+	if string(multi[0]) != `["OK"]` {
+		t.Errorf("changeGlobalOption result = %s", multi[0])
+	}
+	if !strings.Contains(string(multi[1]), "Unauthorized") {
+		t.Errorf("Expected unauthorized subcall, got %s", multi[1])
+	}
+	if pool.MaxDownloads() != 4 {
+		t.Errorf("Expected limit 4, got %d", pool.MaxDownloads())
+	}
+}
+
+func TestAria2_WaitingForWorker(t *testing.T) {
+	setupIsolatedCmdState(t)
+	pool := download.NewWorkerPool(nil, 1)
+	running, waiting := queueBehindStalledDownload(t, pool)
+
+	rpc := &aria2RPC{token: "secret", service: core.NewLocalDownloadService(pool), pool: pool}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleAria2(w, r, rpc)
+	}))
+	t.Cleanup(server.Close)
+
+	gids := func(method, params string) []string {
+		t.Helper()
+		res := callRPC(t, server.URL, `{"jsonrpc":"2.0","id":"1","method":"`+method+`","params":["token:secret"`+params+`]}`)
+		var list []struct {
+			GID    string `json:"gid"`
+			Status string `json:"status"`
+		}
+		if res.Error != nil || json.Unmarshal(res.Result, &list) != nil {
+			t.Fatalf("%s failed: %+v", method, res.Error)
+		}
+		var ids []string
+		for _, s := range list {
+			ids = append(ids, s.GID+"/"+s.Status)
+		}
+		return ids
+	}
+	if got := gids("aria2.tellActive", ""); len(got) != 1 || got[0] != running+"/active" {
+		t.Errorf("tellActive = %v, want only %s", got, running)
+	}
+	if got := gids("aria2.tellWaiting", ",0,10"); len(got) != 1 || got[0] != waiting+"/waiting" {
+		t.Errorf("tellWaiting = %v, want only %s", got, waiting)
+	}
+
+	res := callRPC(t, server.URL, `{"jsonrpc":"2.0","id":"1","method":"aria2.getGlobalStat","params":["token:secret"]}`)
+	var stat map[string]string
+	if res.Error != nil || json.Unmarshal(res.Result, &stat) != nil {
+		t.Fatalf("getGlobalStat failed: %+v", res.Error)
+	}
+	if stat["numActive"] != "1" || stat["numWaiting"] != "1" {
+		t.Errorf("getGlobalStat = %v, want 1 active and 1 waiting", stat)
+	}
+}
+
+func TestAria2Status_Mapping(t *testing.T) {
+	tests := map[string]string{
+		"downloading": "active",
+		"queued":      "waiting",
+		"pausing":     "paused",
+		"paused":      "paused",
+		"completed":   "complete",
+		"error":       "error",
+	}
+	for surge, want := range tests {
+		s := aria2Status(&types.DownloadStatus{ID: "x", Status: surge, Error: "boom", DestPath: "/tmp/dl/f.bin"})
+		if s["status"] != want {
+			t.Errorf("%s -> %v, want %s", surge, s["status"], want)
+		}
+		if s["dir"] != "/tmp/dl" && s["dir"] != `\tmp\dl` {
+			t.Errorf("Unexpected dir %v", s["dir"])
+		}
+		if _, hasErr := s["errorMessage"]; hasErr != (want == "error") {
+			t.Errorf("%s: errorMessage presence = %v", surge, hasErr)
+		}
+	}
+}
+
+func TestAria2_BatchOfBadTokensLocksOut(t *testing.T) {
+	setupIsolatedCmdState(t)
+	throttle := newAuthThrottle()
+	throttle.sleep = func(time.Duration) {}
+	rpc := &aria2RPC{token: "secret", throttle: throttle, service: core.NewLocalDownloadService(nil)}
+
+	// One HTTP request holding more guesses than the lockout allows, the last
+	// of them right, plus a multicall of further guesses
+	var calls []string
+	for i := 0; i < authFailureLimit+5; i++ {
+		calls = append(calls, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"aria2.getVersion","params":["token:guess-%d"]}`, i, i))
+	}
+	calls = append(calls,
+		`{"jsonrpc":"2.0","id":"right","method":"aria2.getVersion","params":["token:secret"]}`,
+		`{"jsonrpc":"2.0","id":"multi","method":"system.multicall","params":[[{"methodName":"aria2.getVersion","params":["token:secret"]}]]}`)
+	req := httptest.NewRequest(http.MethodPost, "/jsonrpc", strings.NewReader("["+strings.Join(calls, ",")+"]"))
+	rec := httptest.NewRecorder()
+	handleAria2(rec, req, rpc)
+
+	var results []rpcResult
+	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil || len(results) != len(calls) {
+		t.Fatalf("Bad batch response: %v (%v)", results, err)
+	}
+	for i, res := range results[:len(results)-1] {
+		if res.Error == nil {
+			t.Fatalf("Call %d succeeded: %s", i, res.Result)
+		}
+		locked := strings.Contains(res.Error.Message, "Too many failed")
+		if locked != (i >= authFailureLimit) {
+			t.Errorf("Call %d: unexpected error %q", i, res.Error.Message)
+		}
+	}
+	if multi := string(results[len(results)-1].Result); !strings.Contains(multi, "Too many failed") {
+		t.Errorf("Expected the multicall entry to be locked out, got %s", multi)
+	}
+	if throttle.lockedFor(clientIP(req)) == 0 {
+		t.Error("Expected the address to be locked out")
+	}
+}
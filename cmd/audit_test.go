@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+func TestAudit_RecordsActorsThroughAPI(t *testing.T) {
+	server := newTestAPI(t)
+
+	secret, _, err := createToken("nas-script", scopeAdd, "", nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+apiPrefix+"/downloads",
+		strings.NewReader(`{"url":"http://127.0.0.1:1/huge.iso","skip_approval":true}`))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	if resp := apiCall(t, server, http.MethodDelete, "/downloads/c", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+
+	// The audit log needs full scope
+	req, _ = http.NewRequest(http.MethodGet, server.URL+apiPrefix+"/audit", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 for add-scoped token, got %v (%v)", resp.StatusCode, err)
+	}
+
+	var page struct {
+		Items []state.AuditEntry `json:"items"`
+		Total int                `json:"total"`
+	}
+	if resp := apiCall(t, server, http.MethodGet, "/audit", "", &page); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if page.Total != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", page.Items)
+	}
+	byAction := map[string]state.AuditEntry{}
+	for _, e := range page.Items {
+		byAction[e.Action] = e
+	}
+	add := byAction[auditAdd]
+	if add.Actor != "nas-script" || add.Addr != "127.0.0.1" || add.URL != "http://127.0.0.1:1/huge.iso" || add.DownloadID == "" {
+		t.Errorf("Unexpected add entry: %+v", add)
+	}
+	del := byAction[auditDelete]
+	if del.Actor != "root" || del.DownloadID != "c" || del.URL != "https://example.com/gamma.zip" {
+		t.Errorf("Unexpected delete entry: %+v", del)
+	}
+
+	if resp := apiCall(t, server, http.MethodGet, "/audit?actor=nas-script&action=delete", "", &page); resp.StatusCode != http.StatusOK || page.Total != 0 {
+		t.Errorf("Expected filters to exclude everything, got %+v", page.Items)
+	}
+}
+
+func TestParseTimeFilter(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"24h", now.Add(-24 * time.Hour).Unix()},
+		{"7d", now.Add(-7 * 24 * time.Hour).Unix()},
+		{"2026-03-01T00:00:00Z", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Unix()},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local).Unix()},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeFilter(tt.in, now)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTimeFilter(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := parseTimeFilter("yesterday", now); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
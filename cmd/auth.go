@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Token scopes. read sees downloads and events, add only queues downloads,
+// full can do everything the root token can.
+const (
+	scopeRead = "read"
+	scopeAdd  = "add"
+	scopeFull = "full"
+)
+
+// tokenPrefix marks named tokens, so they are recognisable in configs and logs
+const tokenPrefix = "surge_"
+
+// tokenTouchInterval limits how often a token's last-used time is written
+const tokenTouchInterval = time.Minute
+
+// apiClient is the authenticated caller of a request
+type apiClient struct {
+	Name        string
+	Scope       string
+	AllowedDirs []string // Empty means any directory
+	Addr        string   // Address the request came from, for the audit log
+}
+
+// from returns a copy of c for a request from addr
+func (c *apiClient) from(addr string) *apiClient {
+	copied := *c
+	copied.Addr = addr
+	return &copied
+}
+
+// rootClient is the holder of the daemon's own token
+var rootClient = &apiClient{Name: "root", Scope: scopeFull}
+
+type clientKey struct{}
+
+func withClient(ctx context.Context, c *apiClient) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// clientFrom returns the client authenticated by authMiddleware, or nil
+func clientFrom(ctx context.Context) *apiClient {
+	c, _ := ctx.Value(clientKey{}).(*apiClient)
+	return c
+}
+
+// validScope reports whether s is a known scope
+func validScope(s string) bool {
+	return s == scopeRead || s == scopeAdd || s == scopeFull
+}
+
+// allows reports whether c may perform an operation needing scope.
+// A nil client is unrestricted (in-process callers).
+func (c *apiClient) allows(scope string) bool {
+	return c == nil || c.Scope == scopeFull || c.Scope == scope
+}
+
+// allowsDir reports whether c may save downloads into dir
+func (c *apiClient) allowsDir(dir string) bool {
+	return c == nil || dirAllowed(dir, c.AllowedDirs)
+}
+
+// dirAllowed reports whether dir lies under one of roots, comparing the
+// real locations so a symlink cannot lead outside them. No roots allows any dir.
+func dirAllowed(dir string, roots []string) bool {
+	if len(roots) == 0 {
+		return true
+	}
+	resolved := utils.ResolvePath(dir)
+	for _, root := range roots {
+		if utils.PathWithin(resolved, utils.ResolvePath(root)) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredScope returns the scope an HTTP request needs
+func requiredScope(r *http.Request) string {
+	// The audit log and the queue's request headers are for admins only
+	if r.URL.Path == apiPrefix+"/audit" || r.URL.Path == apiPrefix+"/queue" {
+		return scopeFull
+	}
+	if r.Method == http.MethodPost && (r.URL.Path == "/download" || r.URL.Path == apiPrefix+"/downloads") {
+		return scopeAdd
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return scopeRead
+	}
+	return scopeFull
+}
+
+// generateToken returns a new random named-token secret
+func generateToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
+
+// hashToken returns the stored form of a token secret
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveToken identifies the client presenting secret: the root token holder,
+// an unexpired named token, or nil
+func resolveToken(secret, rootToken string) *apiClient {
+	if secret == "" {
+		return nil
+	}
+	if tokenMatches(secret, rootToken) {
+		return rootClient
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil
+	}
+
+	// The secret's hash is the lookup key, so no comparison leaks timing
+	tok, err := state.GetTokenByHash(hashToken(secret))
+	if err != nil {
+		utils.Debug("Token lookup failed: %v", err)
+		return nil
+	}
+	now := time.Now()
+	if tok == nil || (tok.ExpiresAt > 0 && now.Unix() >= tok.ExpiresAt) {
+		return nil
+	}
+	if now.Unix()-tok.LastUsedAt >= int64(tokenTouchInterval/time.Second) {
+		if err := state.TouchToken(tok.ID, now.Unix()); err != nil {
+			utils.Debug("Failed to record token use: %v", err)
+		}
+	}
+	return &apiClient{Name: tok.Name, Scope: tok.Scope, AllowedDirs: tok.AllowedDirs}
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+)
+
+func TestCreateAndResolveTokens(t *testing.T) {
+	setupIsolatedCmdState(t)
+	now := time.Now()
+
+	for _, tt := range []struct{ scope, expires string }{{"admin", ""}, {"read", "soon"}, {"read", "-1h"}} {
+		if _, _, err := createToken("bad", tt.scope, tt.expires, nil, now); err == nil {
+			t.Errorf("Expected error for scope %q expires %q", tt.scope, tt.expires)
+		}
+	}
+
+	readSecret, _, err := createToken("ha", scopeRead, "30d", nil, now)
+	if err != nil {
+		t.Fatalf("createToken failed: %v", err)
+	}
+	if _, _, err := createToken("ha", scopeFull, "", nil, now); err == nil {
+		t.Error("Expected duplicate name to be rejected")
+	}
+	expiredSecret, _, err := createToken("old", scopeFull, "1h", nil, now.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("createToken failed: %v", err)
+	}
+
+	if c := resolveToken("root-secret", "root-secret"); c != rootClient {
+		t.Errorf("Expected root client, got %+v", c)
+	}
+	if c := resolveToken(readSecret, "root-secret"); c == nil || c.Name != "ha" || c.Scope != scopeRead {
+		t.Errorf("Expected read client, got %+v", c)
+	}
+	for _, secret := range []string{expiredSecret, "", "surge_unknown", "root"} {
+		if c := resolveToken(secret, "root-secret"); c != nil {
+			t.Errorf("Expected %q to be rejected, got %+v", secret, c)
+		}
+	}
+}
+
+func TestAuthMiddleware_Scopes(t *testing.T) {
+	setupIsolatedCmdState(t)
+	readSecret, _, _ := createToken("reader", scopeRead, "", nil, time.Now())
+	addSecret, _, _ := createToken("extension", scopeAdd, "", nil, time.Now())
+	fullSecret, _, _ := createToken("tui", scopeFull, "", nil, time.Now())
+
+	var seen *apiClient
+	handler := authMiddleware("root-secret", newAuthThrottle(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = clientFrom(r.Context())
+	}))
+
+	tests := []struct {
+		secret, method, path string
+		want                 int
+	}{
+		{readSecret, http.MethodGet, "/list", http.StatusOK},
+		{readSecret, http.MethodGet, apiPrefix + "/downloads", http.StatusOK},
+		{readSecret, http.MethodPost, "/download", http.StatusForbidden},
+		{readSecret, http.MethodPost, "/pause?id=x", http.StatusForbidden},
+		{addSecret, http.MethodPost, "/download", http.StatusOK},
+		{addSecret, http.MethodPost, apiPrefix + "/downloads", http.StatusOK},
+		{addSecret, http.MethodGet, "/list", http.StatusForbidden},
+		{addSecret, http.MethodPost, apiPrefix + "/downloads/x/pause", http.StatusForbidden},
+		{fullSecret, http.MethodDelete, apiPrefix + "/downloads/x", http.StatusOK},
+		{"root-secret", http.MethodPost, "/delete?id=x", http.StatusOK},
+		{"nope", http.MethodGet, "/list", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		seen = nil
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.secret)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q: got %d, want %d", tt.method, tt.path, tt.secret, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusOK && seen == nil {
+			t.Errorf("%s %s: client not passed to handler", tt.method, tt.path)
+		}
+	}
+
+	// WebSocket commands are checked individually
+	if _, err := handleWSCommand(wsRequest{Method: "pause", Params: []byte(`{"id":"x"}`)}, "", nil, nil, &apiClient{Scope: scopeRead}); err == nil {
+		t.Error("Expected read token to be refused pause over /ws")
+	}
+}
+
+func TestQueueDownload_AllowedDirs(t *testing.T) {
+	setupIsolatedCmdState(t)
+	root := t.TempDir()
+	allowed := filepath.Join(root, "allowed")
+
+	pool := download.NewWorkerPool(nil, 1)
+	oldPool := GlobalPool
+	GlobalPool = pool
+	t.Cleanup(func() { GlobalPool = oldPool })
+	svc := core.NewLocalDownloadService(pool)
+
+	client := &apiClient{Name: "ext", Scope: scopeAdd, AllowedDirs: []string{allowed}}
+	for _, path := range []string{filepath.Join(root, "other"), allowed + "-sibling"} {
+		status, _, err := queueDownload(DownloadRequest{URL: "http://127.0.0.1:1/f", Path: path, SkipApproval: true}, "", svc, client)
+		if status != http.StatusForbidden || err == nil {
+			t.Errorf("%s: expected 403, got %d (%v)", path, status, err)
+		}
+	}
+
+	if !client.allowsDir(filepath.Join(allowed, "sub")) || !client.allowsDir(allowed) {
+		t.Error("Expected allowed dir and its children to be permitted")
+	}
+	if !(*apiClient)(nil).allowsDir(root) || !(&apiClient{}).allowsDir(root) {
+		t.Error("Unrestricted clients should allow any directory")
+	}
+}
+
+func TestQueueDownload_SeedsMustBeInAllowedDirs(t *testing.T) {
+	setupIsolatedCmdState(t)
+	root := t.TempDir()
+	allowed := filepath.Join(root, "allowed")
+
+	pool := download.NewWorkerPool(nil, 1)
+	oldPool := GlobalPool
+	GlobalPool = pool
+	t.Cleanup(func() { GlobalPool = oldPool })
+	svc := core.NewLocalDownloadService(pool)
+
+	client := &apiClient{Name: "ext", Scope: scopeAdd, AllowedDirs: []string{allowed}}
+	secret := filepath.Join(root, "secret.key")
+	for name, req := range map[string]DownloadRequest{
+		"seed":    {Seeds: []string{secret}},
+		"control": {Seeds: []string{filepath.Join(allowed, "old.img")}, DeltaControl: secret},
+	} {
+		req.URL, req.Path, req.SkipApproval = "http://127.0.0.1:1/f", allowed, true
+		status, _, err := queueDownload(req, "", svc, client)
+		if status != http.StatusForbidden || err == nil {
+			t.Errorf("%s outside the token's dirs: expected 403, got %d (%v)", name, status, err)
+		}
+	}
+
+	status, _, err := queueDownload(DownloadRequest{URL: "http://127.0.0.1:1/f", Path: allowed, DeltaControl: "relative.zsync", SkipApproval: true}, "", svc, client)
+	if status != http.StatusBadRequest || err == nil {
+		t.Errorf("Relative control path: expected 400, got %d (%v)", status, err)
+	}
+}
+
+func TestQueueDownload_AllowedDownloadDirsSetting(t *testing.T) {
+	setupIsolatedCmdState(t)
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(root, "allowed")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{allowed, outside} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A link inside the allowed root that points out of it
+	escape := filepath.Join(allowed, "escape")
+	if err := os.Symlink(outside, escape); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	settings := config.DefaultSettings()
+	settings.General.DefaultDownloadDir = allowed
+	settings.General.AllowedDownloadDirs = []string{allowed}
+	if err := config.SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := download.NewWorkerPool(nil, 1)
+	oldPool := GlobalPool
+	GlobalPool = pool
+	t.Cleanup(func() { GlobalPool = oldPool })
+	svc := core.NewLocalDownloadService(pool)
+
+	tests := []struct {
+		name string
+		req  DownloadRequest
+		want int
+	}{
+		{"outside root", DownloadRequest{Path: outside}, http.StatusForbidden},
+		{"symlink escape", DownloadRequest{Path: filepath.Join(escape, "sub")}, http.StatusForbidden},
+		{"relative symlink escape", DownloadRequest{Path: "escape", RelativeToDefaultDir: true}, http.StatusForbidden},
+		{"default dir", DownloadRequest{}, http.StatusOK},
+		{"inside root", DownloadRequest{Path: filepath.Join(allowed, "sub")}, http.StatusOK},
+		{"relative to default", DownloadRequest{Path: "nested", RelativeToDefaultDir: true}, http.StatusOK},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.URL = fmt.Sprintf("http://127.0.0.1:1/f%d", i)
+			tt.req.SkipApproval = true
+			status, _, err := queueDownload(tt.req, "", svc, rootClient)
+			if status != tt.want {
+				t.Fatalf("expected %d, got %d (%v)", tt.want, status, err)
+			}
+			if status == http.StatusForbidden && !strings.Contains(err.Error(), "allowed download directories") {
+				t.Errorf("unclear error: %v", err)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "sub")); !os.IsNotExist(err) {
+		t.Error("Rejected request should not create directories")
+	}
+}
@@ -107,7 +107,7 @@ func TestGetRemoteDownloads(t *testing.T) {
 	}
 }
 
-func TestReadURLsFromFile_ParsesAndFilters(t *testing.T) {
+func TestCollectEntries_ParsesAndFilters(t *testing.T) {
 	tmpDir := t.TempDir()
 	urlFile := filepath.Join(tmpDir, "urls.txt")
 	content := strings.Join([]string{
@@ -118,36 +118,70 @@ func TestReadURLsFromFile_ParsesAndFilters(t *testing.T) {
 		"   ",
 		"#another-comment",
 		"https://example.com/c.zip",
+		"  out=c-renamed.zip",
 	}, "\n")
 	if err := os.WriteFile(urlFile, []byte(content), 0o644); err != nil {
 		t.Fatalf("failed to write url file: %v", err)
 	}
 
-	urls, err := readURLsFromFile(urlFile)
+	entries, err := collectEntries([]string{"https://example.com/arg.zip,https://mirror.example.com/arg.zip"}, "", urlFile, false)
 	if err != nil {
-		t.Fatalf("readURLsFromFile returned error: %v", err)
+		t.Fatalf("collectEntries returned error: %v", err)
 	}
 
 	want := []string{
+		"https://example.com/arg.zip",
 		"https://example.com/a.zip",
 		"https://example.com/b.zip",
 		"https://example.com/c.zip",
 	}
-	if len(urls) != len(want) {
-		t.Fatalf("expected %d urls, got %d (%v)", len(want), len(urls), urls)
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d (%v)", len(want), len(entries), entries)
 	}
 	for i := range want {
-		if urls[i] != want[i] {
-			t.Fatalf("url[%d] = %q, want %q", i, urls[i], want[i])
+		if entries[i].URL != want[i] {
+			t.Fatalf("entry[%d] = %q, want %q", i, entries[i].URL, want[i])
 		}
 	}
+	if len(entries[0].Mirrors) != 2 {
+		t.Fatalf("expected the arg's mirrors to be kept, got %v", entries[0].Mirrors)
+	}
+	if entries[3].Filename != "c-renamed.zip" {
+		t.Fatalf("entry[3].Filename = %q, want c-renamed.zip", entries[3].Filename)
+	}
 }
 
-func TestReadURLsFromFile_MissingFile(t *testing.T) {
-	_, err := readURLsFromFile(filepath.Join(t.TempDir(), "missing.txt"))
+func TestCollectEntries_MissingFile(t *testing.T) {
+	entries, err := collectEntries([]string{"https://example.com/a.zip"}, "", filepath.Join(t.TempDir(), "missing.txt"), false)
 	if err == nil {
 		t.Fatal("expected an error for missing file")
 	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the URL args to be kept, got %v", entries)
+	}
+}
+
+func TestCollectEntries_ExpandsPatterns(t *testing.T) {
+	entries, err := collectEntries([]string{"https://example.com/part[01-03].bin"}, "shard-#1.bin", "", false)
+	if err != nil {
+		t.Fatalf("collectEntries returned error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d (%v)", len(entries), entries)
+	}
+	if entries[2].URL != "https://example.com/part03.bin" || entries[2].Filename != "shard-03.bin" {
+		t.Fatalf("unexpected entry: %+v", entries[2])
+	}
+
+	if _, err := collectEntries([]string{"https://example.com/part[9-1].bin"}, "", "", false); err == nil {
+		t.Fatal("expected an error for an invalid range")
+	}
+
+	// --globoff keeps the brackets
+	entries, err = collectEntries([]string{"https://example.com/part[9-1].bin"}, "", "", true)
+	if err != nil || len(entries) != 1 || entries[0].URL != "https://example.com/part[9-1].bin" {
+		t.Fatalf("expected the URL to be kept literally, got %v (%v)", entries, err)
+	}
 }
 
 func TestServerPIDLifecycle(t *testing.T) {
@@ -497,3 +531,59 @@ func captureStdout(t *testing.T, fn func()) string {
 	_ = r.Close()
 	return string(data)
 }
+
+func TestParseRangeFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		rangeFlag  string
+		headFlag   string
+		wantStart  int64
+		wantLength int64
+		wantErr    bool
+	}{
+		{"none", "", "", 0, 0, false},
+		{"head bytes", "", "1024", 0, 1024, false},
+		{"head with unit", "", "100MB", 0, 100 << 20, false},
+		{"closed range", "100-199", "", 100, 100, false},
+		{"open range", "500-", "", 500, 0, false},
+		{"both flags", "0-10", "10", 0, 0, true},
+		{"zero head", "", "0", 0, 0, true},
+		{"missing start", "-100", "", 0, 0, true},
+		{"end before start", "200-100", "", 0, 0, true},
+		{"garbage", "abc", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseRangeFlags(tt.rangeFlag, tt.headFlag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (opts.RangeStart != tt.wantStart || opts.RangeLength != tt.wantLength) {
+				t.Errorf("got (%d, %d), want (%d, %d)", opts.RangeStart, opts.RangeLength, tt.wantStart, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestParseDeltaFlags(t *testing.T) {
+	seed := filepath.Join(t.TempDir(), "old.iso")
+	if err := os.WriteFile(seed, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var opts types.DownloadOptions
+	if err := parseDeltaFlags(&opts, []string{seed}, "https://example.com/new.iso.zsync"); err != nil {
+		t.Fatalf("parseDeltaFlags failed: %v", err)
+	}
+	if len(opts.Seeds) != 1 || opts.Seeds[0] != seed || opts.DeltaControl != "https://example.com/new.iso.zsync" {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	if err := parseDeltaFlags(&types.DownloadOptions{}, nil, "new.iso.zsync"); err == nil {
+		t.Error("Expected error for --zsync without --seed")
+	}
+	if err := parseDeltaFlags(&types.DownloadOptions{}, []string{filepath.Join(t.TempDir(), "missing")}, ""); err == nil {
+		t.Error("Expected error for missing seed")
+	}
+}
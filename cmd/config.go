@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// settingEntry is one setting as listed by surge config list --json
+type settingEntry struct {
+	Key         string      `json:"key"`
+	Category    string      `json:"category"`
+	Type        string      `json:"type"`
+	Value       interface{} `json:"value"` // As stored in settings.json
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings from the command line",
+	Long: `Read and change the settings in settings.json, as the TUI's settings view does.
+
+Values are checked against each setting's type before they are saved. A
+running daemon is told to reload its settings after every change.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := loadSettingsOrExit()
+		value, err := settings.Value(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(config.FormatValue(args[0], value))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Long: `Change a setting. Durations take Go syntax (90s, 1m30s) or bare seconds,
+sizes are in bytes, and lists are separated by the OS path list separator.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := loadSettingsOrExit()
+		if err := settings.Set(args[0], args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveSettingsOrExit(settings)
+	},
+}
+
+var configResetCmd = &cobra.Command{
+	Use:   "reset [key]...",
+	Short: "Restore settings to their defaults",
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) > 0) {
+			fmt.Fprintln(os.Stderr, "Error: provide setting keys or use --all")
+			os.Exit(1)
+		}
+
+		settings := config.DefaultSettings()
+		if !all {
+			settings = loadSettingsOrExit()
+			for _, key := range args {
+				if err := settings.Reset(key); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+		saveSettingsOrExit(settings)
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all settings with their values and descriptions",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := listSettings(loadSettingsOrExit())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(entries); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE\tTYPE\tDESCRIPTION")
+		for _, e := range entries {
+			value := config.FormatValue(e.Key, e.Value)
+			if value == "" {
+				value = `""`
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Key, value, e.Type, e.Description)
+		}
+		_ = w.Flush()
+	},
+}
+
+// listSettings returns every setting in metadata order
+func listSettings(settings *config.Settings) ([]settingEntry, error) {
+	defaults := config.DefaultSettings()
+	metadata := config.GetSettingsMetadata()
+
+	var entries []settingEntry
+	for _, category := range config.CategoryOrder() {
+		for _, meta := range metadata[category] {
+			value, err := settings.Value(meta.Key)
+			if err != nil {
+				return nil, err
+			}
+			def, _ := defaults.Value(meta.Key)
+			entries = append(entries, settingEntry{
+				Key: meta.Key, Category: category, Type: meta.Type,
+				Value: value, Default: def, Description: meta.Description,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// loadSettingsOrExit loads settings.json, refusing to go on if it can't be
+// parsed: saving over it would lose the user's settings
+func loadSettingsOrExit() *config.Settings {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", config.GetSettingsPath(), err)
+		os.Exit(1)
+	}
+	return settings
+}
+
+// saveSettingsOrExit saves settings and has a running daemon pick them up
+func saveSettingsOrExit(settings *config.Settings) {
+	if err := config.SaveSettings(settings); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to save settings: %v\n", err)
+		os.Exit(1)
+	}
+	port := daemonPort()
+	if port == 0 {
+		return
+	}
+	if err := reloadDaemonSettings(port); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: settings saved, but the running daemon did not reload them: %v\n", err)
+		return
+	}
+	utils.Debug("Running daemon reloaded its settings")
+}
+
+// reloadDaemonSettings asks the daemon on this machine to reload settings.json
+func reloadDaemonSettings(port int) error {
+	baseURL, client := localClient(port)
+	req, err := http.NewRequest(http.MethodPost, baseURL+apiPrefix+"/settings/reload", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+ensureAuthToken())
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		var apiErr apiError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Message != "" {
+			return errors.New(apiErr.Error.Message)
+		}
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
+
+func init() {
+	configResetCmd.Flags().Bool("all", false, "Restore every setting")
+	configListCmd.Flags().Bool("json", false, "Output as JSON, with values as stored in settings.json")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configResetCmd, configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
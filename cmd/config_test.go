@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+// reloadCountingService records ReloadSettings calls
+type reloadCountingService struct {
+	*core.LocalDownloadService
+	reloads int
+}
+
+func (s *reloadCountingService) ReloadSettings() error {
+	s.reloads++
+	return s.LocalDownloadService.ReloadSettings()
+}
+
+func TestReloadDaemonSettings(t *testing.T) {
+	setupIsolatedCmdState(t)
+	svc := &reloadCountingService{LocalDownloadService: core.NewLocalDownloadService(nil)}
+	defer func() { _ = svc.Shutdown() }()
+
+	mux := http.NewServeMux()
+	registerAPI(mux, &apiServer{service: svc})
+	server := testutil.NewHTTPServerT(t, authMiddleware(ensureAuthToken(), newAuthThrottle(), mux))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	if err := reloadDaemonSettings(port); err != nil {
+		t.Fatalf("reloadDaemonSettings failed: %v", err)
+	}
+	if svc.reloads != 1 {
+		t.Errorf("Expected one reload, got %d", svc.reloads)
+	}
+
+	// Services without a settings cache say so
+	plain := http.NewServeMux()
+	registerAPI(plain, &apiServer{service: core.NewRemoteDownloadService("http://127.0.0.1:1", "")})
+	other := testutil.NewHTTPServerT(t, authMiddleware(ensureAuthToken(), newAuthThrottle(), plain))
+	defer other.Close()
+	if err := reloadDaemonSettings(other.Listener.Addr().(*net.TCPAddr).Port); err == nil {
+		t.Error("Expected an error from a service that cannot reload settings")
+	}
+}
+
+func TestListSettings(t *testing.T) {
+	settings := config.DefaultSettings()
+	if err := settings.Set("stall_timeout", "42s"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	entries, err := listSettings(settings)
+	if err != nil {
+		t.Fatalf("listSettings failed: %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.Key == "stall_timeout" {
+			found = true
+			if config.FormatValue(e.Key, e.Value) != "42s" || e.Category != "Performance" || e.Type != "duration" || e.Description == "" {
+				t.Errorf("Unexpected entry: %+v", e)
+			}
+			if e.Default == e.Value {
+				t.Error("Expected the default to differ from the changed value")
+			}
+		}
+	}
+	if !found {
+		t.Error("stall_timeout is missing from the list")
+	}
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
@@ -21,13 +24,17 @@ var connectCmd = &cobra.Command{
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		var target string
+		viaSocket := false
 		if len(args) > 0 {
 			target = args[0]
 		} else {
-			// Auto-discovery from local port file
+			// Auto-discovery: the local socket, else the port file
 			port := readActivePort()
-			if port > 0 {
-				target = fmt.Sprintf("127.0.0.1:%d", port)
+			if socketAvailable() {
+				viaSocket = true
+				target = fmt.Sprintf("http://127.0.0.1:%d", port)
+			} else if port > 0 {
+				target = localServerURL(port)
 			} else {
 				fmt.Println("No active Surge daemon found locally.")
 				fmt.Println("Usage: surge connect <host:port>")
@@ -41,6 +48,23 @@ var connectCmd = &cobra.Command{
 			fmt.Println(err.Error())
 			os.Exit(1)
 		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			fmt.Printf("Invalid target: %v\n", err)
+			os.Exit(1)
+		}
+		hostPort := u.Host
+		if u.Port() == "" {
+			hostPort = net.JoinHostPort(u.Hostname(), map[string]string{"http": "80", "https": "443"}[u.Scheme])
+		}
+
+		remotes, err := loadRemotes()
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			remotes = map[string]remoteEntry{}
+		}
+		remembered := remotes[hostPort]
+		local := isLocalHost(u.Hostname())
 
 		// Resolve token
 		tokenFlag, _ := cmd.Flags().GetString("token")
@@ -49,13 +73,12 @@ var connectCmd = &cobra.Command{
 			// Allow env override
 			token = strings.TrimSpace(os.Getenv("SURGE_TOKEN"))
 		}
+		if token == "" {
+			token = remembered.Token
+		}
 		if token == "" {
 			// Only reuse local token for loopback targets.
-			host := target
-			if idx := strings.Index(host, ":"); idx != -1 {
-				host = host[:idx]
-			}
-			if isLocalHost(host) {
+			if local {
 				token = ensureAuthToken()
 			} else {
 				fmt.Println("No token provided. Use --token or set SURGE_TOKEN.")
@@ -63,18 +86,63 @@ var connectCmd = &cobra.Command{
 			}
 		}
 
+		// Verify the certificate: the system's trust store, a pinned fingerprint,
+		// or (for the local daemon) the fingerprint it published
+		var tlsConfig *tls.Config
+		pin := remembered.Fingerprint
+		if u.Scheme == "https" {
+			fingerprintFlag, _ := cmd.Flags().GetString("fingerprint")
+			if fingerprintFlag == "" && local {
+				fingerprintFlag = readActiveTLS()
+			}
+			tlsConfig, pin, err = resolveRemoteTLS(hostPort, pin, fingerprintFlag, func(fp string) bool {
+				return confirmFingerprint(os.Stdin, os.Stdout, hostPort, fp)
+			})
+			if err != nil {
+				fmt.Printf("Failed to connect: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
 		fmt.Printf("Connecting to %s...\n", baseURL)
 
 		// Create Remote Service
 		service := core.NewRemoteDownloadService(baseURL, token)
+		if viaSocket {
+			transport := socketTransport()
+			service.Client.Transport = transport
+			service.SSEClient.Transport = transport
+		} else if tlsConfig != nil {
+			transport := &http.Transport{TLSClientConfig: tlsConfig}
+			service.Client.Transport = transport
+			service.SSEClient.Transport = transport
+		}
 
 		// Verify connection
 		_, err = service.List()
 		if err != nil {
+			var mismatch *fingerprintMismatchError
+			if errors.As(err, &mismatch) {
+				fmt.Printf("WARNING: the certificate of %s has changed since it was pinned.\n", hostPort)
+				fmt.Printf("Pinned:    %s\nPresented: %s\n", mismatch.Expected, mismatch.Got)
+				fmt.Println("This may mean someone is intercepting the connection. If the daemon's")
+				fmt.Println("certificate was replaced on purpose, reconnect with --fingerprint <new fingerprint>.")
+				os.Exit(1)
+			}
 			fmt.Printf("Failed to connect: %v\n", err)
 			os.Exit(1)
 		}
 
+		// Remember the pin and token for next time. The local daemon publishes
+		// both in the state and runtime dirs, so nothing is stored for it.
+		if !local {
+			if entry := (remoteEntry{Fingerprint: pin, Token: token}); entry != remembered {
+				if err := saveRemote(hostPort, entry); err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+			}
+		}
+
 		// Event loop
 		stream, cleanup, err := service.StreamEvents(context.Background())
 		if err != nil {
@@ -86,13 +154,11 @@ var connectCmd = &cobra.Command{
 		// Parse port for display
 		port := 0
 		serverHost := hostnameFromTarget(target)
-		if u, err := url.Parse(baseURL); err == nil {
-			if h := u.Hostname(); h != "" {
-				serverHost = h
-			}
-			if p := u.Port(); p != "" {
-				port, _ = strconv.Atoi(p)
-			}
+		if h := u.Hostname(); h != "" {
+			serverHost = h
+		}
+		if p := u.Port(); p != "" {
+			port, _ = strconv.Atoi(p)
 		}
 
 		// Initialize TUI
@@ -122,6 +188,7 @@ var connectCmd = &cobra.Command{
 func init() {
 	connectCmd.Flags().String("token", "", "Bearer token for remote daemon (or set SURGE_TOKEN)")
 	connectCmd.Flags().Bool("insecure-http", false, "Allow plain HTTP for non-loopback targets")
+	connectCmd.Flags().String("fingerprint", "", "Expected SHA-256 fingerprint of the daemon's certificate (pins it, replacing any earlier pin)")
 	rootCmd.AddCommand(connectCmd)
 }
 
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// eventLogSize is how many events are kept for Last-Event-ID replay
+const eventLogSize = 1024
+
+// subscriberBuffer is how many events a slow /events client may fall behind by
+// before it is disconnected and left to catch up through replay
+const subscriberBuffer = 256
+
+// sseEvent is one server-sent event. Progress events carry no ID: they are
+// superseded by the next update, so they are neither stored nor replayed.
+type sseEvent struct {
+	ID   int64
+	Name string
+	Data []byte
+}
+
+// eventLog numbers download events, keeps the most recent ones in a ring
+// buffer (mirrored to the state DB when persist is set) and fans them out to
+// /events subscribers
+type eventLog struct {
+	mu      sync.Mutex
+	ring    []sseEvent // Oldest event at index head once full
+	head    int
+	size    int
+	lastID  int64
+	persist bool
+	subs    map[chan sseEvent]struct{}
+}
+
+func newEventLog(size int, persist bool) *eventLog {
+	l := &eventLog{
+		ring:    make([]sseEvent, 0, size),
+		size:    size,
+		persist: persist,
+		subs:    make(map[chan sseEvent]struct{}),
+	}
+	if !persist {
+		return l
+	}
+
+	// Continue numbering from the previous run so clients' IDs stay valid
+	stored, err := state.LoadEvents(size)
+	if err != nil {
+		utils.Debug("Failed to load event log: %v", err)
+		return l
+	}
+	for _, e := range stored {
+		l.push(sseEvent{ID: e.ID, Name: e.Name, Data: e.Data})
+		l.lastID = e.ID
+	}
+	return l
+}
+
+// run records every event from the service until its stream closes
+func (l *eventLog) run(service core.DownloadService) {
+	stream, cleanup, err := service.StreamEvents(context.Background())
+	if err != nil {
+		utils.Debug("Failed to subscribe event log: %v", err)
+		return
+	}
+	defer cleanup()
+
+	for msg := range stream {
+		for _, e := range toSSEEvents(msg) {
+			l.publish(e)
+		}
+	}
+
+	l.mu.Lock()
+	for ch := range l.subs {
+		delete(l.subs, ch)
+		close(ch)
+	}
+	l.mu.Unlock()
+}
+
+// publish numbers and stores e (unless it is a progress update) and sends it to every subscriber
+func (l *eventLog) publish(e sseEvent) {
+	l.mu.Lock()
+	if e.Name != "progress" {
+		l.lastID++
+		e.ID = l.lastID
+		l.push(e)
+	}
+	for ch := range l.subs {
+		select {
+		case ch <- e:
+		default:
+			if e.ID == 0 {
+				continue // Dropping progress is harmless
+			}
+			// The client would miss a lifecycle event: disconnect it so it
+			// reconnects with Last-Event-ID and replays from the log
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+	oldest := l.oldestID()
+	l.mu.Unlock()
+
+	if l.persist && e.ID > 0 {
+		if err := state.AppendEvent(state.Event{ID: e.ID, Name: e.Name, Data: e.Data}, oldest); err != nil {
+			utils.Debug("Failed to persist event %d: %v", e.ID, err)
+		}
+	}
+}
+
+// push appends e to the ring, overwriting the oldest event once full
+func (l *eventLog) push(e sseEvent) {
+	if len(l.ring) < l.size {
+		l.ring = append(l.ring, e)
+		return
+	}
+	l.ring[l.head] = e
+	l.head = (l.head + 1) % l.size
+}
+
+// oldestID returns the ID of the oldest buffered event, or lastID+1 when empty
+func (l *eventLog) oldestID() int64 {
+	if len(l.ring) == 0 {
+		return l.lastID + 1
+	}
+	return l.ring[l.head].ID
+}
+
+// subscribe registers a new subscriber. When resuming from lastSeen it also
+// returns the events the client missed, or ok=false if they are no longer all
+// buffered (or lastSeen is from an unknown sequence) and a snapshot is needed.
+// current is the ID of the latest event at subscription time.
+func (l *eventLog) subscribe(lastSeen int64, resume bool) (ch chan sseEvent, missed []sseEvent, current int64, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch = make(chan sseEvent, subscriberBuffer)
+	l.subs[ch] = struct{}{}
+	current = l.lastID
+
+	if !resume {
+		return ch, nil, current, true
+	}
+	if lastSeen > l.lastID || lastSeen < l.oldestID()-1 {
+		return ch, nil, current, false
+	}
+	for i := range l.ring {
+		e := l.ring[(l.head+i)%len(l.ring)]
+		if e.ID > lastSeen {
+			missed = append(missed, e)
+		}
+	}
+	return ch, missed, current, true
+}
+
+// unsubscribe removes ch, unless publish already dropped it
+func (l *eventLog) unsubscribe(ch chan sseEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.subs[ch]; ok {
+		delete(l.subs, ch)
+		close(ch)
+	}
+}
+
+// toSSEEvents converts a service message into SSE events; batches are unrolled
+func toSSEEvents(msg interface{}) []sseEvent {
+	var name string
+	switch msg := msg.(type) {
+	case events.DownloadStartedMsg:
+		name = "started"
+	case events.DownloadCompleteMsg:
+		name = "complete"
+	case events.DownloadErrorMsg:
+		name = "error"
+	case events.ProgressMsg:
+		name = "progress"
+	case events.DownloadPausedMsg:
+		name = "paused"
+	case events.DownloadResumedMsg:
+		name = "resumed"
+	case events.DownloadQueuedMsg:
+		name = "queued"
+	case events.DownloadRemovedMsg:
+		name = "removed"
+	case events.DownloadRequestMsg:
+		name = "request"
+	case events.BatchProgressMsg:
+		result := make([]sseEvent, 0, len(msg))
+		for _, p := range msg {
+			result = append(result, toSSEEvents(p)...)
+		}
+		return result
+	default:
+		return nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		utils.Debug("Error marshaling event: %v", err)
+		return nil
+	}
+	return []sseEvent{{Name: name, Data: data}}
+}
+
+// writeSSE writes e in the text/event-stream format
+func writeSSE(w io.Writer, e sseEvent) {
+	if e.ID > 0 {
+		_, _ = fmt.Fprintf(w, "id: %d\n", e.ID)
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, e.Data)
+}
+
+// lastEventID reads the Last-Event-ID header (sent by EventSource on
+// reconnect) or the last_event_id query parameter
+func lastEventID(r *http.Request) (int64, bool) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || id < 0 {
+		// Unparseable IDs are treated as unknown, which forces a snapshot
+		return -1, true
+	}
+	return id, true
+}
+
+// handleEvents streams download events over SSE, replaying missed events for
+// clients that reconnect with Last-Event-ID
+func handleEvents(w http.ResponseWriter, r *http.Request, elog *eventLog, service core.DownloadService) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastSeen, resume := lastEventID(r)
+	stream, missed, current, ok := elog.subscribe(lastSeen, resume)
+	defer elog.unsubscribe(stream)
+
+	switch {
+	case !resume:
+		// Tell new clients where the sequence stands, so their first reconnect can resume
+		_, _ = fmt.Fprintf(w, "id: %d\n\n", current)
+	case !ok:
+		// Too far behind to replay: send the full state instead. Events
+		// published after subscribing follow on the stream.
+		statuses, err := service.List()
+		if err != nil {
+			http.Error(w, "Failed to list downloads: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err := json.Marshal(statuses)
+		if err != nil {
+			http.Error(w, "Failed to encode snapshot", http.StatusInternalServerError)
+			return
+		}
+		// The ID is always sent, even before the first event, so a client
+		// holding an ID from an older sequence is reset
+		_, _ = fmt.Fprintf(w, "id: %d\nevent: snapshot\ndata: %s\n\n", current, data)
+	default:
+		for _, e := range missed {
+			writeSSE(w, e)
+		}
+	}
+	flusher.Flush()
+
+	done := r.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-stream:
+			if !ok {
+				return
+			}
+			writeSSE(w, e)
+			flusher.Flush()
+		}
+	}
+}
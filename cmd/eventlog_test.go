@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+)
+
+func publishLifecycle(l *eventLog, n int) {
+	for i := 0; i < n; i++ {
+		for _, e := range toSSEEvents(events.DownloadQueuedMsg{DownloadID: "id", Filename: "f"}) {
+			l.publish(e)
+		}
+	}
+}
+
+func TestEventLog_ReplayAndGaps(t *testing.T) {
+	l := newEventLog(3, false)
+	publishLifecycle(l, 5)
+	for _, e := range toSSEEvents(events.BatchProgressMsg{{DownloadID: "id"}, {DownloadID: "id"}}) {
+		l.publish(e) // Progress is not numbered
+	}
+
+	tests := []struct {
+		name     string
+		lastSeen int64
+		wantOK   bool
+		wantIDs  []int64
+	}{
+		{"caught up", 5, true, nil},
+		{"within buffer", 3, true, []int64{4, 5}},
+		{"oldest buffered is next", 2, true, []int64{3, 4, 5}},
+		{"evicted", 1, false, nil},
+		{"unknown future id", 9, false, nil},
+		{"invalid id", -1, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, missed, current, ok := l.subscribe(tt.lastSeen, true)
+			defer l.unsubscribe(ch)
+			if ok != tt.wantOK || current != 5 {
+				t.Fatalf("subscribe(%d) ok=%v current=%d, want ok=%v current=5", tt.lastSeen, ok, current, tt.wantOK)
+			}
+			var ids []int64
+			for _, e := range missed {
+				ids = append(ids, e.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("missed IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("missed IDs = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestEventLog_SlowSubscriberDisconnected(t *testing.T) {
+	l := newEventLog(eventLogSize, false)
+	ch, _, _, _ := l.subscribe(0, false)
+
+	publishLifecycle(l, subscriberBuffer+1)
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before disconnect, got %d", subscriberBuffer, n)
+	}
+	l.unsubscribe(ch) // Must not double-close
+}
+
+func TestEventLog_PersistedAcrossRestart(t *testing.T) {
+	setupIsolatedCmdState(t)
+
+	l := newEventLog(2, true)
+	publishLifecycle(l, 3)
+
+	restarted := newEventLog(2, true)
+	ch, missed, current, ok := restarted.subscribe(1, true)
+	defer restarted.unsubscribe(ch)
+	if !ok || current != 3 || len(missed) != 2 || missed[0].ID != 2 {
+		t.Fatalf("Expected replay of 2..3 after restart, got ok=%v current=%d missed=%+v", ok, current, missed)
+	}
+	if missed[0].Name != "queued" || !strings.Contains(string(missed[0].Data), `"DownloadID":"id"`) {
+		t.Errorf("Stored event not restored: %+v", missed[0])
+	}
+}
+
+// readSSE reads events from r until n events with data have been seen
+func readSSE(t *testing.T, r *bufio.Reader, n int) []map[string]string {
+	t.Helper()
+	var result []map[string]string
+	cur := map[string]string{}
+	for len(result) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream ended after %d events: %v", len(result), err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if cur["event"] != "" {
+				result = append(result, cur)
+			}
+			cur = map[string]string{}
+			continue
+		}
+		key, value, _ := strings.Cut(line, ": ")
+		cur[key] = value
+	}
+	return result
+}
+
+func TestHandleEvents_ReplayAndSnapshot(t *testing.T) {
+	setupIsolatedCmdState(t)
+	svc := core.NewLocalDownloadService(nil)
+	defer func() { _ = svc.Shutdown() }()
+
+	l := newEventLog(2, false)
+	publishLifecycle(l, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleEvents(w, r, l, svc)
+	}))
+	// Registered first so it runs after the response bodies are closed
+	t.Cleanup(server.Close)
+
+	get := func(lastEventID string) *bufio.Reader {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return bufio.NewReader(resp.Body)
+	}
+
+	// Replay of event 3, then a live event
+	r := get("2")
+	got := readSSE(t, r, 1)
+	if got[0]["id"] != "3" || got[0]["event"] != "queued" {
+		t.Fatalf("Expected replay of event 3, got %v", got)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		publishLifecycle(l, 1)
+	}()
+	if got = readSSE(t, r, 1); got[0]["id"] != "4" {
+		t.Fatalf("Expected live event 4, got %v", got)
+	}
+
+	// Event 2 has been evicted: snapshot at the current ID
+	r = get("1")
+	got = readSSE(t, r, 1)
+	if got[0]["event"] != "snapshot" || got[0]["id"] != "4" {
+		t.Fatalf("Expected snapshot at id 4, got %v", got)
+	}
+	var downloads []json.RawMessage
+	if err := json.Unmarshal([]byte(got[0]["data"]), &downloads); err != nil {
+		t.Errorf("Snapshot data is not a download list: %v", err)
+	}
+
+	// New clients are told the current ID without any replay
+	r = get("")
+	line, _ := r.ReadString('\n')
+	if line != "id: 4\n" {
+		t.Errorf("Expected initial id line, got %q", line)
+	}
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// queueFileVersion is the format version written by surge export
+const queueFileVersion = 1
+
+// redactedValue replaces credential header values, and all header values
+// in exports made with --redact
+const redactedValue = "REDACTED"
+
+// queueFile is the document written by surge export and read by surge import
+type queueFile struct {
+	Version    int        `json:"version"`
+	ExportedAt int64      `json:"exported_at"` // Unix timestamp
+	Jobs       []queueJob `json:"jobs"`        // In queue order
+}
+
+// queueJob is one unfinished download in a queue export
+type queueJob struct {
+	ID          string               `json:"id"`
+	URL         string               `json:"url"`
+	Mirrors     []string             `json:"mirrors,omitempty"` // Excluding URL
+	Headers     map[string]string    `json:"headers,omitempty"`
+	Dir         string               `json:"dir"`
+	Filename    string               `json:"filename,omitempty"` // Empty until a queued download has been probed
+	Status      string               `json:"status"`             // queued, downloading, paused or error
+	TotalSize   int64                `json:"total_size,omitempty"`
+	Downloaded  int64                `json:"downloaded,omitempty"`
+	RangeStart  int64                `json:"range_start,omitempty"`
+	RangeLength int64                `json:"range_length,omitempty"`
+	State       *types.DownloadState `json:"state,omitempty"` // Remaining tasks and chunk bitmap of paused downloads
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write unfinished downloads to a file for surge import",
+	Long: `Write queued, running, paused and failed downloads as JSON, for moving them to
+another machine with surge import.
+
+Paused downloads carry their remaining byte ranges and chunk bitmap, so copying
+their .surge files along lets the import resume them exactly. Pause running
+downloads first (surge pause --all) to include their progress. Credential
+headers such as cookies and Authorization are replaced with REDACTED unless
+--include-secrets is given; --redact replaces every header value.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		jobs, err := collectQueueJobs(daemonPort())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		redactAll, _ := cmd.Flags().GetBool("redact")
+		includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+		if redactAll || !includeSecrets {
+			redactHeaders(jobs, redactAll)
+		}
+
+		out := os.Stdout
+		if output, _ := cmd.Flags().GetString("output"); output != "" {
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(queueFile{Version: queueFileVersion, ExportedAt: time.Now().Unix(), Jobs: jobs}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d downloads.\n", len(jobs))
+	},
+}
+
+// collectQueueJobs returns the unfinished downloads: the running daemon's
+// (if port is set) in queue order, then paused and failed ones from the database
+func collectQueueJobs(port int) ([]queueJob, error) {
+	var jobs []queueJob
+	seen := make(map[string]bool)
+
+	entries, err := state.ListAllDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load downloads: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.Status != "completed" {
+			ids = append(ids, e.ID)
+		}
+	}
+	states, err := state.LoadStates(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load download state: %w", err)
+	}
+
+	// Downloads with saved progress are exported from the database even when
+	// the daemon is running them, so their .surge files can be reused
+	resumable := func(id string) bool { return states[id] != nil && len(states[id].Tasks) > 0 }
+
+	if port > 0 {
+		live, err := fetchDaemonQueue(port)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range live {
+			if !resumable(job.ID) {
+				jobs = append(jobs, job)
+				seen[job.ID] = true
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if e.Status == "completed" || seen[e.ID] {
+			continue
+		}
+		job := queueJob{
+			ID: e.ID, URL: e.URL, Mirrors: withoutURL(e.Mirrors, e.URL),
+			Dir: filepath.Dir(e.DestPath), Filename: e.Filename, Status: e.Status,
+			TotalSize: e.TotalSize, Downloaded: e.Downloaded,
+		}
+		if st := states[e.ID]; st != nil {
+			job.Headers = st.Headers
+			job.RangeStart, job.RangeLength = st.RangeStart, st.RangeLength
+			if len(st.Mirrors) > 0 {
+				job.Mirrors = withoutURL(st.Mirrors, e.URL)
+			}
+			if resumable(e.ID) {
+				job.State = st
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// fetchDaemonQueue asks the daemon on this machine for its running and waiting downloads
+func fetchDaemonQueue(port int) ([]queueJob, error) {
+	baseURL, client := localClient(port)
+	req, err := http.NewRequest(http.MethodGet, baseURL+apiPrefix+"/queue", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+ensureAuthToken())
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, errors.New(apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	var jobs []queueJob
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode queue: %w", err)
+	}
+	return jobs, nil
+}
+
+// jobFromConfig describes a running or waiting download
+func jobFromConfig(cfg types.DownloadConfig) queueJob {
+	job := queueJob{
+		ID: cfg.ID, URL: cfg.URL, Mirrors: withoutURL(cfg.Mirrors, cfg.URL), Headers: cfg.Headers,
+		Dir: cfg.OutputPath, Filename: cfg.Filename, Status: "queued",
+		RangeStart: cfg.RangeStart, RangeLength: cfg.RangeLength,
+	}
+	if cfg.DestPath != "" {
+		job.Dir = filepath.Dir(cfg.DestPath)
+	}
+	if cfg.State != nil {
+		job.Downloaded, job.TotalSize, _, _, _, _ = cfg.State.GetProgress()
+		if dp := cfg.State.GetDestPath(); dp != "" {
+			job.Dir = filepath.Dir(dp)
+		}
+	}
+	return job
+}
+
+// redactHeaders replaces the values of credential headers, or of every
+// header if all is set, keeping the names so the importer can tell which ones
+// to supply again
+func redactHeaders(jobs []queueJob, all bool) {
+	for i := range jobs {
+		redacted := make(map[string]string, len(jobs[i].Headers))
+		for name, value := range jobs[i].Headers {
+			if all || isCredentialHeader(name) {
+				value = redactedValue
+			}
+			redacted[name] = value
+		}
+		if len(redacted) > 0 {
+			jobs[i].Headers = redacted
+		}
+		if jobs[i].State != nil && len(jobs[i].State.Headers) > 0 {
+			st := *jobs[i].State
+			st.Headers = redacted
+			jobs[i].State = &st
+		}
+	}
+}
+
+// isCredentialHeader reports whether a header named name likely carries a
+// secret, such as a cookie, bearer token or API key
+func isCredentialHeader(name string) bool {
+	lower := strings.ToLower(name)
+	switch lower {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	for _, s := range []string{"token", "secret", "api-key", "apikey", "password", "session"} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutURL(mirrors []string, url string) []string {
+	var out []string
+	for _, m := range mirrors {
+		if m != url {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	exportCmd.Flags().Bool("redact", false, "Replace every request header value with "+redactedValue)
+	exportCmd.Flags().Bool("include-secrets", false, "Keep cookie, Authorization and other credential header values")
+	rootCmd.AddCommand(exportCmd)
+}
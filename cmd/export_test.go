@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// importRecorder records the calls surge import makes
+type importRecorder struct {
+	core.DownloadService
+	known   map[string]bool
+	added   []string
+	mirrors [][]string
+	headers []map[string]string
+	resumed []string
+}
+
+func (s *importRecorder) GetStatus(id string) (*types.DownloadStatus, error) {
+	if s.known[id] {
+		return &types.DownloadStatus{ID: id}, nil
+	}
+	return nil, errors.New("download not found")
+}
+
+func (s *importRecorder) AddWithOptions(url, path, filename string, mirrors []string, headers map[string]string, opts types.DownloadOptions) (string, error) {
+	s.added = append(s.added, filepath.Join(path, filename))
+	s.mirrors = append(s.mirrors, mirrors)
+	s.headers = append(s.headers, headers)
+	return "new-" + filename, nil
+}
+
+func (s *importRecorder) Resume(id string) error {
+	s.resumed = append(s.resumed, id)
+	return nil
+}
+
+func TestCollectQueueJobs(t *testing.T) {
+	setupIsolatedCmdState(t)
+	dir := t.TempDir()
+
+	paused := &types.DownloadState{
+		ID: "p", URL: "https://example.com/big.iso", DestPath: filepath.Join(dir, "big.iso"), Filename: "big.iso",
+		TotalSize: 1000, Downloaded: 400, Tasks: []types.Task{{Offset: 400, Length: 600}},
+		Mirrors: []string{"https://example.com/big.iso", "https://mirror.example.org/big.iso"},
+		Headers: map[string]string{"Authorization": "Bearer abc", "Accept": "*/*"},
+	}
+	if err := state.SaveState(paused.URL, paused.DestPath, paused); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	for _, e := range []types.DownloadEntry{
+		{ID: "e", URL: "https://example.com/broken.zip", DestPath: filepath.Join(dir, "broken.zip"), Filename: "broken.zip", Status: "error"},
+		{ID: "c", URL: "https://example.com/done.zip", DestPath: filepath.Join(dir, "done.zip"), Filename: "done.zip", Status: "completed"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("Failed to seed download: %v", err)
+		}
+	}
+
+	jobs, err := collectQueueJobs(0)
+	if err != nil {
+		t.Fatalf("collectQueueJobs failed: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("Expected the paused and failed downloads, got %+v", jobs)
+	}
+	byID := map[string]queueJob{}
+	for _, j := range jobs {
+		byID[j.ID] = j
+	}
+
+	p := byID["p"]
+	if p.Dir != dir || p.Filename != "big.iso" || p.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("Unexpected paused job: %+v", p)
+	}
+	if len(p.Mirrors) != 1 || p.Mirrors[0] != "https://mirror.example.org/big.iso" {
+		t.Errorf("Expected only the extra mirror, got %v", p.Mirrors)
+	}
+	if p.State == nil || len(p.State.Tasks) != 1 || p.State.Tasks[0].Offset != 400 {
+		t.Errorf("Expected the saved tasks, got %+v", p.State)
+	}
+	if e := byID["e"]; e.Status != "error" || e.State != nil {
+		t.Errorf("Unexpected failed job: %+v", e)
+	}
+
+	// By default only credentials are redacted; --redact covers every header
+	for _, all := range []bool{false, true} {
+		redacted := append([]queueJob(nil), jobs...)
+		redactHeaders(redacted, all)
+		if got := byID["p"].Headers["Authorization"]; got != "Bearer abc" {
+			t.Fatalf("redactHeaders changed the original map: %q", got)
+		}
+		wantAccept := "*/*"
+		if all {
+			wantAccept = redactedValue
+		}
+		for _, j := range redacted {
+			if j.ID != "p" {
+				continue
+			}
+			if j.Headers["Authorization"] != redactedValue || j.State.Headers["Authorization"] != redactedValue {
+				t.Errorf("Expected a redacted Authorization header, got %v and %v", j.Headers, j.State.Headers)
+			}
+			if j.Headers["Accept"] != wantAccept {
+				t.Errorf("all=%v: expected Accept %q, got %q", all, wantAccept, j.Headers["Accept"])
+			}
+		}
+	}
+}
+
+func TestIsCredentialHeader(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Authorization": true, "cookie": true, "Proxy-Authorization": true,
+		"X-Api-Key": true, "X-Auth-Token": true, "Referer": false, "User-Agent": false,
+	} {
+		if got := isCredentialHeader(name); got != want {
+			t.Errorf("isCredentialHeader(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestImportQueueJob(t *testing.T) {
+	setupIsolatedCmdState(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "big.iso"+types.IncompleteSuffix), make([]byte, 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &importRecorder{known: map[string]bool{"known": true}}
+	saved := &types.DownloadState{TotalSize: 1000, Downloaded: 400, Tasks: []types.Task{{Offset: 400, Length: 600}}, ChunkBitmap: []byte{3}}
+	headers := map[string]string{"Authorization": "Bearer abc", "Cookie": redactedValue}
+
+	// The .surge file is in the new directory: resume exactly
+	res, err := importQueueJob(svc, queueJob{ID: "p", URL: "https://example.com/big.iso", Dir: "/laptop/dl", Filename: "big.iso", Headers: headers, State: saved}, dir, false)
+	if err != nil || !res.Resumed || len(svc.resumed) != 1 || svc.resumed[0] != "p" {
+		t.Fatalf("Expected a resume, got %+v (%v), resumed %v", res, err, svc.resumed)
+	}
+	if len(res.Redacted) != 1 || res.Redacted[0] != "Cookie" {
+		t.Errorf("Expected the redacted Cookie header to be reported, got %v", res.Redacted)
+	}
+	loaded, err := state.LoadState("https://example.com/big.iso", filepath.Join(dir, "big.iso"))
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if len(loaded.Tasks) != 1 || loaded.Downloaded != 400 || len(loaded.ChunkBitmap) != 1 || len(loaded.Headers) != 1 {
+		t.Errorf("Unexpected saved state: %+v", loaded)
+	}
+
+	// No .surge file: queue from scratch, with mirrors and headers
+	res, err = importQueueJob(svc, queueJob{
+		ID: "q", URL: "https://example.com/other.iso", Dir: dir, Filename: "other.iso",
+		Mirrors: []string{"https://mirror.example.org/other.iso"}, Headers: headers, State: saved,
+	}, "", false)
+	if err != nil || res.Resumed || res.ID != "new-other.iso" {
+		t.Fatalf("Expected a fresh queue, got %+v (%v)", res, err)
+	}
+	if len(svc.mirrors[0]) != 2 || svc.mirrors[0][0] != "https://example.com/other.iso" || svc.headers[0]["Authorization"] != "Bearer abc" || len(svc.headers[0]) != 1 {
+		t.Errorf("Unexpected add: mirrors %v, headers %v", svc.mirrors[0], svc.headers[0])
+	}
+
+	// --fresh ignores the copied .surge file
+	if res, err = importQueueJob(svc, queueJob{ID: "r", URL: "https://example.com/big.iso", Filename: "big.iso", State: saved}, dir, true); err != nil || res.Resumed {
+		t.Errorf("Expected --fresh to queue from scratch, got %+v (%v)", res, err)
+	}
+
+	if res, err = importQueueJob(svc, queueJob{ID: "known", URL: "https://example.com/x"}, dir, false); err != nil || !res.Skipped {
+		t.Errorf("Expected a known download to be skipped, got %+v (%v)", res, err)
+	}
+}
+
+func TestAPI_QueueNeedsFullScope(t *testing.T) {
+	server := newTestAPI(t)
+
+	var jobs []queueJob
+	if resp := apiCall(t, server, http.MethodGet, "/queue", "", &jobs); resp.StatusCode != http.StatusOK || len(jobs) != 0 {
+		t.Fatalf("Expected an empty queue, got %d: %+v", resp.StatusCode, jobs)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req := httptest.NewRequest(method, apiPrefix+"/queue", nil)
+		if scope := requiredScope(req); scope != scopeFull {
+			t.Errorf("%s /queue needs %q, want %q", method, scope, scopeFull)
+		}
+	}
+}
+
+func TestAPI_ImportQueue(t *testing.T) {
+	server := newTestAPI(t)
+	dir := t.TempDir()
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"job":{"id":"x","dir":"` + dir + `"}}`, http.StatusBadRequest},
+		{`{"job":{"url":"https://example.com/a","dir":"relative"}}`, http.StatusBadRequest},
+		{`{"job":{"url":"https://example.com/a","filename":"../a","dir":"` + dir + `"}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := apiCall(t, server, http.MethodPost, "/queue", tt.body, nil); resp.StatusCode != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.body, resp.StatusCode, tt.code)
+		}
+	}
+
+	var res importResult
+	body := `{"job":{"id":"c","url":"https://example.com/gamma.zip","filename":"gamma.zip"},"dir":"` + dir + `"}`
+	if resp := apiCall(t, server, http.MethodPost, "/queue", body, &res); resp.StatusCode != http.StatusOK || !res.Skipped || res.ID != "c" {
+		t.Errorf("Expected a known download to be skipped, got %d: %+v", resp.StatusCode, res)
+	}
+}
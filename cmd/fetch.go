@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Exit codes of surge fetch, following wget's where they overlap
+const (
+	fetchExitError       = 1   // Anything not covered below
+	fetchExitUsage       = 2   // Invalid arguments or flags
+	fetchExitIO          = 3   // Local file error: can't create, write or rename
+	fetchExitNetwork     = 4   // DNS, connection or timeout failure
+	fetchExitTLS         = 5   // Certificate verification failed
+	fetchExitServer      = 8   // Server answered with an error status
+	fetchExitInterrupted = 130 // Stopped by SIGINT or SIGTERM
+)
+
+// fetchProgressInterval is how often the progress line is redrawn
+const fetchProgressInterval = 250 * time.Millisecond
+
+// fetchOptions describes one surge fetch run
+type fetchOptions struct {
+	URL     string
+	Mirrors []string
+	Output  string // File, directory, "-" for stdout, or "" for the current directory
+	Headers map[string]string
+	Quiet   bool
+}
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch <url>",
+	Short: "Download one file in the foreground, without the daemon or TUI",
+	Long: `Download a single file with the concurrent engine and exit, like curl or wget.
+
+fetch runs entirely in this process: it starts no server, shows no TUI and
+does not take the instance lock, so it works alongside a running daemon and
+in containers and CI jobs. A compact progress line is drawn on stderr when it
+is a terminal.
+
+The file is written to a temporary name next to its destination and renamed
+into place once complete, replacing any existing file. With -o - the file is
+written to stdout when the download finishes.
+
+Exit codes:
+  0    success
+  1    other error
+  2    invalid arguments
+  3    local file error
+  4    network failure
+  5    TLS certificate error
+  8    server returned an error status
+  130  interrupted`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := fetchOptionsFromFlags(cmd, args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(fetchExitUsage)
+		}
+
+		// Keep fetches out of the daemon's history: they get a throwaway state database
+		stateDir, err := os.MkdirTemp("", "surge-fetch-state-*")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(fetchExitIO)
+		}
+		state.Configure(filepath.Join(stateDir, "surge.db"))
+		if verbose {
+			utils.ConfigureDebug(config.GetLogsDir())
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		_, err = runFetch(ctx, opts, os.Stdout, os.Stderr)
+		stop()
+		state.CloseDB()
+		_ = os.RemoveAll(stateDir)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(fetchExitCode(err))
+		}
+	},
+}
+
+// fetchOptionsFromFlags validates the command line
+func fetchOptionsFromFlags(cmd *cobra.Command, args []string) (fetchOptions, error) {
+	var opts fetchOptions
+	if len(args) != 1 {
+		return opts, fmt.Errorf("expected exactly one URL, got %d", len(args))
+	}
+	opts.URL, opts.Mirrors = ParseURLArg(args[0])
+	if opts.URL == "" {
+		return opts, errors.New("no URL given")
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	document, _ := cmd.Flags().GetString("output-document")
+	if output != "" && document != "" {
+		return opts, errors.New("use only one of -o and -O")
+	}
+	opts.Output = output + document
+
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+	headers, err := parseHeaderFlags(headerFlags)
+	if err != nil {
+		return opts, err
+	}
+	opts.Headers = headers
+	opts.Quiet, _ = cmd.Flags().GetBool("quiet")
+	return opts, nil
+}
+
+// runFetch downloads opts.URL and returns where it was saved ("-" for stdout)
+func runFetch(ctx context.Context, opts fetchOptions, stdout io.Writer, stderr io.Writer) (string, error) {
+	toStdout := opts.Output == "-"
+	targetDir, targetName := ".", ""
+	switch {
+	case toStdout:
+		targetDir = os.TempDir()
+	case opts.Output == "":
+	case strings.HasSuffix(opts.Output, "/") || strings.HasSuffix(opts.Output, string(filepath.Separator)):
+		targetDir = opts.Output
+	default:
+		if info, err := os.Stat(opts.Output); err == nil && info.IsDir() {
+			targetDir = opts.Output
+		} else {
+			targetDir, targetName = filepath.Dir(opts.Output), filepath.Base(opts.Output)
+		}
+	}
+
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// Downloading beside the target keeps the final rename on one filesystem
+	scratch, err := os.MkdirTemp(targetDir, ".surge-fetch-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(scratch) }()
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = config.DefaultSettings()
+	}
+	id := uuid.New().String()
+	progress := types.NewProgressState(id, 0)
+	cfg := types.DownloadConfig{
+		URL:        opts.URL,
+		OutputPath: scratch,
+		ID:         id,
+		Filename:   targetName,
+		State:      progress,
+		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+		Mirrors:    opts.Mirrors,
+		Headers:    opts.Headers,
+	}
+
+	start := time.Now()
+	stopProgress := func() {}
+	if !opts.Quiet && isTerminal(stderr) {
+		stopProgress = showFetchProgress(stderr, progress)
+	}
+	err = download.TUIDownload(ctx, &cfg)
+	stopProgress()
+	if err == nil && ctx.Err() != nil {
+		// The engine treats cancellation as a clean stop
+		err = ctx.Err()
+	}
+	if err != nil {
+		return "", err
+	}
+	elapsed := time.Since(start)
+
+	downloaded := progress.GetDestPath()
+	info, err := os.Stat(downloaded)
+	if err != nil {
+		return "", fmt.Errorf("downloaded file is missing: %w", err)
+	}
+
+	dest := "-"
+	if toStdout {
+		f, err := os.Open(downloaded)
+		if err != nil {
+			return "", fmt.Errorf("failed to read downloaded file: %w", err)
+		}
+		_, err = io.Copy(stdout, f)
+		_ = f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to write to stdout: %w", err)
+		}
+	} else {
+		dest = filepath.Join(targetDir, filepath.Base(downloaded))
+		if err := os.Rename(downloaded, dest); err != nil {
+			return "", fmt.Errorf("failed to move download into place: %w", err)
+		}
+	}
+
+	if !opts.Quiet {
+		name := dest
+		if toStdout {
+			name = "stdout"
+		}
+		_, _ = fmt.Fprintf(stderr, "Saved %s (%s in %s, %s/s)\n", name,
+			utils.ConvertBytesToHumanReadable(info.Size()), elapsed.Round(time.Millisecond),
+			utils.ConvertBytesToHumanReadable(int64(float64(info.Size())/max(elapsed.Seconds(), 0.001))))
+	}
+	return dest, nil
+}
+
+// showFetchProgress redraws a progress line on w until the returned stop
+// function is called, which clears the line
+func showFetchProgress(w io.Writer, progress *types.ProgressState) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(fetchProgressInterval)
+		defer ticker.Stop()
+		width := 0
+		for {
+			select {
+			case <-done:
+				_, _ = fmt.Fprintf(w, "\r%s\r", strings.Repeat(" ", width))
+				return
+			case <-ticker.C:
+				downloaded, total, _, sessionElapsed, _, sessionStart := progress.GetProgress()
+				line := formatFetchProgress(downloaded, total, downloaded-sessionStart, sessionElapsed)
+				_, _ = fmt.Fprintf(w, "\r%-*s", width, line)
+				width = max(width, len(line))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// formatFetchProgress renders the progress line: percentage and sizes,
+// average speed over this session and the time left when the size is known
+func formatFetchProgress(downloaded, total, sessionBytes int64, sessionElapsed time.Duration) string {
+	var speed float64
+	if secs := sessionElapsed.Seconds(); secs > 0 {
+		speed = float64(sessionBytes) / secs
+	}
+	rate := utils.ConvertBytesToHumanReadable(int64(speed)) + "/s"
+
+	if total <= 0 {
+		return fmt.Sprintf("%s  %s", utils.ConvertBytesToHumanReadable(downloaded), rate)
+	}
+	line := fmt.Sprintf("%3.0f%%  %s / %s  %s", float64(downloaded)*100/float64(total),
+		utils.ConvertBytesToHumanReadable(downloaded), utils.ConvertBytesToHumanReadable(total), rate)
+	if speed > 0 && downloaded < total {
+		eta := time.Duration(float64(total-downloaded) / speed * float64(time.Second))
+		line += "  ETA " + eta.Round(time.Second).String()
+	}
+	return line
+}
+
+// isTerminal reports whether w is a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// fetchExitCode maps a fetch failure to the documented exit status
+func fetchExitCode(err error) int {
+	var statusErr *types.HTTPStatusError
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var netErr net.Error
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return fetchExitInterrupted
+	case errors.As(err, &statusErr):
+		return fetchExitServer
+	case errors.As(err, &certErr), errors.As(err, &authorityErr), errors.As(err, &hostErr):
+		return fetchExitTLS
+	case errors.As(err, &netErr):
+		return fetchExitNetwork
+	case errors.Is(err, types.ErrInsufficientDiskSpace), errors.As(err, &pathErr), errors.As(err, &linkErr):
+		return fetchExitIO
+	default:
+		return fetchExitError
+	}
+}
+
+func init() {
+	fetchCmd.Flags().StringP("output", "o", "", "Save to this file or directory (- for stdout; default: current directory)")
+	fetchCmd.Flags().StringP("output-document", "O", "", "Same as -o, as in wget")
+	fetchCmd.Flags().BoolP("quiet", "q", false, "Print nothing but errors")
+	fetchCmd.Flags().StringArrayP("header", "H", nil, "Extra HTTP header as \"Key: Value\" (repeatable)")
+	fetchCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(fetchExitUsage)
+		return nil
+	})
+	rootCmd.AddCommand(fetchCmd)
+}
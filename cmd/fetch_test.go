@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestRunFetch_Destinations(t *testing.T) {
+	setupIsolatedCmdState(t)
+	const size = 256 * 1024
+	mock := testutil.NewMockServerT(t, testutil.WithFileSize(size), testutil.WithRangeSupport(true), testutil.WithFilename("data.bin"))
+	dir := t.TempDir()
+
+	// An existing file is replaced, not renamed around
+	target := filepath.Join(dir, "out", "named.bin")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	dest, err := runFetch(context.Background(), fetchOptions{URL: mock.URL(), Output: target}, nil, &stderr)
+	if err != nil {
+		t.Fatalf("runFetch failed: %v", err)
+	}
+	if dest != target {
+		t.Errorf("Expected %s, got %s", target, dest)
+	}
+	if info, err := os.Stat(target); err != nil || info.Size() != size {
+		t.Fatalf("Expected %d-byte file, got %v (%v)", size, info, err)
+	}
+	if !strings.Contains(stderr.String(), "Saved "+target) {
+		t.Errorf("Expected a summary line, got %q", stderr.String())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the downloaded file to remain, got %v", entries)
+	}
+
+	// A directory keeps the server's file name
+	dest, err = runFetch(context.Background(), fetchOptions{URL: mock.URL(), Output: dir + string(filepath.Separator), Quiet: true}, nil, &stderr)
+	if err != nil || filepath.Dir(dest) != filepath.Clean(dir) {
+		t.Fatalf("Expected download into %s, got %s (%v)", dir, dest, err)
+	}
+
+	// Stdout gets the bytes and nothing is left behind
+	var stdout bytes.Buffer
+	stderr.Reset()
+	if dest, err := runFetch(context.Background(), fetchOptions{URL: mock.URL(), Output: "-", Quiet: true}, &stdout, &stderr); err != nil || dest != "-" {
+		t.Fatalf("Expected stdout download, got %s (%v)", dest, err)
+	}
+	if stdout.Len() != size || stderr.Len() != 0 {
+		t.Errorf("Expected %d bytes on stdout and a quiet stderr, got %d and %q", size, stdout.Len(), stderr.String())
+	}
+}
+
+func TestRunFetch_ServerErrorExitCode(t *testing.T) {
+	setupIsolatedCmdState(t)
+	mock := testutil.NewMockServerT(t, testutil.WithHandler(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+
+	dir := t.TempDir()
+	_, err := runFetch(context.Background(), fetchOptions{URL: mock.URL(), Output: filepath.Join(dir, "f"), Quiet: true}, nil, &bytes.Buffer{})
+	if code := fetchExitCode(err); code != fetchExitServer {
+		t.Errorf("Expected exit %d for a 404, got %d (%v)", fetchExitServer, code, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Failed fetch should leave nothing behind, got %v", entries)
+	}
+}
+
+func TestFetchExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("probe: %w", context.Canceled), fetchExitInterrupted},
+		{fmt.Errorf("probe: %w", &types.HTTPStatusError{StatusCode: 503}), fetchExitServer},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, fetchExitNetwork},
+		{fmt.Errorf("save: %w", &os.PathError{Op: "open", Path: "/x", Err: os.ErrPermission}), fetchExitIO},
+		{types.ErrInsufficientDiskSpace, fetchExitIO},
+		{errors.New("something else"), fetchExitError},
+	}
+	for _, tt := range tests {
+		if got := fetchExitCode(tt.err); got != tt.want {
+			t.Errorf("fetchExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFormatFetchProgress(t *testing.T) {
+	line := formatFetchProgress(512*1024*1024, 1024*1024*1024, 512*1024*1024, 8*time.Second)
+	for _, want := range []string{"50%", "/s", "ETA 8s"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in %q", want, line)
+		}
+	}
+	if line := formatFetchProgress(1024, 0, 1024, time.Second); strings.Contains(line, "%") || strings.Contains(line, "ETA") {
+		t.Errorf("Unknown size should show no percentage or ETA, got %q", line)
+	}
+}
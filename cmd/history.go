@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// historyFilter selects download history entries. Zero fields match everything.
+type historyFilter struct {
+	Since, Until     int64 // Completion time bounds as Unix times, Until exclusive
+	Statuses         []string
+	Host             string // Matches the host and its subdomains
+	MinSize, MaxSize int64
+	Name             string // Glob matched against the filename, case-insensitively
+}
+
+// matches reports whether e passes every condition of f
+func (f historyFilter) matches(e types.DownloadEntry) bool {
+	if (f.Since > 0 && e.CompletedAt < f.Since) || (f.Until > 0 && (e.CompletedAt == 0 || e.CompletedAt >= f.Until)) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsFold(f.Statuses, e.Status) {
+		return false
+	}
+	if f.Host != "" {
+		host := strings.ToLower(entryHost(e))
+		want := strings.ToLower(f.Host)
+		if host != want && !strings.HasSuffix(host, "."+want) {
+			return false
+		}
+	}
+	if (f.MinSize > 0 && e.TotalSize < f.MinSize) || (f.MaxSize > 0 && e.TotalSize > f.MaxSize) {
+		return false
+	}
+	if f.Name != "" {
+		// The pattern is validated when the filter is built
+		if ok, _ := path.Match(strings.ToLower(f.Name), strings.ToLower(e.Filename)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// filterHistory returns the entries f selects, sorted by key ("time",
+// "speed" or "size"), largest or newest first unless reverse is set
+func filterHistory(entries []types.DownloadEntry, f historyFilter, key string, reverse bool) ([]types.DownloadEntry, error) {
+	var less func(a, b types.DownloadEntry) bool
+	switch key {
+	case "time", "":
+		less = func(a, b types.DownloadEntry) bool { return a.CompletedAt < b.CompletedAt }
+	case "speed":
+		less = func(a, b types.DownloadEntry) bool { return entrySpeed(a) < entrySpeed(b) }
+	case "size":
+		less = func(a, b types.DownloadEntry) bool { return a.TotalSize < b.TotalSize }
+	default:
+		return nil, fmt.Errorf("invalid sort key %q: use time, speed or size", key)
+	}
+
+	var selected []types.DownloadEntry
+	for _, e := range entries {
+		if f.matches(e) {
+			selected = append(selected, e)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if reverse {
+			return less(selected[i], selected[j])
+		}
+		return less(selected[j], selected[i])
+	})
+	return selected, nil
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Search and export the download history",
+	Long: `List downloads recorded in the database, newest first, with optional filters.
+
+Times for --since and --until are ages (24h, 7d), dates (2006-01-02) or RFC 3339
+timestamps, and apply to the completion time. Sizes take suffixes (100MB, 2GB).
+--name is a glob such as '*.iso', matched case-insensitively.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		entries, err := historyFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		if err := writeHistory(os.Stdout, entries, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var historyRmCmd = &cobra.Command{
+	Use:   "rm",
+	Short: "Remove finished downloads from the history",
+	Long: `Remove the completed and failed downloads matching the filters from the
+history. Downloaded files are left in place. Queued, paused and active
+downloads are never removed. Use --all to remove every finished download.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		all, _ := cmd.Flags().GetBool("all")
+		if !all && !historyFiltered(cmd) {
+			fmt.Fprintln(os.Stderr, "Error: provide at least one filter or use --all")
+			os.Exit(1)
+		}
+
+		entries, err := historyFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		var ids []string
+		for _, e := range entries {
+			if e.Status == "completed" || e.Status == "error" {
+				ids = append(ids, e.ID)
+			}
+		}
+
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			for _, e := range entries {
+				if e.Status == "completed" || e.Status == "error" {
+					fmt.Printf("%s  %s\n", shortID(e.ID), e.Filename)
+				}
+			}
+			fmt.Printf("Would remove %d downloads.\n", len(ids))
+			return
+		}
+
+		count, err := state.RemoveDownloads(ids)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %d downloads from the history.\n", count)
+	},
+}
+
+// historyFilterFlags are the flags that narrow down the entries
+var historyFilterFlags = []string{"since", "until", "status", "host", "min-size", "max-size", "name"}
+
+// historyFiltered reports whether any filter flag was given
+func historyFiltered(cmd *cobra.Command) bool {
+	for _, name := range historyFilterFlags {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// historyFromFlags loads the history and applies the command's filter and sort flags
+func historyFromFlags(cmd *cobra.Command) ([]types.DownloadEntry, error) {
+	filter, err := historyFilterFromFlags(cmd, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	entries, err := state.ListAllDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	sortKey, _ := cmd.Flags().GetString("sort")
+	reverse, _ := cmd.Flags().GetBool("reverse")
+	return filterHistory(entries, filter, sortKey, reverse)
+}
+
+// historyFilterFromFlags builds the filter from the command's flags
+func historyFilterFromFlags(cmd *cobra.Command, now time.Time) (historyFilter, error) {
+	var f historyFilter
+	for name, dest := range map[string]*int64{"since": &f.Since, "until": &f.Until} {
+		raw, _ := cmd.Flags().GetString(name)
+		if raw == "" {
+			continue
+		}
+		ts, err := parseTimeFilter(raw, now)
+		if err != nil {
+			return f, fmt.Errorf("invalid --%s: %w", name, err)
+		}
+		*dest = ts
+	}
+	for name, dest := range map[string]*int64{"min-size": &f.MinSize, "max-size": &f.MaxSize} {
+		raw, _ := cmd.Flags().GetString(name)
+		if raw == "" {
+			continue
+		}
+		n, err := utils.ParseSize(raw)
+		if err != nil {
+			return f, fmt.Errorf("invalid --%s: %w", name, err)
+		}
+		*dest = n
+	}
+
+	f.Statuses, _ = cmd.Flags().GetStringSlice("status")
+	f.Host, _ = cmd.Flags().GetString("host")
+	f.Name, _ = cmd.Flags().GetString("name")
+	if _, err := path.Match(f.Name, ""); err != nil {
+		return f, fmt.Errorf("invalid --name pattern %q: %w", f.Name, err)
+	}
+	return f, nil
+}
+
+// writeHistory writes entries as a table, json, csv or ndjson
+func writeHistory(out io.Writer, entries []types.DownloadEntry, format string) error {
+	switch format {
+	case "table", "":
+		if len(entries) == 0 {
+			_, err := fmt.Fprintln(out, "No downloads found.")
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tCOMPLETED\tSTATUS\tSIZE\tSPEED\tHOST\tFILENAME")
+		for _, e := range entries {
+			speed := "-"
+			if s := entrySpeed(e); s > 0 {
+				speed = utils.ConvertBytesToHumanReadable(int64(s)) + "/s"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", shortID(e.ID), formatUnix(e.CompletedAt, "-"), e.Status,
+				utils.ConvertBytesToHumanReadable(e.TotalSize), speed, orDash(entryHost(e)), e.Filename)
+		}
+		return w.Flush()
+
+	case "json":
+		if entries == nil {
+			entries = []types.DownloadEntry{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+
+	case "ndjson":
+		enc := json.NewEncoder(out)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"id", "url", "filename", "dest_path", "status", "total_size", "completed_at", "time_taken_ms", "avg_speed"})
+		for _, e := range entries {
+			completed := ""
+			if e.CompletedAt > 0 {
+				completed = time.Unix(e.CompletedAt, 0).UTC().Format(time.RFC3339)
+			}
+			_ = w.Write([]string{e.ID, e.URL, e.Filename, e.DestPath, e.Status, strconv.FormatInt(e.TotalSize, 10),
+				completed, strconv.FormatInt(e.TimeTaken, 10), strconv.FormatFloat(entrySpeed(e), 'f', 0, 64)})
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		return fmt.Errorf("invalid format %q: use table, json, csv or ndjson", format)
+	}
+}
+
+// entrySpeed returns the average speed of a finished download in bytes/sec
+func entrySpeed(e types.DownloadEntry) float64 {
+	if e.AvgSpeed > 0 {
+		return e.AvgSpeed
+	}
+	if e.TimeTaken > 0 {
+		return float64(e.TotalSize) * 1000 / float64(e.TimeTaken)
+	}
+	return 0
+}
+
+func entryHost(e types.DownloadEntry) string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// addHistoryFilterFlags registers the flags shared by history and history rm
+func addHistoryFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("since", "", "Only downloads completed since an age (24h, 7d), date or RFC 3339 time")
+	cmd.Flags().String("until", "", "Only downloads completed before an age, date or RFC 3339 time")
+	cmd.Flags().StringSlice("status", nil, "Only these statuses, comma-separated (completed, error, paused, queued)")
+	cmd.Flags().String("host", "", "Only downloads from this host or its subdomains")
+	cmd.Flags().String("min-size", "", "Only files at least this large (e.g. 100MB)")
+	cmd.Flags().String("max-size", "", "Only files at most this large")
+	cmd.Flags().String("name", "", "Only filenames matching this glob (e.g. '*.iso')")
+	cmd.Flags().String("sort", "time", "Sort by time, speed or size, largest or newest first")
+	cmd.Flags().Bool("reverse", false, "Reverse the sort order")
+}
+
+func init() {
+	addHistoryFilterFlags(historyCmd)
+	historyCmd.Flags().String("format", "table", "Output format: table, json, csv or ndjson")
+	historyCmd.Flags().Int("limit", 0, "Maximum number of downloads to show (0 for all)")
+
+	addHistoryFilterFlags(historyRmCmd)
+	historyRmCmd.Flags().Bool("all", false, "Remove every finished download")
+	historyRmCmd.Flags().Bool("dry-run", false, "Only list what would be removed")
+
+	historyCmd.AddCommand(historyRmCmd)
+	rootCmd.AddCommand(historyCmd)
+}
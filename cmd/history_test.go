@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func historyFixture() []types.DownloadEntry {
+	return []types.DownloadEntry{
+		{ID: "a", URL: "https://cdn.example.com/a.iso", Filename: "a.iso", Status: "completed", TotalSize: 4 << 30, CompletedAt: 1000, TimeTaken: 4000},
+		{ID: "b", URL: "https://example.com/b.zip", Filename: "B.ZIP", Status: "completed", TotalSize: 1 << 20, CompletedAt: 3000, AvgSpeed: 10 << 20},
+		{ID: "c", URL: "https://other.org/c.iso", Filename: "c.iso", Status: "error", TotalSize: 2 << 20, CompletedAt: 2000},
+		{ID: "d", URL: "https://notexample.com/d.bin", Filename: "d.bin", Status: "queued", TotalSize: 512},
+	}
+}
+
+func historyIDs(entries []types.DownloadEntry) string {
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFilterHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  historyFilter
+		sort    string
+		reverse bool
+		want    string
+	}{
+		{"newest first", historyFilter{}, "time", false, "b,c,a,d"},
+		{"oldest first", historyFilter{}, "time", true, "d,a,c,b"},
+		{"by size", historyFilter{}, "size", false, "a,c,b,d"},
+		{"by speed", historyFilter{}, "speed", false, "a,b,c,d"},
+		{"date range", historyFilter{Since: 1500, Until: 3000}, "time", false, "c"},
+		{"status", historyFilter{Statuses: []string{"Completed"}}, "time", false, "b,a"},
+		{"host and subdomains", historyFilter{Host: "example.com"}, "time", false, "b,a"},
+		{"size range", historyFilter{MinSize: 1 << 20, MaxSize: 2 << 20}, "time", false, "b,c"},
+		{"name glob", historyFilter{Name: "*.zip"}, "time", false, "b"},
+	}
+	for _, tt := range tests {
+		got, err := filterHistory(historyFixture(), tt.filter, tt.sort, tt.reverse)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ids := historyIDs(got); ids != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, ids, tt.want)
+		}
+	}
+
+	if _, err := filterHistory(historyFixture(), historyFilter{}, "name", false); err == nil {
+		t.Error("Expected an error for an unknown sort key")
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	entries := historyFixture()[:2]
+
+	var buf bytes.Buffer
+	if err := writeHistory(&buf, entries, "json"); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var decoded []types.DownloadEntry
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 {
+		t.Fatalf("Invalid JSON output (%v): %s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeHistory(&buf, entries, "ndjson"); err != nil {
+		t.Fatalf("ndjson: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Errorf("Expected 2 NDJSON lines, got %d", len(lines))
+	}
+
+	buf.Reset()
+	if err := writeHistory(&buf, entries, "csv"); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected a header and 2 records, got %d (%v)", len(records), err)
+	}
+	// a has no recorded speed, so it is derived from size and time taken
+	if records[1][0] != "a" || records[1][8] != "1073741824" {
+		t.Errorf("Unexpected CSV record: %v", records[1])
+	}
+
+	if err := writeHistory(&buf, entries, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// importResult is what happened to one imported job
+type importResult struct {
+	ID       string   `json:"id"`
+	Resumed  bool     `json:"resumed,omitempty"`  // Continued from the copied .surge file
+	Skipped  bool     `json:"skipped,omitempty"`  // Already known here
+	Redacted []string `json:"redacted,omitempty"` // Header names dropped because their values were redacted
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Queue the downloads from a surge export file",
+	Long: `Add the downloads in a file written by surge export to the running Surge instance,
+in the order they were exported.
+
+A paused download whose .surge file is found at its destination resumes exactly
+where it stopped. Everything else is queued from scratch. Use --dir when the
+files live somewhere else on this machine; copy the .surge files there too.
+Downloads that are already known here are skipped.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		file, err := readQueueFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		dir, _ := cmd.Flags().GetString("dir")
+		if dir != "" {
+			dir = utils.EnsureAbsPath(dir)
+		}
+		fresh, _ := cmd.Flags().GetBool("fresh")
+
+		port := daemonPort()
+		if port == 0 {
+			fmt.Println("Error: Surge is not running.")
+			fmt.Println("Start it with 'surge server start' or 'surge', then import again.")
+			os.Exit(1)
+		}
+
+		var imported, resumed, failed int
+		for _, job := range file.Jobs {
+			// The daemon writes the saved state and queues the download, so
+			// the import never races it for the database
+			res, err := sendImportRequest(apiImportRequest{Job: job, Dir: dir, Fresh: fresh}, port)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", job.URL, err)
+				failed++
+				continue
+			}
+			if len(res.Redacted) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %s was exported without its %s header values\n", job.URL, strings.Join(res.Redacted, ", "))
+			}
+			switch {
+			case res.Skipped:
+				fmt.Printf("Skipped %s: already known as %s\n", job.URL, shortID(res.ID))
+			case res.Resumed:
+				fmt.Printf("Resumed %s (%s)\n", orDefault(job.Filename, job.URL), shortID(res.ID))
+				imported++
+				resumed++
+			default:
+				fmt.Printf("Queued %s (%s)\n", orDefault(job.Filename, job.URL), shortID(res.ID))
+				imported++
+			}
+		}
+
+		fmt.Printf("Imported %d of %d downloads, %d resumed.\n", imported, len(file.Jobs), resumed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// sendImportRequest asks the daemon on this machine to import one job
+func sendImportRequest(req apiImportRequest, port int) (importResult, error) {
+	var res importResult
+	body, err := json.Marshal(req)
+	if err != nil {
+		return res, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	baseURL, client := localClient(port)
+	httpReq, err := http.NewRequest(http.MethodPost, baseURL+apiPrefix+"/queue", bytes.NewReader(body))
+	if err != nil {
+		return res, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+ensureAuthToken())
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return res, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Message != "" {
+			return res, errors.New(apiErr.Error.Message)
+		}
+		return res, fmt.Errorf("server returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return res, fmt.Errorf("failed to decode import result: %w", err)
+	}
+	return res, nil
+}
+
+// readQueueFile reads and checks a surge export file
+func readQueueFile(path string) (*queueFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var file queueFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s is not a surge export: %w", path, err)
+	}
+	if file.Version != queueFileVersion {
+		return nil, fmt.Errorf("%s has unsupported version %d (expected %d)", path, file.Version, queueFileVersion)
+	}
+	return &file, nil
+}
+
+// importQueueJob adds one exported download to service. Paused downloads
+// whose .surge file exists at the destination are written to the database
+// with their saved tasks and resumed; others are queued from scratch. A
+// non-empty dir replaces the exported destination directory.
+func importQueueJob(service core.DownloadService, job queueJob, dir string, fresh bool) (importResult, error) {
+	res := importResult{ID: job.ID}
+	if job.URL == "" {
+		return res, fmt.Errorf("job has no URL")
+	}
+	if job.ID != "" {
+		if _, err := service.GetStatus(job.ID); err == nil {
+			res.Skipped = true
+			return res, nil
+		}
+	}
+	if dir == "" {
+		dir = job.Dir
+	}
+
+	headers := make(map[string]string, len(job.Headers))
+	for name, value := range job.Headers {
+		if value == redactedValue {
+			res.Redacted = append(res.Redacted, name)
+			continue
+		}
+		headers[name] = value
+	}
+	sort.Strings(res.Redacted)
+	if len(headers) == 0 {
+		headers = nil
+	}
+
+	if !fresh && job.ID != "" && job.State != nil && len(job.State.Tasks) > 0 && job.Filename != "" {
+		destPath := filepath.Join(dir, job.Filename)
+		if _, err := os.Stat(destPath + types.IncompleteSuffix); err == nil {
+			saved := *job.State
+			saved.ID, saved.URL, saved.DestPath, saved.Filename = job.ID, job.URL, destPath, job.Filename
+			saved.Headers = headers
+			saved.FileHash = "" // Recomputed from the copied .surge file
+			if err := state.SaveState(job.URL, destPath, &saved); err != nil {
+				return res, fmt.Errorf("failed to save state: %w", err)
+			}
+			if err := service.Resume(job.ID); err != nil {
+				return res, fmt.Errorf("failed to resume: %w", err)
+			}
+			res.Resumed = true
+			return res, nil
+		}
+		utils.Debug("Import: no %s%s, queueing %s from scratch", destPath, types.IncompleteSuffix, job.URL)
+	}
+
+	var mirrors []string
+	if len(job.Mirrors) > 0 {
+		mirrors = append([]string{job.URL}, job.Mirrors...)
+	}
+	id, err := service.AddWithOptions(job.URL, dir, job.Filename, mirrors, headers, types.DownloadOptions{
+		RangeStart: job.RangeStart, RangeLength: job.RangeLength,
+	})
+	if err != nil {
+		return res, err
+	}
+	res.ID = id
+	return res, nil
+}
+
+func init() {
+	importCmd.Flags().String("dir", "", "Save into this directory instead of the exported ones")
+	importCmd.Flags().Bool("fresh", false, "Start every download from scratch, ignoring saved progress")
+	rootCmd.AddCommand(importCmd)
+}
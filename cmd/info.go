@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/concurrent"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Download strategies, as chosen by download.TUIDownload
+const (
+	strategyConcurrent = "concurrent"
+	strategySingle     = "single"
+)
+
+// urlInfo is everything surge decides about a URL before downloading it
+type urlInfo struct {
+	URL            string       `json:"url"`
+	Redirects      []string     `json:"redirects"`
+	Status         int          `json:"status"`
+	Protocol       string       `json:"protocol"`
+	TLSVersion     string       `json:"tls_version,omitempty"`
+	Filename       string       `json:"filename"`
+	FilenameSource string       `json:"filename_source"`
+	Size           int64        `json:"size"` // 0 when the server doesn't say
+	SupportsRange  bool         `json:"supports_range"`
+	ContentType    string       `json:"content_type,omitempty"`
+	ETag           string       `json:"etag,omitempty"`
+	LastModified   string       `json:"last_modified,omitempty"`
+	Strategy       string       `json:"strategy"`
+	Reason         string       `json:"reason,omitempty"` // Why the single-connection downloader is used
+	Connections    int          `json:"connections"`
+	ChunkSize      int64        `json:"chunk_size,omitempty"`
+	Sequential     bool         `json:"sequential,omitempty"`
+	Mirrors        []mirrorInfo `json:"mirrors,omitempty"`
+}
+
+// mirrorInfo is the probe outcome of one mirror
+type mirrorInfo struct {
+	URL    string `json:"url"`
+	Usable bool   `json:"usable"`
+	Error  string `json:"error,omitempty"`
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <url>",
+	Short: "Probe a URL and show how Surge would download it",
+	Long: `Probe a URL, and any mirrors given as a comma-separated list, without
+downloading it. Shows the filename and where it came from, the size, range
+support, redirects, validators and protocol, and the number of connections
+and chunk size the current settings would start with.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		headerFlags, _ := cmd.Flags().GetStringArray("header")
+		headers, err := parseHeaderFlags(headerFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		url, mirrors := ParseURLArg(args[0])
+		if url == "" {
+			fmt.Fprintln(os.Stderr, "Error: no URL given")
+			os.Exit(1)
+		}
+		settings, err := config.LoadSettings()
+		if err != nil {
+			settings = config.DefaultSettings()
+		}
+
+		info, err := probeURLInfo(context.Background(), url, mirrors, headers, types.ConvertRuntimeConfig(settings.ToRuntimeConfig()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(info); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printURLInfo(os.Stdout, info)
+	},
+}
+
+// probeURLInfo probes url and its mirrors the way a download would
+func probeURLInfo(ctx context.Context, url string, mirrors []string, headers map[string]string, runtime *types.RuntimeConfig) (*urlInfo, error) {
+	probe, err := engine.ProbeServer(ctx, url, "", headers)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &urlInfo{
+		URL:            url,
+		Redirects:      probe.Redirects,
+		Status:         probe.StatusCode,
+		Protocol:       probe.Proto,
+		TLSVersion:     probe.TLSVersion,
+		Filename:       probe.Filename,
+		FilenameSource: probe.FilenameSource,
+		Size:           probe.FileSize,
+		SupportsRange:  probe.SupportsRange,
+		ContentType:    probe.ContentType,
+		ETag:           probe.ETag,
+		LastModified:   probe.LastModified,
+		Strategy:       strategySingle,
+		Connections:    1,
+	}
+	if info.Redirects == nil {
+		info.Redirects = []string{}
+	}
+
+	switch {
+	case !probe.SupportsRange:
+		info.Reason = "server does not support range requests"
+	case probe.FileSize <= 0:
+		info.Reason = "server did not report the file size"
+	default:
+		info.Strategy = strategyConcurrent
+		info.Connections, info.ChunkSize = concurrent.PlanConnections(runtime, probe.FileSize)
+		info.Sequential = runtime.SequentialDownload
+	}
+
+	// ParseURLArg lists the primary URL among the mirrors
+	var others []string
+	for _, m := range mirrors {
+		if m != url {
+			others = append(others, m)
+		}
+	}
+	if len(others) > 0 {
+		valid, errs := engine.ProbeMirrors(ctx, others)
+		for _, m := range valid {
+			info.Mirrors = append(info.Mirrors, mirrorInfo{URL: m, Usable: true})
+		}
+		for m, err := range errs {
+			info.Mirrors = append(info.Mirrors, mirrorInfo{URL: m, Error: err.Error()})
+		}
+		sort.Slice(info.Mirrors, func(i, j int) bool { return info.Mirrors[i].URL < info.Mirrors[j].URL })
+	}
+	return info, nil
+}
+
+// printURLInfo writes info as an aligned report
+func printURLInfo(out io.Writer, info *urlInfo) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	row := func(label, value string) { _, _ = fmt.Fprintf(w, "%s:\t%s\n", label, value) }
+
+	row("URL", info.URL)
+	for i, r := range info.Redirects {
+		row(fmt.Sprintf("Redirect %d", i+1), r)
+	}
+	protocol := info.Protocol
+	if info.TLSVersion != "" {
+		protocol += " over " + info.TLSVersion
+	}
+	row("Status", fmt.Sprintf("%d (%s)", info.Status, protocol))
+	row("Filename", fmt.Sprintf("%s (from %s)", info.Filename, info.FilenameSource))
+	if info.Size > 0 {
+		row("Size", fmt.Sprintf("%s (%d bytes)", utils.ConvertBytesToHumanReadable(info.Size), info.Size))
+	} else {
+		row("Size", "unknown")
+	}
+	row("Content type", orDash(info.ContentType))
+	row("Range requests", yesNo(info.SupportsRange))
+	row("ETag", orDash(info.ETag))
+	row("Last modified", orDash(info.LastModified))
+
+	if info.Strategy == strategyConcurrent {
+		strategy := fmt.Sprintf("concurrent, %d connections, %s chunks",
+			info.Connections, utils.ConvertBytesToHumanReadable(info.ChunkSize))
+		if info.Sequential {
+			strategy += ", sequential"
+		}
+		row("Download", strategy)
+	} else {
+		row("Download", "single connection ("+info.Reason+")")
+	}
+
+	for _, m := range info.Mirrors {
+		status := "usable"
+		if !m.Usable {
+			status = "not used: " + m.Error
+		}
+		row("Mirror", m.URL+" ("+status+")")
+	}
+	_ = w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	infoCmd.Flags().Bool("json", false, "Output as JSON")
+	infoCmd.Flags().StringArrayP("header", "H", nil, "Extra HTTP header as \"Key: Value\" (repeatable)")
+	rootCmd.AddCommand(infoCmd)
+}
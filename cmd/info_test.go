@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/concurrent"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+func TestProbeURLInfo_ConcurrentThroughRedirect(t *testing.T) {
+	const size = 64 * types.MB
+	server := testutil.NewMockServerT(t, testutil.WithFileSize(size), testutil.WithFilename("disk.img"))
+	defer server.Close()
+	redirect := testutil.NewHTTPServerT(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL()+"/files/disk", http.StatusFound)
+	}))
+	defer redirect.Close()
+	noRange := testutil.NewMockServerT(t, testutil.WithRangeSupport(false))
+	defer noRange.Close()
+
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: 32, MinChunkSize: 2 * types.MB}
+	info, err := probeURLInfo(context.Background(), redirect.URL+"/get", []string{redirect.URL + "/get", noRange.URL()}, nil, runtime)
+	if err != nil {
+		t.Fatalf("probeURLInfo failed: %v", err)
+	}
+
+	wantConns, wantChunk := concurrent.PlanConnections(runtime, size)
+	if info.Strategy != strategyConcurrent || info.Connections != wantConns || info.ChunkSize != wantChunk {
+		t.Errorf("Got %s with %d connections of %d, want concurrent with %d of %d",
+			info.Strategy, info.Connections, info.ChunkSize, wantConns, wantChunk)
+	}
+	if len(info.Redirects) != 1 || info.Redirects[0] != server.URL()+"/files/disk" {
+		t.Errorf("Redirects = %v, want the mock server's URL", info.Redirects)
+	}
+	if info.Filename != "disk.img" || info.FilenameSource != utils.FilenameFromHeader {
+		t.Errorf("Filename = %q from %s, want disk.img from Content-Disposition", info.Filename, info.FilenameSource)
+	}
+	if info.Size != size || !info.SupportsRange || info.Status != http.StatusPartialContent || info.Protocol != "HTTP/1.1" {
+		t.Errorf("Unexpected probe details: %+v", info)
+	}
+	if len(info.Mirrors) != 1 || info.Mirrors[0].Usable || !strings.Contains(info.Mirrors[0].Error, "ranges") {
+		t.Errorf("Expected the mirror to be rejected for lacking range support, got %+v", info.Mirrors)
+	}
+}
+
+func TestProbeURLInfo_SingleConnectionReason(t *testing.T) {
+	server := testutil.NewMockServerT(t, testutil.WithRangeSupport(false), testutil.WithFilename(""))
+	defer server.Close()
+
+	info, err := probeURLInfo(context.Background(), server.URL()+"/pub/notes.txt", nil, nil, &types.RuntimeConfig{})
+	if err != nil {
+		t.Fatalf("probeURLInfo failed: %v", err)
+	}
+	if info.Strategy != strategySingle || info.Connections != 1 || info.Reason != "server does not support range requests" {
+		t.Errorf("Expected single connection without range support, got %+v", info)
+	}
+	if info.Filename != "notes.txt" || info.FilenameSource != utils.FilenameFromPath {
+		t.Errorf("Filename = %q from %s, want notes.txt from the path", info.Filename, info.FilenameSource)
+	}
+
+	var out bytes.Buffer
+	printURLInfo(&out, info)
+	for _, want := range []string{"notes.txt (from path)", "Range requests:", "single connection (server does not support range requests)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Report is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -61,7 +61,7 @@ func printDownloads(jsonOutput bool) {
 	var downloads []downloadInfo
 
 	// Try to get from running server first
-	port := readActivePort()
+	port := daemonPort()
 	if port > 0 {
 		serverDownloads, err := GetRemoteDownloads(port)
 		if err == nil {
@@ -161,9 +161,10 @@ func showDownloadDetails(partialID string, jsonOutput bool) {
 	}
 
 	// Try to get from running server first
-	port := readActivePort()
+	port := daemonPort()
 	if port > 0 {
-		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/download?id=%s", port, fullID))
+		baseURL, client := localClient(port)
+		resp, err := client.Get(baseURL + "/download?id=" + fullID)
 		if err == nil {
 			defer func() {
 				if err := resp.Body.Close(); err != nil {
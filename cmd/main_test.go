@@ -13,6 +13,9 @@ func TestMain(m *testing.M) {
 		_ = os.Setenv("USERPROFILE", tmpDir)
 	}
 
+	// Tests that need the API socket enable it with an isolated runtime dir
+	noSocket = true
+
 	code := m.Run()
 
 	if err == nil {
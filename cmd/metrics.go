@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/metrics"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// metricsStatuses are always exported, so dashboards see zeros rather than gaps
+var metricsStatuses = []string{"downloading", "queued", "paused", "pausing", "error", "completed"}
+
+// handleMetrics serves engine and queue metrics in the Prometheus text exposition format
+func handleMetrics(w http.ResponseWriter, r *http.Request, service core.DownloadService, pool *download.WorkerPool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses, err := listStatuses(service, pool)
+	if err != nil {
+		http.Error(w, "Failed to list downloads: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer func() {
+		if err := bw.Flush(); err != nil {
+			utils.Debug("Failed to write metrics: %v", err)
+		}
+	}()
+
+	// Bytes downloaded, in total and per host
+	hostBytes := metrics.HostBytes()
+	hosts := make([]string, 0, len(hostBytes))
+	var total int64
+	for host, n := range hostBytes {
+		hosts = append(hosts, host)
+		total += n
+	}
+	sort.Strings(hosts)
+
+	writeMetricHeader(bw, "surge_downloaded_bytes_total", "counter", "Bytes downloaded since the daemon started.")
+	_, _ = fmt.Fprintf(bw, "surge_downloaded_bytes_total %d\n", total)
+
+	writeMetricHeader(bw, "surge_host_downloaded_bytes_total", "counter", "Bytes downloaded since the daemon started, by host.")
+	for _, host := range hosts {
+		_, _ = fmt.Fprintf(bw, "surge_host_downloaded_bytes_total{host=\"%s\"} %d\n", escapeLabel(host), hostBytes[host])
+	}
+
+	// Queue state
+	counts := make(map[string]int)
+	for _, s := range statuses {
+		counts[s.Status]++
+	}
+	writeMetricHeader(bw, "surge_downloads", "gauge", "Downloads by status.")
+	for _, status := range metricsStatuses {
+		_, _ = fmt.Fprintf(bw, "surge_downloads{status=\"%s\"} %d\n", status, counts[status])
+	}
+
+	writeMetricHeader(bw, "surge_download_speed_bytes_per_second", "gauge", "Current speed of each running download.")
+	for _, s := range statuses {
+		if s.Status != "downloading" {
+			continue
+		}
+		_, _ = fmt.Fprintf(bw, "surge_download_speed_bytes_per_second{id=\"%s\",filename=\"%s\"} %g\n",
+			escapeLabel(s.ID), escapeLabel(s.Filename), s.Speed*1024*1024)
+	}
+
+	// Engine internals
+	writeMetricHeader(bw, "surge_active_connections", "gauge", "HTTP connections currently transferring data.")
+	_, _ = fmt.Fprintf(bw, "surge_active_connections %d\n", metrics.ActiveConnections.Load())
+
+	counters := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"surge_task_retries_total", "Task attempts retried after a failure.", metrics.TaskRetries.Load()},
+		{"surge_hedged_tasks_total", "Tasks duplicated onto an idle worker to race a slow connection.", metrics.HedgedTasks.Load()},
+		{"surge_stolen_tasks_total", "Tasks split off a busy worker for an idle one.", metrics.StolenTasks.Load()},
+		{"surge_mirror_failures_total", "Errors reported against mirrors.", metrics.MirrorFailures.Load()},
+		{"surge_health_restarts_total", "Workers restarted by the health monitor for stalling or running slow.", metrics.HealthRestarts.Load()},
+	}
+	for _, c := range counters {
+		writeMetricHeader(bw, c.name, "counter", c.help)
+		_, _ = fmt.Fprintf(bw, "%s %d\n", c.name, c.value)
+	}
+}
+
+func writeMetricHeader(w *bufio.Writer, name, kind, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
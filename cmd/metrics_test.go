@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/metrics"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	setupIsolatedCmdState(t)
+	metrics.Reset()
+	t.Cleanup(metrics.Reset)
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "done-1", URL: "https://a.example/1", Filename: "one.iso", Status: "completed"},
+		{ID: "done-2", URL: "https://a.example/2", Filename: "two.iso", Status: "completed"},
+		{ID: "failed", URL: "https://b.example/3", Filename: "three.iso", Status: "error"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metrics.HostCounter("a.example").Add(1000)
+	metrics.HostCounter(`b"example`).Add(24)
+	metrics.TaskRetries.Add(3)
+	metrics.HedgedTasks.Add(1)
+	metrics.ActiveConnections.Add(2)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, req, core.NewLocalDownloadService(nil), nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE surge_downloaded_bytes_total counter\n",
+		"surge_downloaded_bytes_total 1024\n",
+		`surge_host_downloaded_bytes_total{host="a.example"} 1000` + "\n",
+		`surge_host_downloaded_bytes_total{host="b\"example"} 24` + "\n",
+		`surge_downloads{status="completed"} 2` + "\n",
+		`surge_downloads{status="error"} 1` + "\n",
+		`surge_downloads{status="queued"} 0` + "\n",
+		"surge_active_connections 2\n",
+		"surge_task_retries_total 3\n",
+		"surge_hedged_tasks_total 1\n",
+		"surge_stolen_tasks_total 0\n",
+		"surge_mirror_failures_total 0\n",
+		"surge_health_restarts_total 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics output missing %q\n%s", want, body)
+		}
+	}
+}
+
+func TestHandleMetrics_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, req, core.NewLocalDownloadService(nil), nil)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
+
+func TestListStatuses_ReportsPoolQueue(t *testing.T) {
+	setupIsolatedCmdState(t)
+
+	pool := download.NewWorkerPool(nil, 1)
+	running, waiting := queueBehindStalledDownload(t, pool)
+	service := core.NewLocalDownloadService(pool)
+
+	statuses, err := listStatuses(service, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, s := range statuses {
+		got[s.ID] = s.Status
+	}
+	if got[running] != "downloading" || got[waiting] != "queued" {
+		t.Errorf("statuses = %v, want %s downloading and %s queued", got, running, waiting)
+	}
+
+	// The metrics count the same way
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, req, service, pool)
+	for _, line := range []string{`surge_downloads{status="downloading"} 1`, `surge_downloads{status="queued"} 1`} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("Expected %q in metrics:\n%s", line, rec.Body.String())
+		}
+	}
+}
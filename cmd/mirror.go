@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/mirror"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Actions planned for each file of a mirror
+const (
+	mirrorNew     = "new"     // Not present locally
+	mirrorChanged = "changed" // Present with a different size; downloaded again
+	mirrorCurrent = "current" // Present with the same size
+	mirrorUnknown = "unknown" // Present, and the server doesn't report a size; the daemon compares validators
+	mirrorPending = "pending" // An unfinished download of it exists
+	mirrorStale   = "stale"   // A .surge file no download owns; removed and downloaded again
+)
+
+// mirrorItem is a crawled file and what surge mirror does with it
+type mirrorItem struct {
+	mirror.File
+	Local  string
+	Action string
+}
+
+// queued reports whether the item will be queued
+func (it mirrorItem) queued() bool {
+	return it.Action == mirrorNew || it.Action == mirrorChanged || it.Action == mirrorUnknown || it.Action == mirrorStale
+}
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror <url>",
+	Short: "Queue every file below an HTTP directory listing",
+	Long: `Crawl an HTTP directory index (Apache or nginx autoindex) or an S3 bucket
+listing recursively, and queue every file it lists in the running Surge instance,
+recreating the directory structure under --output.
+
+Files that already exist with the same size are skipped. A file whose size
+changed on the server, or is not reported, is queued as a conditional download
+that replaces the local copy only once the new one is complete.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		opts, err := mirrorOptionsFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		output, _ := cmd.Flags().GetString("output")
+		outDir := utils.EnsureAbsPath(output)
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		port := daemonPort()
+		if port == 0 && !dryRun {
+			fmt.Println("Error: Surge is not running.")
+			fmt.Println("Start it with 'surge server start', or use --dry-run to only list the files.")
+			os.Exit(1)
+		}
+		unfinished, err := unfinishedDestPaths(port)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		files, err := mirror.Crawl(ctx, args[0], opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		items := planMirror(ctx, files, outDir, opts.Headers, unfinished)
+
+		if dryRun {
+			printMirrorPlan(os.Stdout, items)
+			return
+		}
+
+		wanted, queued := 0, 0
+		for _, it := range items {
+			if !it.queued() {
+				continue
+			}
+			wanted++
+			if err := queueMirrorItem(it, opts.Headers, port); err != nil {
+				fmt.Printf("Error adding %s: %v\n", it.Path, err)
+				continue
+			}
+			queued++
+		}
+		fmt.Printf("Queued %d of %d files; %d already present.\n", queued, len(items), len(items)-wanted)
+		if queued < wanted {
+			os.Exit(1)
+		}
+	},
+}
+
+// queueMirrorItem adds one file to the daemon at port. Every file is queued
+// as a conditional download, so a changed file replaces its local copy once
+// the new one is complete.
+func queueMirrorItem(it mirrorItem, headers map[string]string, port int) error {
+	if it.Action == mirrorStale {
+		// Nothing can resume it, and it would block the conditional download
+		if err := os.Remove(it.Local + types.IncompleteSuffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove the stale %s file: %w", types.IncompleteSuffix, err)
+		}
+	}
+	_, _, err := sendDownloadRequest(DownloadRequest{
+		URL:          it.URL,
+		Path:         filepath.Dir(it.Local),
+		Filename:     filepath.Base(it.Local),
+		Headers:      headers,
+		SkipApproval: true, // Re-downloading files already fetched is the point of a mirror
+		IfModified:   true,
+	}, port)
+	return err
+}
+
+// mirrorOptionsFromFlags validates the crawl flags
+func mirrorOptionsFromFlags(cmd *cobra.Command) (mirror.Options, error) {
+	var opts mirror.Options
+	opts.MaxDepth, _ = cmd.Flags().GetInt("depth")
+	opts.Include, _ = cmd.Flags().GetStringArray("include")
+	opts.Exclude, _ = cmd.Flags().GetStringArray("exclude")
+
+	for flag, dst := range map[string]*int64{"min-size": &opts.MinSize, "max-size": &opts.MaxSize} {
+		value, _ := cmd.Flags().GetString(flag)
+		if value == "" {
+			continue
+		}
+		size, err := utils.ParseSize(value)
+		if err != nil {
+			return opts, fmt.Errorf("--%s: %w", flag, err)
+		}
+		*dst = size
+	}
+
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+	headers, err := parseHeaderFlags(headerFlags)
+	if err != nil {
+		return opts, err
+	}
+	opts.Headers = headers
+	return opts, opts.Validate()
+}
+
+// planMirror decides what to do with each crawled file, comparing it with the
+// local copy under outDir. Sizes the listing didn't show are probed only for
+// files that exist locally. unfinished holds the destinations of downloads
+// that own their .surge files.
+func planMirror(ctx context.Context, files []mirror.File, outDir string, headers map[string]string, unfinished map[string]bool) []mirrorItem {
+	items := make([]mirrorItem, 0, len(files))
+	for _, f := range files {
+		it := mirrorItem{File: f, Local: filepath.Join(outDir, filepath.FromSlash(f.Path)), Action: mirrorNew}
+		if _, err := os.Stat(it.Local + types.IncompleteSuffix); err == nil {
+			it.Action = mirrorStale
+			if unfinished[it.Local] {
+				it.Action = mirrorPending
+			}
+		} else if info, err := os.Stat(it.Local); err == nil {
+			if it.Size < 0 {
+				it.Size = mirror.RemoteSize(ctx, f.URL, headers)
+			}
+			switch {
+			case it.Size < 0:
+				it.Action = mirrorUnknown
+			case it.Size == info.Size():
+				it.Action = mirrorCurrent
+			default:
+				it.Action = mirrorChanged
+			}
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// unfinishedDestPaths returns the destinations of the downloads that are not
+// finished: those saved in the database and, if port is set, the daemon's
+func unfinishedDestPaths(port int) (map[string]bool, error) {
+	paths := make(map[string]bool)
+	entries, err := state.ListAllDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load downloads: %w", err)
+	}
+	for _, e := range entries {
+		if e.Status != "completed" && e.DestPath != "" {
+			paths[e.DestPath] = true
+		}
+	}
+	if port > 0 {
+		statuses, err := localService(port).List()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list downloads: %w", err)
+		}
+		for _, s := range statuses {
+			if s.Status != "completed" && s.DestPath != "" {
+				paths[s.DestPath] = true
+			}
+		}
+	}
+	return paths, nil
+}
+
+// printMirrorPlan writes the --dry-run table
+func printMirrorPlan(out io.Writer, items []mirrorItem) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ACTION\tSIZE\tPATH")
+	queued := 0
+	var bytes int64
+	for _, it := range items {
+		size := "-"
+		if it.Size >= 0 {
+			size = utils.ConvertBytesToHumanReadable(it.Size)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", it.Action, size, it.Path)
+		if it.queued() {
+			queued++
+			if it.Size > 0 {
+				bytes += it.Size
+			}
+		}
+	}
+	_ = w.Flush()
+	total := ""
+	if bytes > 0 {
+		total = fmt.Sprintf(" (at least %s)", utils.ConvertBytesToHumanReadable(bytes))
+	}
+	_, _ = fmt.Fprintf(out, "\n%d of %d files would be queued%s.\n", queued, len(items), total)
+}
+
+func init() {
+	rootCmd.AddCommand(mirrorCmd)
+	mirrorCmd.Flags().StringP("output", "o", ".", "Directory to recreate the listing's structure in")
+	mirrorCmd.Flags().Int("depth", -1, "Levels of subdirectories to descend into (-1 for no limit)")
+	mirrorCmd.Flags().StringArray("include", nil, "Only files matching this glob (repeatable; globs with '/' match the path)")
+	mirrorCmd.Flags().StringArray("exclude", nil, "Skip files and directories matching this glob (repeatable)")
+	mirrorCmd.Flags().String("min-size", "", "Skip files smaller than SIZE (e.g. 1MB)")
+	mirrorCmd.Flags().String("max-size", "", "Skip files larger than SIZE (e.g. 2GB)")
+	mirrorCmd.Flags().StringArrayP("header", "H", nil, "Request header for the listing and the downloads, as \"Key: Value\" (repeatable)")
+	mirrorCmd.Flags().Bool("dry-run", false, "List what would be downloaded without queueing anything")
+}
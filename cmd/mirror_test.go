@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/mirror"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestPlanMirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nosize.bin" {
+			// Streamed without a length or range support
+			_, _ = w.Write([]byte("12345"))
+			w.(http.Flusher).Flush()
+			return
+		}
+		http.ServeContent(w, r, "f", time.Time{}, strings.NewReader("12345"))
+	}))
+	defer server.Close()
+
+	outDir := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(outDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("same.bin", "abc")
+	write("sub/changed.bin", "abc")
+	write("probed.bin", "12345")
+	write("partial.bin"+types.IncompleteSuffix, "")
+	write("nosize.bin", "abc")
+	write("stale.bin"+types.IncompleteSuffix, "")
+
+	files := []mirror.File{
+		{URL: server.URL + "/new.bin", Path: "sub/new.bin", Size: 7},
+		{URL: server.URL + "/same.bin", Path: "same.bin", Size: 3},
+		{URL: server.URL + "/changed.bin", Path: "sub/changed.bin", Size: 4},
+		{URL: server.URL + "/probed.bin", Path: "probed.bin", Size: -1},
+		{URL: server.URL + "/partial.bin", Path: "partial.bin", Size: 9},
+		{URL: server.URL + "/nosize.bin", Path: "nosize.bin", Size: -1},
+		{URL: server.URL + "/stale.bin", Path: "stale.bin", Size: 9},
+	}
+	unfinished := map[string]bool{filepath.Join(outDir, "partial.bin"): true}
+	items := planMirror(context.Background(), files, outDir, nil, unfinished)
+
+	want := []string{mirrorNew, mirrorCurrent, mirrorChanged, mirrorCurrent, mirrorPending, mirrorUnknown, mirrorStale}
+	for i, it := range items {
+		if it.Action != want[i] {
+			t.Errorf("%s: action %q, want %q", it.Path, it.Action, want[i])
+		}
+	}
+	if items[0].Local != filepath.Join(outDir, "sub", "new.bin") {
+		t.Errorf("Local = %q", items[0].Local)
+	}
+	if items[3].Size != 5 {
+		t.Errorf("Expected the probed size 5, got %d", items[3].Size)
+	}
+
+	var out bytes.Buffer
+	printMirrorPlan(&out, items)
+	if !strings.Contains(out.String(), "4 of 7 files would be queued") {
+		t.Errorf("Unexpected plan output:\n%s", out.String())
+	}
+}
+
+func TestQueueMirrorItem_ReplacesInPlace(t *testing.T) {
+	setupIsolatedCmdState(t)
+	var got DownloadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	local := filepath.Join(t.TempDir(), "fw.bin")
+	if err := os.WriteFile(local, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	it := mirrorItem{File: mirror.File{URL: "http://example.com/fw.bin", Path: "fw.bin", Size: 4}, Local: local, Action: mirrorChanged}
+	if err := queueMirrorItem(it, nil, port); err != nil {
+		t.Fatalf("queueMirrorItem failed: %v", err)
+	}
+	if !got.IfModified || got.Filename != "fw.bin" || got.Path != filepath.Dir(local) {
+		t.Errorf("Expected a conditional download of fw.bin, got %+v", got)
+	}
+	// The old copy stays until the download replaces it
+	if data, err := os.ReadFile(local); err != nil || string(data) != "old" {
+		t.Errorf("Expected the old copy in place, got %q, %v", data, err)
+	}
+
+	// A stale .surge file is removed so the conditional download can start
+	if err := os.WriteFile(local+types.IncompleteSuffix, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	it.Action = mirrorStale
+	if err := queueMirrorItem(it, nil, port); err != nil {
+		t.Fatalf("queueMirrorItem failed: %v", err)
+	}
+	if _, err := os.Stat(local + types.IncompleteSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale .surge file to be removed, got %v", err)
+	}
+}
+
+func TestUnfinishedDestPaths(t *testing.T) {
+	setupIsolatedCmdState(t)
+	for _, e := range []types.DownloadEntry{
+		{ID: "p", URL: "https://example.com/p.bin", DestPath: "/data/p.bin", Status: "paused"},
+		{ID: "c", URL: "https://example.com/c.bin", DestPath: "/data/c.bin", Status: "completed"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("Failed to seed download: %v", err)
+		}
+	}
+	paths, err := unfinishedDestPaths(0)
+	if err != nil {
+		t.Fatalf("unfinishedDestPaths failed: %v", err)
+	}
+	if !paths["/data/p.bin"] || paths["/data/c.bin"] {
+		t.Errorf("Expected only the paused download, got %v", paths)
+	}
+}
@@ -25,7 +25,7 @@ var pauseCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		port := readActivePort()
+		port := daemonPort()
 
 		if all {
 			// Pause all downloads
@@ -54,7 +54,8 @@ var pauseCmd = &cobra.Command{
 
 		if port > 0 {
 			// Send to running server
-			resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d/pause?id=%s", port, id), "application/json", nil)
+			baseURL, client := localClient(port)
+			resp, err := client.Post(baseURL+"/pause?id="+id, "application/json", nil)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
 				os.Exit(1)
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+// remoteEntry is what surge connect remembers about a daemon
+type remoteEntry struct {
+	Fingerprint string `json:"fingerprint,omitempty"` // Pinned certificate, for untrusted TLS
+	Token       string `json:"token,omitempty"`
+}
+
+// remotesFile holds remembered daemons, keyed by host:port. It contains
+// tokens, so it is only readable by the user.
+func remotesFile() string {
+	return filepath.Join(config.GetStateDir(), "remotes.json")
+}
+
+func loadRemotes() (map[string]remoteEntry, error) {
+	remotes := make(map[string]remoteEntry)
+	data, err := os.ReadFile(remotesFile())
+	if os.IsNotExist(err) {
+		return remotes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remotes: %w", err)
+	}
+	if err := json.Unmarshal(data, &remotes); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", remotesFile(), err)
+	}
+	return remotes, nil
+}
+
+// saveRemote records the entry for hostPort
+func saveRemote(hostPort string, entry remoteEntry) error {
+	remotes, err := loadRemotes()
+	if err != nil {
+		return err
+	}
+	remotes[hostPort] = entry
+
+	data, err := json.MarshalIndent(remotes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode remotes: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(remotesFile()), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(remotesFile(), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write remotes: %w", err)
+	}
+	return nil
+}
+
+// resolveRemoteTLS decides how to verify the daemon at hostPort. An explicit
+// fingerprint replaces any pin; otherwise a stored pin is used. A new daemon
+// whose certificate the system trusts is verified normally; an untrusted one
+// is pinned once confirm accepts its fingerprint (trust on first use). It
+// returns a nil config for normal verification, and the fingerprint to store.
+func resolveRemoteTLS(hostPort, stored, explicit string, confirm func(fingerprint string) bool) (*tls.Config, string, error) {
+	if explicit != "" {
+		fp := normalizeFingerprint(explicit)
+		if fp == "" {
+			return nil, "", fmt.Errorf("invalid fingerprint %q (want a SHA-256 hex digest)", explicit)
+		}
+		return pinnedTLSConfig(fp), fp, nil
+	}
+	if stored != "" {
+		return pinnedTLSConfig(stored), stored, nil
+	}
+
+	fp, trusted, err := fetchCertFingerprint(hostPort)
+	if err != nil {
+		return nil, "", err
+	}
+	if trusted {
+		return nil, "", nil
+	}
+	if !confirm(fp) {
+		return nil, "", errors.New("server certificate not trusted")
+	}
+	return pinnedTLSConfig(fp), fp, nil
+}
+
+// confirmFingerprint asks the user whether to trust an unknown certificate
+func confirmFingerprint(in io.Reader, out io.Writer, hostPort, fingerprint string) bool {
+	_, _ = fmt.Fprintf(out, "The certificate of %s is not signed by a trusted authority.\n", hostPort)
+	_, _ = fmt.Fprintf(out, "SHA-256 fingerprint: %s\n", fingerprint)
+	_, _ = fmt.Fprintln(out, "Compare it with the fingerprint printed by `surge server start`.")
+	_, _ = fmt.Fprint(out, "Trust this certificate for future connections? [y/N] ")
+
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
@@ -25,7 +25,7 @@ var resumeCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		port := readActivePort()
+		port := daemonPort()
 
 		if all {
 			if port > 0 {
@@ -51,7 +51,8 @@ var resumeCmd = &cobra.Command{
 
 		if port > 0 {
 			// Send to running server
-			resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d/resume?id=%s", port, id), "application/json", nil)
+			baseURL, client := localClient(port)
+			resp, err := client.Post(baseURL+"/resume?id="+id, "application/json", nil)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
 				os.Exit(1)
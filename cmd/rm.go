@@ -26,7 +26,7 @@ var rmCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		port := readActivePort()
+		port := daemonPort()
 
 		if clean {
 			// Remove completed downloads from DB
@@ -50,7 +50,8 @@ var rmCmd = &cobra.Command{
 
 		if port > 0 {
 			// Send to running server
-			resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d/delete?id=%s", port, id), "application/json", nil)
+			baseURL, client := localClient(port)
+			resp, err := client.Post(baseURL+"/delete?id="+id, "application/json", nil)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
 				os.Exit(1)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -13,11 +14,13 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/batch"
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/tui"
 	"github.com/surge-downloader/surge/internal/utils"
 
@@ -105,6 +108,20 @@ var rootCmd = &cobra.Command{
 		outputDir, _ := cmd.Flags().GetString("output")
 		noResume, _ := cmd.Flags().GetBool("no-resume")
 		exitWhenDone, _ := cmd.Flags().GetBool("exit-when-done")
+		rangeFlag, _ := cmd.Flags().GetString("range")
+		headFlag, _ := cmd.Flags().GetString("head")
+		seeds, _ := cmd.Flags().GetStringArray("seed")
+		zsync, _ := cmd.Flags().GetString("zsync")
+		globoff, _ := cmd.Flags().GetBool("globoff")
+
+		opts, err := parseRangeFlags(rangeFlag, headFlag)
+		if err == nil {
+			err = parseDeltaFlags(&opts, seeds, zsync)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		port, listener, err := bindServerListener(portFlag)
 		if err != nil {
@@ -115,26 +132,20 @@ var rootCmd = &cobra.Command{
 		// Save port for browser extension AND CLI discovery
 		saveActivePort(port)
 		defer removeActivePort()
+		defer removeSocket()
 
 		// Start HTTP server in background (reuse the listener)
 		go startHTTPServer(listener, port, outputDir, GlobalService)
 
 		// Queue initial downloads if any
 		go func() {
-			var urls []string
-			urls = append(urls, args...)
-
-			if batchFile != "" {
-				fileUrls, err := readURLsFromFile(batchFile)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
-				} else {
-					urls = append(urls, fileUrls...)
-				}
+			entries, err := collectEntries(args, "", batchFile, globoff)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			}
 
-			if len(urls) > 0 {
-				processDownloads(urls, outputDir, 0) // 0 port = internal direct add
+			if len(entries) > 0 {
+				processEntries(entries, outputDir, 0, opts) // 0 port = internal direct add
 			}
 		}()
 
@@ -247,7 +258,11 @@ func StartHeadlessConsumer() {
 				if len(id) > 8 {
 					id = id[:8]
 				}
-				fmt.Printf("Paused: %s [%s]\n", m.Filename, id)
+				if m.Reason != "" {
+					fmt.Printf("Paused: %s [%s] (%s)\n", m.Filename, id, m.Reason)
+				} else {
+					fmt.Printf("Paused: %s [%s]\n", m.Filename, id)
+				}
 			case events.DownloadResumedMsg:
 				id := m.DownloadID
 				if len(id) > 8 {
@@ -313,6 +328,7 @@ func removeActivePort() {
 // startHTTPServer starts the HTTP server using an existing listener
 func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service core.DownloadService) {
 	authToken := ensureAuthToken()
+	throttle := newAuthThrottle()
 
 	mux := http.NewServeMux()
 
@@ -328,91 +344,19 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 	})
 
 	// SSE Events Endpoint (Protected)
+	persistEvents := config.DefaultSettings().General.PersistEvents
+	if settings, err := config.LoadSettings(); err == nil {
+		persistEvents = settings.General.PersistEvents
+	}
+	eventLog := newEventLog(eventLogSize, persistEvents)
+	go eventLog.run(service)
 	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
-		// Set headers for SSE
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-
-		// Get event stream
-		stream, cleanup, err := service.StreamEvents(r.Context())
-		if err != nil {
-			http.Error(w, "Failed to subscribe to events", http.StatusInternalServerError)
-			return
-		}
-		defer cleanup()
-
-		// Flush headers immediately
-		flusher, ok := w.(http.Flusher)
-		if !ok {
-			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-			return
-		}
-		flusher.Flush()
-
-		// Send events
-		// Create a closer notifier
-		done := r.Context().Done()
-
-		for {
-			select {
-			case <-done:
-				return
-			case msg, ok := <-stream:
-				if !ok {
-					return
-				}
-
-				// Encode message to JSON
-				data, err := json.Marshal(msg)
-				if err != nil {
-					utils.Debug("Error marshaling event: %v", err)
-					continue
-				}
-
-				// Determine event type name based on struct
-				// Events are in internal/engine/events package
-				eventType := "unknown"
-				switch msg := msg.(type) {
-				case events.DownloadStartedMsg:
-					eventType = "started"
-				case events.DownloadCompleteMsg:
-					eventType = "complete"
-				case events.DownloadErrorMsg:
-					eventType = "error"
-				case events.ProgressMsg:
-					eventType = "progress"
-				case events.DownloadPausedMsg:
-					eventType = "paused"
-				case events.DownloadResumedMsg:
-					eventType = "resumed"
-				case events.DownloadQueuedMsg:
-					eventType = "queued"
-				case events.DownloadRemovedMsg:
-					eventType = "removed"
-				case events.DownloadRequestMsg:
-					eventType = "request"
-				case events.BatchProgressMsg:
-					// Unroll batch and send individual progress events
-					for _, p := range msg {
-						data, _ := json.Marshal(p)
-						_, _ = fmt.Fprintf(w, "event: progress\n")
-						_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
-					}
-					flusher.Flush()
-					continue // Skip default send
-				}
+		handleEvents(w, r, eventLog, service)
+	})
 
-				// SSE Format:
-				// event: <type>
-				// data: <json>
-				// \n
-				_, _ = fmt.Fprintf(w, "event: %s\n", eventType)
-				_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
-				flusher.Flush()
-			}
-		}
+	// WebSocket control channel (Protected): the event stream plus commands
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(w, r, eventLog, defaultOutputDir, service, GlobalPool)
 	})
 
 	// Download endpoint (Protected + Public for simple GET status if needed? No, let's protect all for now)
@@ -432,7 +376,7 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 			return
 		}
 
-		if err := service.Pause(id); err != nil {
+		if err := auditedChange(service, clientFrom(r.Context()), auditPause, id, service.Pause); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -455,7 +399,7 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 			return
 		}
 
-		if err := service.Resume(id); err != nil {
+		if err := auditedChange(service, clientFrom(r.Context()), auditResume, id, service.Resume); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -478,7 +422,7 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 			return
 		}
 
-		if err := service.Delete(id); err != nil {
+		if err := auditedChange(service, clientFrom(r.Context()), auditDelete, id, service.Delete); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -527,8 +471,37 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 		}
 	})
 
+	// Stream endpoint (Protected): serves downloads over HTTP with Range support
+	mux.HandleFunc("/stream/", func(w http.ResponseWriter, r *http.Request) {
+		handleStream(w, r, GlobalPool)
+	})
+
+	// aria2-compatible JSON-RPC (Protected: token checked per call)
+	aria2 := &aria2RPC{token: authToken, throttle: throttle, defaultOutputDir: defaultOutputDir, service: service, pool: GlobalPool}
+	mux.HandleFunc("/jsonrpc", func(w http.ResponseWriter, r *http.Request) {
+		handleAria2(w, r, aria2)
+	})
+
+	// Versioned REST API (Protected). The routes above remain as aliases for
+	// the browser extension.
+	registerAPI(mux, &apiServer{defaultOutputDir: defaultOutputDir, service: service, pool: GlobalPool, streamKey: authToken})
+
+	// Metrics endpoint (Protected): Prometheus text format
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		handleMetrics(w, r, service, GlobalPool)
+	})
+
+	// Local clients may use the Unix socket instead, without a token
+	if !noSocket {
+		if sockLn, err := listenSocket(); err != nil {
+			utils.Debug("Socket listener disabled: %v", err)
+		} else {
+			go serveSocket(sockLn, mux)
+		}
+	}
+
 	// Wrap mux with Auth and CORS (CORS outermost to ensure 401/403 include headers)
-	handler := corsMiddleware(authMiddleware(authToken, mux))
+	handler := corsMiddleware(authMiddleware(authToken, throttle, mux))
 
 	server := &http.Server{Handler: handler}
 	if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
@@ -554,7 +527,9 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func authMiddleware(token string, next http.Handler) http.Handler {
+// authMiddleware identifies the client behind each request and checks its
+// token's scope. Addresses that keep failing are throttled, then locked out.
+func authMiddleware(token string, throttle *authThrottle, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow health check without auth
 		if r.URL.Path == "/health" {
@@ -568,22 +543,61 @@ func authMiddleware(token string, next http.Handler) http.Handler {
 			return
 		}
 
+		// aria2 clients send the token inside each call, so the RPC handler checks it
+		if r.URL.Path == "/jsonrpc" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		addr := clientIP(r)
+		if wait := throttle.lockedFor(addr); wait > 0 {
+			writeLockedOut(w, r, wait)
+			return
+		}
+
 		// Check for Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				providedToken := strings.TrimPrefix(authHeader, "Bearer ")
-				if len(providedToken) == len(token) && subtle.ConstantTimeCompare([]byte(providedToken), []byte(token)) == 1 {
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
+		var client *apiClient
+		if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			client = resolveToken(strings.TrimPrefix(authHeader, "Bearer "), token)
+		}
+
+		// Media players can't set headers, so /stream/ also takes a signed,
+		// expiring link. Browser WebSockets pass the token as a query parameter.
+		if client == nil && strings.HasPrefix(r.URL.Path, "/stream/") && r.URL.Query().Has("sig") {
+			client = streamLinkClient(r, token, time.Now())
+		}
+		if client == nil && r.URL.Path == "/ws" {
+			client = resolveToken(r.URL.Query().Get("token"), token)
 		}
 
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if client == nil {
+			authFailed(throttle, addr)
+			writeAuthError(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		client = client.from(addr)
+		if !client.allows(requiredScope(r)) {
+			writeAuthError(w, r, http.StatusForbidden, "Token scope does not allow this request")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withClient(r.Context(), client)))
 	})
 }
 
+// writeAuthError reports an authentication failure, as JSON for /api/v1
+func writeAuthError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		writeAPIError(w, status, errorCodeFor(status), message)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// tokenMatches compares a client-provided token against the API token in constant time
+func tokenMatches(provided, token string) bool {
+	return provided != "" && len(provided) == len(token) && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
 func ensureAuthToken() string {
 	tokenFile := filepath.Join(config.GetStateDir(), "token")
 	data, err := os.ReadFile(tokenFile)
@@ -613,6 +627,13 @@ type DownloadRequest struct {
 	Mirrors              []string          `json:"mirrors,omitempty"`
 	SkipApproval         bool              `json:"skip_approval,omitempty"` // Extension validated request, skip TUI prompt
 	Headers              map[string]string `json:"headers,omitempty"`       // Custom HTTP headers from browser (cookies, auth, etc.)
+	RangeStart           int64             `json:"range_start,omitempty"`   // Fetch only part of the resource, starting at this byte
+	RangeLength          int64             `json:"range_length,omitempty"`  // Number of bytes to fetch (0 = through the end)
+	Seeds                []string          `json:"seeds,omitempty"`         // Absolute local paths reused for delta updates
+	DeltaControl         string            `json:"delta_control,omitempty"` // .zsync or block hash list URL/path
+	Checksum             string            `json:"checksum,omitempty"`      // Expected digest as "<algorithm>=<hex>", checked on completion
+	MaxConnections       int               `json:"max_connections,omitempty"`
+	IfModified           bool              `json:"if_modified,omitempty"` // Download only if the remote file changed, replacing the local copy
 }
 
 func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir string, service core.DownloadService) {
@@ -648,13 +669,6 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		return
 	}
 
-	// Load settings once for use throughout the function
-	settings, err := config.LoadSettings()
-	if err != nil {
-		// Fallback to defaults if loading fails (though LoadSettings handles missing file)
-		settings = config.DefaultSettings()
-	}
-
 	var req DownloadRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
@@ -666,67 +680,122 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		}
 	}()
 
-	if req.URL == "" {
-		http.Error(w, "URL is required", http.StatusBadRequest)
+	status, resp, err := queueDownload(req, defaultOutputDir, service, clientFrom(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), status)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
+	}
+}
+
+// queueDownload validates a download request and adds it through service, or
+// hands it to the TUI for approval. It returns the HTTP status with either the
+// JSON response body or, for rejected requests, an error to report.
+func queueDownload(req DownloadRequest, defaultOutputDir string, service core.DownloadService, client *apiClient) (int, map[string]string, error) {
+	// Load settings once for use throughout the function. A missing file gives the
+	// defaults; an unreadable one must not lift the allowed directories.
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if req.URL == "" {
+		return http.StatusBadRequest, nil, errors.New("URL is required")
+	}
+
 	if strings.Contains(req.Path, "..") || strings.Contains(req.Filename, "..") {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, nil, errors.New("Invalid path")
 	}
 	if strings.Contains(req.Filename, "/") || strings.Contains(req.Filename, "\\") {
-		http.Error(w, "Invalid filename", http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, nil, errors.New("Invalid filename")
+	}
+	if req.RangeStart < 0 || req.RangeLength < 0 {
+		return http.StatusBadRequest, nil, errors.New("Invalid range")
+	}
+	for _, seed := range req.Seeds {
+		if !filepath.IsAbs(seed) {
+			return http.StatusBadRequest, nil, errors.New("Seed paths must be absolute")
+		}
+	}
+	if isLocalControl(req.DeltaControl) && !filepath.IsAbs(req.DeltaControl) {
+		return http.StatusBadRequest, nil, errors.New("Control file path must be absolute")
+	}
+	if req.Checksum != "" {
+		if _, _, err := utils.ParseChecksum(req.Checksum); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+	}
+	if req.MaxConnections < 0 {
+		return http.StatusBadRequest, nil, errors.New("Invalid max_connections")
+	}
+	if req.IfModified && (req.RangeStart > 0 || req.RangeLength > 0) {
+		return http.StatusBadRequest, nil, errors.New("if_modified cannot be combined with a range")
+	}
+	opts := types.DownloadOptions{
+		RangeStart:     req.RangeStart,
+		RangeLength:    req.RangeLength,
+		Seeds:          req.Seeds,
+		DeltaControl:   req.DeltaControl,
+		Checksum:       req.Checksum,
+		MaxConnections: req.MaxConnections,
+		IfModified:     req.IfModified,
 	}
 
 	utils.Debug("Received download request: URL=%s, Path=%s", req.URL, req.Path)
 
 	downloadID := uuid.New().String()
 	if service == nil {
-		http.Error(w, "Service unavailable", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, nil, errors.New("Service unavailable")
 	}
 
-	// Prepare output path
+	// Prepare output path. The default directory is the daemon's --output,
+	// then the configured default download dir, then the working directory.
+	baseDir := defaultOutputDir
+	if baseDir == "" {
+		baseDir = settings.General.DefaultDownloadDir
+	}
+	if baseDir == "" {
+		baseDir = "."
+	}
 	outPath := req.Path
-	if req.RelativeToDefaultDir && req.Path != "" {
-		// Resolve relative to default download directory
-		baseDir := settings.General.DefaultDownloadDir
-		if baseDir == "" {
-			baseDir = defaultOutputDir
-		}
-		if baseDir == "" {
-			baseDir = "."
-		}
-		outPath = filepath.Join(baseDir, req.Path)
-		if err := os.MkdirAll(outPath, 0o755); err != nil {
-			http.Error(w, "Failed to create directory: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-	} else if outPath == "" {
-		if defaultOutputDir != "" {
-			outPath = defaultOutputDir
-			if err := os.MkdirAll(outPath, 0o755); err != nil {
-				http.Error(w, "Failed to create output directory: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			if settings.General.DefaultDownloadDir != "" {
-				outPath = settings.General.DefaultDownloadDir
-				if err := os.MkdirAll(outPath, 0o755); err != nil {
-					http.Error(w, "Failed to create output directory: "+err.Error(), http.StatusInternalServerError)
-					return
-				}
-			} else {
-				outPath = "."
-			}
-		}
+	underBase := false
+	if outPath == "" {
+		outPath, underBase = baseDir, baseDir != "."
+	} else if req.RelativeToDefaultDir && !filepath.IsAbs(outPath) {
+		outPath, underBase = filepath.Join(baseDir, outPath), true
 	}
 
 	// Enforce absolute path to ensure resume works even if CWD changes
 	outPath = utils.EnsureAbsPath(outPath)
+	if roots := settings.General.AllowedDownloadDirs; !dirAllowed(outPath, roots) {
+		return http.StatusForbidden, nil, fmt.Errorf("Output directory %s is outside the allowed download directories (%s)", outPath, strings.Join(roots, ", "))
+	}
+	if !client.allowsDir(outPath) {
+		return http.StatusForbidden, nil, errors.New("Output directory not allowed for this token")
+	}
+	// The daemon reads seeds and a local control file, so they are held to
+	// the same directories as the output
+	readPaths := req.Seeds
+	if isLocalControl(req.DeltaControl) {
+		readPaths = append(append([]string(nil), req.Seeds...), req.DeltaControl)
+	}
+	for _, p := range readPaths {
+		if !dirAllowed(p, settings.General.AllowedDownloadDirs) || !client.allowsDir(p) {
+			return http.StatusForbidden, nil, fmt.Errorf("%s is outside the allowed download directories", p)
+		}
+	}
+	if underBase {
+		if err := os.MkdirAll(outPath, 0o755); err != nil {
+			return http.StatusInternalServerError, nil, fmt.Errorf("Failed to create output directory: %w", err)
+		}
+	}
 
 	// Check settings for extension prompt and duplicates
 	// Logic modified to distinguish between ACTIVE (corruption risk) and COMPLETED (overwrite safe)
@@ -761,6 +830,10 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 	if req.SkipApproval {
 		// Trust extension -> Skip all prompting logic, proceed to download
 		utils.Debug("Extension request: skipping all prompts, proceeding with download")
+	} else if req.IfModified {
+		// A conditional request is a refresh of a file fetched before, so it
+		// is never a duplicate to warn about or a new download to approve
+		utils.Debug("Conditional request: skipping the duplicate check")
 	} else {
 		// Logic for prompting:
 		// 1. If ExtensionPrompt is enabled
@@ -780,85 +853,116 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 					Path:     outPath, // Use the path we resolved (default or requested)
 					Mirrors:  mirrorsForAdd,
 					Headers:  req.Headers,
+					Options:  opts,
 				}); err != nil {
-					http.Error(w, "Failed to notify TUI: "+err.Error(), http.StatusInternalServerError)
-					return
+					return http.StatusInternalServerError, nil, fmt.Errorf("Failed to notify TUI: %w", err)
 				}
 
-				w.Header().Set("Content-Type", "application/json")
+				recordAudit(client, auditAdd, downloadID, urlForAdd, outPath+" (pending approval)")
+
 				// Return 202 Accepted to indicate it's pending approval
-				w.WriteHeader(http.StatusAccepted)
-				if err := json.NewEncoder(w).Encode(map[string]string{
+				return http.StatusAccepted, map[string]string{
 					"status":  "pending_approval",
 					"message": "Download request sent to TUI for confirmation",
 					"id":      downloadID, // ID might change if user modifies it, but useful for tracking
-				}); err != nil {
-					utils.Debug("Failed to encode response: %v", err)
-				}
-				return
+				}, nil
 			} else {
 				// Headless mode check
 				if settings.General.ExtensionPrompt || (settings.General.WarnOnDuplicate && isDuplicate) {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusConflict)
-					if err := json.NewEncoder(w).Encode(map[string]string{
+					return http.StatusConflict, map[string]string{
 						"status":  "error",
 						"message": "Download rejected: Duplicate download or approval required (Headless mode)",
-					}); err != nil {
-						utils.Debug("Failed to encode response: %v", err)
-					}
-					return
+					}, nil
 				}
 			}
 		}
 	}
 
 	// Add via service
-	newID, err := service.Add(urlForAdd, outPath, req.Filename, mirrorsForAdd, req.Headers)
+	newID, err := service.AddWithOptions(urlForAdd, outPath, req.Filename, mirrorsForAdd, req.Headers, opts)
 	if err != nil {
-		http.Error(w, "Failed to add download: "+err.Error(), http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to add download: %w", err)
 	}
 
 	// Increment active downloads counter
 	atomic.AddInt32(&activeDownloads, 1)
+	recordAudit(client, auditAdd, newID, urlForAdd, outPath)
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]string{
+	return http.StatusOK, map[string]string{
 		"status":  "queued",
 		"message": "Download queued successfully",
 		"id":      newID,
-	}); err != nil {
-		utils.Debug("Failed to encode response: %v", err)
-	}
+	}, nil
 }
 
 // processDownloads handles the logic of adding downloads either to local pool or remote server
 // Returns the number of successfully added downloads
 func processDownloads(urls []string, outputDir string, port int) int {
-	successCount := 0
+	entries, _ := collectEntries(urls, "", "", false)
+	return len(processEntries(entries, outputDir, port, types.DownloadOptions{}))
+}
+
+// entryOptions applies a batch entry's own checksum, connection limit and
+// conditional-get over opts
+func entryOptions(e batch.Entry, opts types.DownloadOptions) types.DownloadOptions {
+	if e.Options.Checksum != "" {
+		opts.Checksum = e.Options.Checksum
+	}
+	if e.Options.MaxConnections > 0 {
+		opts.MaxConnections = e.Options.MaxConnections
+	}
+	if e.Options.IfModified {
+		opts.IfModified = true
+	}
+	return opts
+}
+
+// processEntries is processDownloads for batch entries, with opts applied to every entry
+// under the entry's own options. Returns the IDs of the downloads that were added;
+// requests left awaiting approval in the TUI are reported but not returned.
+func processEntries(entries []batch.Entry, outputDir string, port int, opts types.DownloadOptions) []string {
+	var ids []string
 
 	// If port > 0, we are sending to a remote server
 	if port > 0 {
-		for _, arg := range urls {
-			url, mirrors := ParseURLArg(arg)
-			if url == "" {
-				continue
+		for _, e := range entries {
+			eopts := entryOptions(e, opts)
+			req := DownloadRequest{
+				URL:            e.URL,
+				Mirrors:        e.Mirrors,
+				Filename:       e.Filename,
+				Path:           e.OutputDir(outputDir),
+				Headers:        e.Headers,
+				RangeStart:     eopts.RangeStart,
+				RangeLength:    eopts.RangeLength,
+				Seeds:          eopts.Seeds,
+				DeltaControl:   eopts.DeltaControl,
+				Checksum:       eopts.Checksum,
+				MaxConnections: eopts.MaxConnections,
+				IfModified:     eopts.IfModified,
 			}
-			err := sendToServer(url, mirrors, outputDir, port)
-			if err != nil {
-				fmt.Printf("Error adding %s: %v\n", url, err)
-			} else {
-				successCount++
+			// Without --output a relative dir is resolved under the daemon's default
+			if outputDir == "" && e.Dir != "" && !filepath.IsAbs(e.Dir) {
+				req.RelativeToDefaultDir = true
+			}
+			id, pending, err := sendDownloadRequest(req, port)
+			switch {
+			case err != nil:
+				fmt.Printf("Error adding %s: %v\n", e.URL, err)
+			case pending:
+				// Approval assigns a new ID, so this one cannot be followed
+				fmt.Printf("Awaiting approval in the TUI: %s\n", e.URL)
+			default:
+				ids = append(ids, id)
 			}
 		}
-		return successCount
+		return ids
 	}
 
 	// Internal add (TUI or Headless mode)
 	if GlobalService == nil {
 		fmt.Fprintln(os.Stderr, "Error: GlobalService not initialized")
-		return 0
+		return nil
 	}
 
 	settings, err := config.LoadSettings()
@@ -866,17 +970,7 @@ func processDownloads(urls []string, outputDir string, port int) int {
 		settings = config.DefaultSettings()
 	}
 
-	for _, arg := range urls {
-		// Validation
-		if arg == "" {
-			continue
-		}
-
-		url, mirrors := ParseURLArg(arg)
-		if url == "" {
-			continue
-		}
-
+	for _, e := range entries {
 		// Prepare output path
 		outPath := outputDir
 		if outPath == "" {
@@ -887,25 +981,19 @@ func processDownloads(urls []string, outputDir string, port int) int {
 				outPath = "."
 			}
 		}
-		outPath = utils.EnsureAbsPath(outPath)
-
-		// Check for duplicates/extensions if we are in TUI mode (serverProgram != nil)
-		// For headless/root direct add, we might skip prompt or auto-approve?
-		// For now, let's just add directly if headless, or prompt if TUI is up.
-
-		// If TUI is up (serverProgram != nil), we might want to send a request msg?
-		// But processDownloads is called from QUEUE init routine, primarily for CLI args.
-		// If CLI args provided, user probably wants them added immediately.
+		outPath = utils.EnsureAbsPath(e.OutputDir(outPath))
 
-		_, err := GlobalService.Add(url, outPath, "", mirrors, nil)
+		// CLI args and batch files are added directly, without the TUI prompt:
+		// the user asked for them explicitly.
+		id, err := GlobalService.AddWithOptions(e.URL, outPath, e.Filename, e.Mirrors, e.Headers, entryOptions(e, opts))
 		if err != nil {
-			fmt.Printf("Error adding %s: %v\n", url, err)
+			fmt.Printf("Error adding %s: %v\n", e.URL, err)
 			continue
 		}
 		atomic.AddInt32(&activeDownloads, 1)
-		successCount++
+		ids = append(ids, id)
 	}
-	return successCount
+	return ids
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -922,6 +1010,12 @@ func init() {
 	rootCmd.Flags().StringP("output", "o", "", "Default output directory")
 	rootCmd.Flags().Bool("no-resume", false, "Do not auto-resume paused downloads on startup")
 	rootCmd.Flags().Bool("exit-when-done", false, "Exit when all downloads complete")
+	rootCmd.Flags().BoolVar(&noSocket, "no-socket", false, "Do not serve the API on a Unix socket in the runtime directory")
+	rootCmd.Flags().String("range", "", "Download only bytes START-END of each file (inclusive, e.g. 1GB-1.5GB)")
+	rootCmd.Flags().String("head", "", "Download only the first SIZE bytes of each file (e.g. 100MB)")
+	rootCmd.Flags().StringArray("seed", nil, "Older local copy (file or directory) to reuse matching blocks from (repeatable)")
+	rootCmd.Flags().Bool("globoff", false, "Take URLs literally instead of expanding [] and {} patterns")
+	rootCmd.Flags().String("zsync", "", "zsync control file or block hash list for --seed (URL or path; default: URL + \".zsync\")")
 	rootCmd.SetVersionTemplate("Surge v{{.Version}}\n")
 }
 
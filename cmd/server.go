@@ -1,8 +1,8 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"fmt"
-
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -14,6 +14,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -132,6 +133,11 @@ func init() {
 	serverStartCmd.Flags().StringP("output", "o", "", "Default output directory")
 	serverStartCmd.Flags().Bool("exit-when-done", false, "Exit when all downloads complete")
 	serverStartCmd.Flags().Bool("no-resume", false, "Do not auto-resume paused downloads on startup")
+	serverStartCmd.Flags().Bool("globoff", false, "Take URLs literally instead of expanding [] and {} patterns")
+	serverStartCmd.Flags().BoolVar(&noSocket, "no-socket", false, "Do not serve the API on a Unix socket in the runtime directory")
+	serverStartCmd.Flags().Bool("tls", false, "Serve HTTPS with a self-signed certificate generated in the state directory")
+	serverStartCmd.Flags().String("tls-cert", "", "Serve HTTPS with this PEM certificate (requires --tls-key)")
+	serverStartCmd.Flags().String("tls-key", "", "PEM private key for --tls-cert")
 }
 
 func savePID() {
@@ -166,36 +172,55 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 		os.Exit(1)
 	}
 
+	// Serve HTTPS from the given certificate, or a generated self-signed one
+	removeActiveTLS()
+	useTLS, _ := cmd.Flags().GetBool("tls")
+	globoff, _ := cmd.Flags().GetBool("globoff")
+	certFile, _ := cmd.Flags().GetString("tls-cert")
+	keyFile, _ := cmd.Flags().GetString("tls-key")
+	var fingerprint string
+	if useTLS || certFile != "" || keyFile != "" {
+		var tlsConfig *tls.Config
+		tlsConfig, fingerprint, err = loadServerTLS(certFile, keyFile)
+		if err != nil {
+			_ = listener.Close()
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+		saveActiveTLS(fingerprint)
+		defer removeActiveTLS()
+	}
+
 	// Initialize Service
 	GlobalService = core.NewLocalDownloadServiceWithInput(GlobalPool, GlobalProgressCh)
 
 	saveActivePort(port)
 	defer removeActivePort()
+	defer removeSocket()
 
 	go startHTTPServer(listener, port, outputDir, GlobalService)
 
 	// Queue initial downloads
 	go func() {
-		var urls []string
-		urls = append(urls, args...)
-
-		if batchFile != "" {
-			fileUrls, err := readURLsFromFile(batchFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
-			} else {
-				urls = append(urls, fileUrls...)
-			}
+		entries, err := collectEntries(args, "", batchFile, globoff)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
 
-		if len(urls) > 0 {
-			processDownloads(urls, outputDir, 0)
+		if len(entries) > 0 {
+			processEntries(entries, outputDir, 0, types.DownloadOptions{})
 		}
 	}()
 
 	fmt.Printf("Surge %s running in server mode.\n", Version)
 	host := getServerBindHost()
-	fmt.Printf("Serving on %s:%d\n", host, port)
+	if fingerprint != "" {
+		fmt.Printf("Serving HTTPS on %s:%d\n", host, port)
+		fmt.Printf("Certificate fingerprint (SHA-256): %s\n", fingerprint)
+	} else {
+		fmt.Printf("Serving on %s:%d\n", host, port)
+	}
 	fmt.Println("Press Ctrl+C to exit.")
 
 	StartHeadlessConsumer()
@@ -218,6 +243,8 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 						// Manual cleanup
 						removePID()
 						removeActivePort()
+						removeActiveTLS()
+						removeSocket()
 						os.Exit(0)
 					}
 				}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// socketName is the API socket's file name in the runtime dir
+const socketName = "surge.sock"
+
+// socketHost is the placeholder host in URLs for requests sent over the socket
+const socketHost = "surge.sock"
+
+// socketOnlyPort stands in for the port of a daemon reachable only over the
+// socket. It is not a valid TCP port, so nothing is ever dialed on it.
+const socketOnlyPort = 1 << 16
+
+// noSocket disables the Unix socket listener (--no-socket)
+var noSocket bool
+
+func socketPath() string {
+	return filepath.Join(config.GetRuntimeDir(), socketName)
+}
+
+// listenSocket creates the API socket, readable only by the current user.
+// A socket left behind by a daemon that crashed is replaced.
+//
+// The socket is bound inside a fresh 0700 directory and restricted there
+// before it is moved into place, so it is never reachable with the umask's
+// permissions, even when the runtime dir falls back to the shared state dir.
+func listenSocket() (net.Listener, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("unix sockets are not used on Windows")
+	}
+
+	path := socketPath()
+	if _, err := os.Lstat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("another daemon is listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+	private, err := os.MkdirTemp(filepath.Dir(path), ".surge-sock-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(private) }()
+
+	bound := filepath.Join(private, socketName)
+	ln, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// The listener would otherwise try to unlink the temporary path on Close;
+	// removeSocket deletes the real one
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	// File permissions are the access control: only the owner may connect
+	if err := os.Chmod(bound, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	if err := os.Rename(bound, path); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to move socket into place: %w", err)
+	}
+	return ln, nil
+}
+
+// serveSocket serves handler on the socket. Anyone who can open it already
+// has the owner's file access, so requests are trusted as the root client.
+func serveSocket(ln net.Listener, handler http.Handler) {
+	trusted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(withClient(r.Context(), rootClient.from("local"))))
+	})
+	server := &http.Server{Handler: trusted}
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+		utils.Debug("Socket server error: %v", err)
+	}
+}
+
+// removeSocket deletes the API socket on shutdown
+func removeSocket() {
+	if err := os.Remove(socketPath()); err != nil && !os.IsNotExist(err) {
+		utils.Debug("Error removing socket: %v", err)
+	}
+}
+
+// socketTransport sends every request to the local API socket
+func socketTransport() *http.Transport {
+	path := socketPath()
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+}
+
+// socketAvailable reports whether a daemon is accepting connections on the socket
+func socketAvailable() bool {
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", socketPath(), 500*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// daemonPort returns the port of the daemon running on this machine, or 0 if
+// none is. The socket is probed first: a daemon that answers on it but left
+// no port file is reported as socketOnlyPort, which localClient resolves to
+// the socket.
+func daemonPort() int {
+	if socketAvailable() {
+		if port := readActivePort(); port > 0 {
+			return port
+		}
+		return socketOnlyPort
+	}
+	return readActivePort()
+}
+
+// localClient returns the base URL and client for the daemon running on this
+// machine: the Unix socket when it is up, otherwise TCP on port (pinned to
+// the daemon's certificate when it serves TLS)
+func localClient(port int) (string, *http.Client) {
+	if socketAvailable() {
+		return "http://" + socketHost, &http.Client{Transport: socketTransport()}
+	}
+	return localServerURL(port), localHTTPClient()
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+)
+
+// setupSocketDir points the runtime dir at a short temporary path, since
+// socket paths are limited to about 100 bytes
+func setupSocketDir(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not used on Windows")
+	}
+	dir, err := os.MkdirTemp("", "srg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+}
+
+func TestListenSocket_PermissionsAndStaleSocket(t *testing.T) {
+	setupSocketDir(t)
+
+	// A socket left behind by a crashed daemon is replaced
+	if err := os.MkdirAll(filepath.Dir(socketPath()), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := net.Listen("unix", socketPath())
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := listenSocket()
+	if err != nil {
+		t.Fatalf("listenSocket failed: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	info, err := os.Stat(socketPath())
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected socket mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(socketPath()))
+	if len(entries) != 1 || entries[0].Name() != socketName {
+		t.Errorf("Expected only the socket in the runtime dir, got %v", entries)
+	}
+
+	// A live socket is left alone
+	if _, err := listenSocket(); err == nil {
+		t.Error("Expected listenSocket to refuse a socket in use")
+	}
+}
+
+func TestSocket_TrustedWithoutToken(t *testing.T) {
+	setupIsolatedCmdState(t)
+	setupSocketDir(t)
+	requireTCPListener(t)
+
+	noSocket = false
+	t.Cleanup(func() { noSocket = true })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	t.Cleanup(func() { _ = ln.Close() })
+
+	svc := core.NewLocalDownloadService(download.NewWorkerPool(nil, 1))
+	go startHTTPServer(ln, port, "", svc)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !socketAvailable() {
+		if time.Now().After(deadline) {
+			t.Fatal("Socket listener did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Over TCP a token is required; the socket needs none
+	resp, err := http.Get(localServerURL(port) + "/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 over TCP, got %d", resp.StatusCode)
+	}
+
+	baseURL, client := localClient(port)
+	if baseURL != "http://"+socketHost {
+		t.Errorf("Expected the socket to be preferred, got %s", baseURL)
+	}
+	resp, err = client.Post(baseURL+"/pause?id=missing", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Socket request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		t.Errorf("Socket request was not trusted: %d", resp.StatusCode)
+	}
+
+	// Local commands go through the socket even when the port is wrong
+	if _, err := GetRemoteDownloads(1); err != nil {
+		t.Errorf("GetRemoteDownloads over socket failed: %v", err)
+	}
+
+	// and find the daemon without a port file
+	if got := daemonPort(); got != socketOnlyPort {
+		t.Errorf("Expected daemonPort %d without a port file, got %d", socketOnlyPort, got)
+	}
+	if _, err := GetRemoteDownloads(daemonPort()); err != nil {
+		t.Errorf("GetRemoteDownloads for a socket-only daemon failed: %v", err)
+	}
+}
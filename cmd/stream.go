@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+const (
+	// streamPollInterval is how often a blocked stream reader re-checks the chunk map
+	streamPollInterval = 100 * time.Millisecond
+	// streamBufferSize is the copy buffer used when serving file data
+	streamBufferSize = 256 * 1024
+	// streamStallTimeout ends a response whose next bytes haven't arrived for this long
+	streamStallTimeout = 2 * time.Minute
+
+	// streamLinkTTL and streamLinkMaxTTL bound the lifetime of signed stream links
+	streamLinkTTL    = time.Hour
+	streamLinkMaxTTL = 7 * 24 * time.Hour
+)
+
+// streamClient is the caller of a valid signed stream link
+var streamClient = &apiClient{Name: "stream link", Scope: scopeRead}
+
+// streamSignature authenticates a link to download id that expires at the
+// Unix time expires
+func streamSignature(key, id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = fmt.Fprintf(mac, "stream\n%s\n%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedStreamPath returns the path and query of a link that plays download id
+// until expires, without exposing an API token
+func signedStreamPath(key, id string, expires int64) string {
+	q := url.Values{
+		"expires": {strconv.FormatInt(expires, 10)},
+		"sig":     {streamSignature(key, id, expires)},
+	}
+	return "/stream/" + url.PathEscape(id) + "?" + q.Encode()
+}
+
+// streamLinkClient returns streamClient if r carries a valid, unexpired
+// signature for the download it requests, or nil
+func streamLinkClient(r *http.Request, key string, now time.Time) *apiClient {
+	id := strings.TrimPrefix(r.URL.Path, "/stream/")
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if key == "" || err != nil || now.Unix() >= expires {
+		return nil
+	}
+	if !hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(streamSignature(key, id, expires))) {
+		return nil
+	}
+	return streamClient
+}
+
+// handleStream serves a download's bytes with Range support so media players can
+// play it while it downloads. Active downloads (sequential mode only) are served
+// from the working file as chunks land; reads past the downloaded region block and
+// ask the downloader to fetch that range next. Completed downloads are served as-is.
+func handleStream(w http.ResponseWriter, r *http.Request, pool *download.WorkerPool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/stream/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Missing download id", http.StatusBadRequest)
+		return
+	}
+
+	if pool != nil {
+		if cfg, ok := pool.GetConfig(id); ok && cfg.State != nil && !cfg.State.Done.Load() {
+			if cfg.Runtime == nil || !cfg.Runtime.SequentialDownload {
+				http.Error(w, "Streaming requires sequential download mode", http.StatusConflict)
+				return
+			}
+			if cfg.State.IsPaused() {
+				http.Error(w, "Download is paused", http.StatusConflict)
+				return
+			}
+			streamActiveDownload(w, r, pool, id, cfg.State)
+			return
+		}
+	}
+
+	// Finished downloads are plain files on disk
+	entry, err := state.GetDownload(id)
+	if err != nil || entry == nil {
+		http.Error(w, "Download not found", http.StatusNotFound)
+		return
+	}
+	if entry.Status != "completed" {
+		http.Error(w, "Download is not active", http.StatusConflict)
+		return
+	}
+
+	f, err := os.Open(entry.DestPath)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Failed to stat file", http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, filepath.Base(entry.DestPath), info.ModTime(), f)
+}
+
+// streamActiveDownload writes the requested range of an in-progress download,
+// waiting for missing chunks to arrive
+func streamActiveDownload(w http.ResponseWriter, r *http.Request, pool *download.WorkerPool, id string, st *types.ProgressState) {
+	destPath := st.GetDestPath()
+	_, total, _, _, _, _ := st.GetProgress()
+	if destPath == "" || total <= 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Download has not started yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	start, end, partial, err := parseByteRange(r.Header.Get("Range"), total)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", total))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(destPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+
+	status := http.StatusOK
+	if partial {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, total))
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, streamBufferSize)
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+
+	pos := start
+	lastData := time.Now()
+	for pos <= end {
+		available := st.ContiguousAvailable(pos)
+		if available <= pos {
+			if err := st.GetError(); err != nil {
+				utils.Debug("Stream %s: download failed: %v", id, err)
+				return
+			}
+			// The bytes won't come while paused; end the response rather than hang
+			if st.IsPaused() {
+				utils.Debug("Stream %s: download paused", id)
+				return
+			}
+			if time.Since(lastData) > streamStallTimeout {
+				utils.Debug("Stream %s: no data for %v", id, streamStallTimeout)
+				return
+			}
+			if pool != nil {
+				if _, ok := pool.GetConfig(id); !ok && !st.Done.Load() {
+					utils.Debug("Stream %s: download removed", id)
+					return
+				}
+			}
+
+			st.RequestStreamOffset(pos)
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		limit := available
+		if limit > end+1 {
+			limit = end + 1
+		}
+		n, err := copyStreamRange(w, st, destPath, pos, limit, buf)
+		pos += n
+		lastData = time.Now()
+		if err != nil {
+			utils.Debug("Stream %s: %v", id, err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// copyStreamRange copies bytes [from, to) of the download to w. It reads the working
+// file, or the final file once the download has finished and been renamed.
+func copyStreamRange(w io.Writer, st *types.ProgressState, destPath string, from, to int64, buf []byte) (int64, error) {
+	path := destPath + types.IncompleteSuffix
+	if st.Done.Load() {
+		path = destPath
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && path != destPath {
+		// Finished and renamed between the availability check and the open
+		f, err = os.Open(destPath)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return io.CopyBuffer(w, io.NewSectionReader(f, from, to-from), buf)
+}
+
+// parseByteRange parses a single-range Range header against a resource of the given size.
+// It returns the inclusive byte span and whether the response is partial.
+func parseByteRange(header string, size int64) (start, end int64, partial bool, err error) {
+	if header == "" {
+		return 0, size - 1, false, nil
+	}
+
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, 0, false, fmt.Errorf("invalid range unit")
+	}
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, fmt.Errorf("multiple ranges are not supported")
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, false, fmt.Errorf("invalid range %q", spec)
+	}
+
+	if first == "" {
+		// Suffix range: last N bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, fmt.Errorf("invalid range %q", spec)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, fmt.Errorf("range %q not satisfiable", spec)
+	}
+
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, fmt.Errorf("invalid range %q", spec)
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	return start, end, true, nil
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantStart   int64
+		wantEnd     int64
+		wantPartial bool
+		wantErr     bool
+	}{
+		{"no header", "", 0, 999, false, false},
+		{"closed range", "bytes=100-199", 100, 199, true, false},
+		{"open range", "bytes=900-", 900, 999, true, false},
+		{"suffix range", "bytes=-100", 900, 999, true, false},
+		{"suffix larger than file", "bytes=-5000", 0, 999, true, false},
+		{"end clamped", "bytes=500-5000", 500, 999, true, false},
+		{"start past end", "bytes=1000-", 0, 0, false, true},
+		{"reversed", "bytes=200-100", 0, 0, false, true},
+		{"multiple ranges", "bytes=0-1,5-6", 0, 0, false, true},
+		{"wrong unit", "items=0-1", 0, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, partial, err := parseByteRange(tt.header, 1000)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if start != tt.wantStart || end != tt.wantEnd || partial != tt.wantPartial {
+				t.Errorf("got (%d, %d, %v), want (%d, %d, %v)", start, end, partial, tt.wantStart, tt.wantEnd, tt.wantPartial)
+			}
+		})
+	}
+}
+
+// newStreamingState creates a working file with known content and a state tracking it
+func newStreamingState(t *testing.T, size, chunkSize int64) (*types.ProgressState, []byte) {
+	t.Helper()
+
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(destPath+types.IncompleteSuffix, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := types.NewProgressState("stream-id", size)
+	st.SetDestPath(destPath)
+	st.InitBitmap(size, chunkSize)
+	return st, content
+}
+
+func TestStreamActiveDownload_ServesAvailableRange(t *testing.T) {
+	st, content := newStreamingState(t, 1000, 100)
+	st.UpdateChunkStatus(0, 500, types.ChunkCompleted)
+
+	req := httptest.NewRequest(http.MethodGet, "/stream/stream-id", nil)
+	req.Header.Set("Range", "bytes=100-299")
+	rec := httptest.NewRecorder()
+
+	streamActiveDownload(rec, req, nil, "stream-id", st)
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 100-299/1000" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content[100:300]) {
+		t.Error("Body does not match requested range")
+	}
+}
+
+func TestStreamActiveDownload_BlocksUntilChunkArrives(t *testing.T) {
+	st, content := newStreamingState(t, 1000, 100)
+	st.UpdateChunkStatus(0, 100, types.ChunkCompleted)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamActiveDownload(w, r, nil, "stream-id", st)
+	}))
+	defer server.Close()
+
+	// Complete the missing chunks once the reader has asked for them
+	go func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if offset, ok := st.TakeStreamOffset(); ok {
+				st.UpdateChunkStatus(offset, 1000-offset, types.ChunkCompleted)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Range", "bytes=50-749")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, content[50:750]) {
+		t.Errorf("Body length %d does not match requested range", len(body))
+	}
+}
+
+func TestStreamActiveDownload_NotStarted(t *testing.T) {
+	st := types.NewProgressState("stream-id", 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/stream/stream-id", nil)
+	rec := httptest.NewRecorder()
+
+	streamActiveDownload(rec, req, nil, "stream-id", st)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestHandleStream_RejectsPost(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/stream/abc", nil)
+	rec := httptest.NewRecorder()
+
+	handleStream(rec, req, nil)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
+
+func TestStreamActiveDownload_EndsWhenPaused(t *testing.T) {
+	st, content := newStreamingState(t, 1000, 100)
+	st.UpdateChunkStatus(0, 100, types.ChunkCompleted)
+	st.Paused.Store(true)
+
+	req := httptest.NewRequest(http.MethodGet, "/stream/stream-id", nil)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		streamActiveDownload(rec, req, nil, "stream-id", st)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream of a paused download did not return")
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content[:100]) {
+		t.Errorf("Expected only the downloaded bytes, got %d", rec.Body.Len())
+	}
+}
+
+func TestAuthMiddleware_SignedStreamLink(t *testing.T) {
+	handler := authMiddleware("secret-token", newAuthThrottle(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c := clientFrom(r.Context()); c == nil || c.Scope != scopeRead {
+			t.Errorf("Expected a read-only client, got %+v", c)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+	valid := signedStreamPath("secret-token", "abc", future)
+	for path, want := range map[string]int{
+		valid: http.StatusOK,
+		signedStreamPath("secret-token", "abc", past):             http.StatusUnauthorized,
+		signedStreamPath("other-token", "abc", future):            http.StatusUnauthorized,
+		strings.Replace(valid, "/stream/abc", "/stream/xyz", 1):   http.StatusUnauthorized,
+		"/stream/abc?token=secret-token":                          http.StatusUnauthorized,
+		strings.Replace(valid, "/stream/abc", "/api/v1/audit", 1): http.StatusUnauthorized,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <manifest>",
+	Short: "Download the files of a manifest that changed on the server",
+	Long: `Refresh local copies of the files listed in a manifest, a batch file in the
+same format as surge add --batch (one URL per line, with optional aria2
+options such as dir= and out=).
+
+Each file is downloaded only if it changed on the server, as with wget -N:
+its size, ETag and Last-Modified are compared with the local file and the
+history entry of its last download. A changed file replaces the local copy
+once its download completes; files that are still current are left alone.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		output, _ := cmd.Flags().GetString("output")
+		wait, _ := cmd.Flags().GetBool("wait")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		entries, err := collectEntries(nil, "", args[0], false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no URLs found in %s\n", args[0])
+			os.Exit(1)
+		}
+
+		port := daemonPort()
+		if port == 0 {
+			fmt.Println("Error: Surge is not running.")
+			fmt.Println("Start it with 'surge server start'.")
+			os.Exit(1)
+		}
+
+		ids := processEntries(entries, output, port, types.DownloadOptions{IfModified: true})
+
+		if !wait {
+			fmt.Printf("Checking %d of %d files.\n", len(ids), len(entries))
+			if len(ids) < len(entries) {
+				os.Exit(1)
+			}
+			return
+		}
+
+		code := runWait(localService(port), ids, true, timeout)
+		if code == 0 && len(ids) < len(entries) {
+			code = waitExitFailed
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringP("output", "o", "", "Directory the manifest's files are kept in")
+	syncCmd.Flags().Bool("wait", false, "Wait for the files to be checked and downloaded, and print their paths")
+	syncCmd.Flags().Duration("timeout", 0, "With --wait, give up after this long (e.g. 30m)")
+}
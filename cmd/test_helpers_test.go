@@ -2,7 +2,13 @@ package cmd
 
 import (
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/types"
 )
 
 func requireTCPListener(t *testing.T) {
@@ -14,3 +20,50 @@ func requireTCPListener(t *testing.T) {
 	}
 	_ = ln.Close()
 }
+
+// queueBehindStalledDownload adds two downloads to a pool with a single worker.
+// The first one's server never answers, so the second waits for a worker until
+// the test ends. It returns the IDs of the running and the waiting download.
+func queueBehindStalledDownload(t *testing.T, pool *download.WorkerPool) (running, waiting string) {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		// Let both downloads fail, so nothing writes to the test's directories afterwards
+		close(release)
+		server.Close()
+		deadline := time.Now().Add(10 * time.Second)
+		for len(pool.GetAll()) > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	running, waiting = "stalled-download", "waiting-download"
+	for _, id := range []string{running, waiting} {
+		pool.Add(types.DownloadConfig{
+			ID:         id,
+			URL:        server.URL + "/" + id + ".bin",
+			OutputPath: t.TempDir(),
+			Filename:   id + ".bin",
+			State:      types.NewProgressState(id, 0),
+			Runtime:    &types.RuntimeConfig{},
+		})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.QueuedIDs()[running] {
+		if time.Now().After(deadline) {
+			t.Fatal("First download was never handed to a worker")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !pool.QueuedIDs()[waiting] {
+		t.Fatal("Expected the second download to wait for a worker")
+	}
+	return running, waiting
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Failed authentication handling: after authDelayAfter failures each further
+// failure is answered more slowly, and an address that fails authFailureLimit
+// times within authFailureWindow is locked out for authLockoutDuration.
+const (
+	authFailureLimit    = 10
+	authFailureWindow   = 10 * time.Minute
+	authLockoutDuration = 15 * time.Minute
+	authDelayAfter      = 3
+	authDelayStep       = 250 * time.Millisecond
+	authMaxDelay        = 2 * time.Second
+)
+
+// authThrottle tracks failed authentication attempts per client address.
+// A nil throttle never delays or locks out.
+type authThrottle struct {
+	mu       sync.Mutex
+	failures map[string]*authFailures
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+type authFailures struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+func newAuthThrottle() *authThrottle {
+	return &authThrottle{failures: make(map[string]*authFailures), now: time.Now, sleep: time.Sleep}
+}
+
+// lockedFor returns how much longer addr is locked out, or 0
+func (t *authThrottle) lockedFor(addr string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.failures[addr]
+	if f == nil {
+		return 0
+	}
+	return max(f.lockedUntil.Sub(t.now()), 0)
+}
+
+// fail records a failed attempt from addr. It returns how long to hold the
+// response, and whether this failure started a lockout.
+func (t *authThrottle) fail(addr string) (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	f := t.failures[addr]
+	if f == nil || now.Sub(f.first) > authFailureWindow {
+		t.prune(now)
+		f = &authFailures{first: now}
+		t.failures[addr] = f
+	}
+	f.count++
+
+	if f.count >= authFailureLimit {
+		// Start the next window fresh once the lockout ends
+		t.failures[addr] = &authFailures{first: now, lockedUntil: now.Add(authLockoutDuration)}
+		return 0, true
+	}
+	if f.count <= authDelayAfter {
+		return 0, false
+	}
+	return min(time.Duration(f.count-authDelayAfter)*authDelayStep, authMaxDelay), false
+}
+
+// prune drops addresses with no recent failures and no lockout. Called with mu held.
+func (t *authThrottle) prune(now time.Time) {
+	for addr, f := range t.failures {
+		if now.Sub(f.first) > authFailureWindow && now.After(f.lockedUntil) {
+			delete(t.failures, addr)
+		}
+	}
+}
+
+// authFailed records a failed attempt from addr and holds the response
+// back as the throttle asks. The start of a lockout goes in the audit log.
+func authFailed(t *authThrottle, addr string) {
+	delay, locked := t.fail(addr)
+	if locked {
+		utils.Debug("Locked out %s after %d failed authentication attempts", addr, authFailureLimit)
+		recordAudit(&apiClient{Addr: addr}, auditLockout, "", "", fmt.Sprintf("%d failed authentication attempts", authFailureLimit))
+	}
+	if delay > 0 {
+		t.sleep(delay)
+	}
+}
+
+// writeLockedOut answers a request from a locked-out address
+func writeLockedOut(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+	writeAuthError(w, r, http.StatusTooManyRequests, "Too many failed authentication attempts; try again later")
+}
+
+// clientIP returns the address a request came from, without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
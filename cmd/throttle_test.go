@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+func TestAuthThrottle_DelayAndLockout(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	th := newAuthThrottle()
+	th.now = func() time.Time { return now }
+
+	for i := 1; i <= authDelayAfter; i++ {
+		if delay, locked := th.fail("10.0.0.5"); delay != 0 || locked {
+			t.Fatalf("failure %d: expected no delay, got %v (locked %v)", i, delay, locked)
+		}
+	}
+	delay, _ := th.fail("10.0.0.5")
+	if delay != authDelayStep {
+		t.Errorf("Expected first throttled failure to wait %v, got %v", authDelayStep, delay)
+	}
+
+	var locked bool
+	for i := authDelayAfter + 2; i <= authFailureLimit; i++ {
+		_, locked = th.fail("10.0.0.5")
+	}
+	if !locked {
+		t.Fatal("Expected lockout after reaching the failure limit")
+	}
+	if wait := th.lockedFor("10.0.0.5"); wait != authLockoutDuration {
+		t.Errorf("Expected lockout of %v, got %v", authLockoutDuration, wait)
+	}
+	if wait := th.lockedFor("10.0.0.6"); wait != 0 {
+		t.Errorf("Other addresses should not be locked, got %v", wait)
+	}
+
+	now = now.Add(authLockoutDuration + time.Second)
+	if wait := th.lockedFor("10.0.0.5"); wait != 0 {
+		t.Errorf("Expected lockout to expire, got %v", wait)
+	}
+
+	// Failures spread wider than the window never add up to a lockout
+	for i := 0; i < authFailureLimit*2; i++ {
+		now = now.Add(authFailureWindow/authDelayAfter + time.Second)
+		if _, locked := th.fail("10.0.0.7"); locked {
+			t.Fatal("Slow failures should not lock out")
+		}
+	}
+
+	if delay, locked := (*authThrottle)(nil).fail("x"); delay != 0 || locked || (*authThrottle)(nil).lockedFor("x") != 0 {
+		t.Error("A nil throttle should do nothing")
+	}
+}
+
+func TestAuthMiddleware_LocksOutAndAudits(t *testing.T) {
+	setupIsolatedCmdState(t)
+	th := newAuthThrottle()
+	th.sleep = func(time.Duration) {}
+	handler := authMiddleware("root-secret", th, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.RemoteAddr = "192.168.1.50:40000"
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < authFailureLimit; i++ {
+		if rec := send("wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, rec.Code)
+		}
+	}
+	// Even the right token is refused while locked out
+	rec := send("root-secret")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d", rec.Code)
+	}
+
+	entries, err := state.ListAudit(state.AuditFilter{Action: auditLockout})
+	if err != nil || len(entries) != 1 || entries[0].Addr != "192.168.1.50" {
+		t.Errorf("Expected one lockout entry for the address, got %+v (%v)", entries, err)
+	}
+}
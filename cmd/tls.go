@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Self-signed certificate files, kept in the state dir
+const (
+	tlsCertFile = "tls-cert.pem"
+	tlsKeyFile  = "tls-key.pem"
+)
+
+// selfSignedValidity is how long a generated certificate is valid for
+const selfSignedValidity = 10 * 365 * 24 * time.Hour
+
+// loadServerTLS loads the certificate and key to serve, generating a
+// self-signed pair in the state dir when none is given. It returns the
+// certificate's SHA-256 fingerprint along with the config.
+func loadServerTLS(certFile, keyFile string) (*tls.Config, string, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, "", errors.New("--tls-cert and --tls-key must be given together")
+	}
+	if certFile == "" {
+		certFile = filepath.Join(config.GetStateDir(), tlsCertFile)
+		keyFile = filepath.Join(config.GetStateDir(), tlsKeyFile)
+		if _, err := os.Stat(certFile); os.IsNotExist(err) {
+			if err := generateSelfSignedCert(certFile, keyFile, time.Now()); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return cfg, certFingerprint(cert.Certificate[0]), nil
+}
+
+// generateSelfSignedCert writes a new ECDSA certificate valid for this
+// machine's host name and addresses
+func generateSelfSignedCert(certPath, keyPath string, now time.Time) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Surge daemon"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		tmpl.DNSNames = append(tmpl.DNSNames, host)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				tmpl.IPAddresses = append(tmpl.IPAddresses, ipNet.IP)
+			}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+	utils.Debug("Generated self-signed TLS certificate at %s", certPath)
+	return nil
+}
+
+// certFingerprint returns the SHA-256 fingerprint of a DER certificate as
+// colon-separated hex, the format browsers and openssl show
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// normalizeFingerprint accepts fingerprints with or without separators, in any case
+func normalizeFingerprint(fp string) string {
+	clean := strings.ToUpper(strings.NewReplacer(":", "", " ", "", "-", "").Replace(strings.TrimSpace(fp)))
+	if len(clean) != sha256.Size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(clean); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, sha256.Size)
+	for i := 0; i < len(clean); i += 2 {
+		parts = append(parts, clean[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// pinnedTLSConfig trusts exactly the certificate with the given fingerprint,
+// whatever its issuer or host names
+func pinnedTLSConfig(fingerprint string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Chain and host name checks are replaced by the fingerprint check below
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server sent no certificate")
+			}
+			if got := certFingerprint(cs.PeerCertificates[0].Raw); got != fingerprint {
+				return &fingerprintMismatchError{Expected: fingerprint, Got: got}
+			}
+			return nil
+		},
+	}
+}
+
+// fingerprintMismatchError reports a server certificate that differs from the pinned one
+type fingerprintMismatchError struct {
+	Expected, Got string
+}
+
+func (e *fingerprintMismatchError) Error() string {
+	return fmt.Sprintf("server certificate fingerprint %s does not match pinned %s", e.Got, e.Expected)
+}
+
+// fetchCertFingerprint connects to addr without verification and returns the
+// fingerprint of the certificate it presents, and whether the system trusts it
+func fetchCertFingerprint(addr string) (fingerprint string, trusted bool, err error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return "", false, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	cs := conn.ConnectionState()
+	if len(cs.PeerCertificates) == 0 {
+		return "", false, errors.New("server sent no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+
+	host, _, splitErr := net.SplitHostPort(addr)
+	if splitErr != nil {
+		host = addr
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, verifyErr := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	return certFingerprint(leaf.Raw), verifyErr == nil, nil
+}
+
+// saveActiveTLS records the fingerprint of the certificate the daemon serves,
+// so local commands know to use HTTPS and which certificate to expect
+func saveActiveTLS(fingerprint string) {
+	path := filepath.Join(config.GetRuntimeDir(), "tls_fingerprint")
+	if err := os.WriteFile(path, []byte(fingerprint), 0o644); err != nil {
+		utils.Debug("Error writing TLS fingerprint file: %v", err)
+	}
+}
+
+func removeActiveTLS() {
+	path := filepath.Join(config.GetRuntimeDir(), "tls_fingerprint")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		utils.Debug("Error removing TLS fingerprint file: %v", err)
+	}
+}
+
+func readActiveTLS() string {
+	data, err := os.ReadFile(filepath.Join(config.GetRuntimeDir(), "tls_fingerprint"))
+	if err != nil {
+		return ""
+	}
+	return normalizeFingerprint(string(data))
+}
+
+// localServerURL returns the base URL of the daemon running on this machine
+func localServerURL(port int) string {
+	if readActiveTLS() != "" {
+		return fmt.Sprintf("https://127.0.0.1:%d", port)
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// localHTTPClient returns a client for the local daemon, pinned to its
+// certificate when it serves TLS
+func localHTTPClient() *http.Client {
+	fp := readActiveTLS()
+	if fp == "" {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: pinnedTLSConfig(fp)}}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func newTLSServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	setupIsolatedCmdState(t)
+
+	tlsConfig, fingerprint, err := loadServerTLS("", "")
+	if err != nil {
+		t.Fatalf("loadServerTLS failed: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, fingerprint
+}
+
+func TestLoadServerTLS_SelfSigned(t *testing.T) {
+	_, fingerprint := newTLSServer(t)
+
+	if normalizeFingerprint(fingerprint) != fingerprint || len(fingerprint) != 95 {
+		t.Errorf("Unexpected fingerprint format %q", fingerprint)
+	}
+
+	// The generated pair is reused on the next start
+	_, again, err := loadServerTLS("", "")
+	if err != nil || again != fingerprint {
+		t.Errorf("Expected the same certificate, got %q (%v)", again, err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(config.GetStateDir(), tlsKeyFile))
+		if err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected private key mode 0600, got %v (%v)", info.Mode().Perm(), err)
+		}
+	}
+
+	if _, _, err := loadServerTLS("cert.pem", ""); err == nil {
+		t.Error("Expected an error when only the certificate is given")
+	}
+}
+
+func TestResolveRemoteTLS_TrustOnFirstUse(t *testing.T) {
+	server, fingerprint := newTLSServer(t)
+	hostPort := strings.TrimPrefix(server.URL, "https://")
+
+	// Refusing the unknown certificate aborts
+	if _, _, err := resolveRemoteTLS(hostPort, "", "", func(string) bool { return false }); err == nil {
+		t.Fatal("Expected untrusted certificate to be refused")
+	}
+
+	var offered string
+	cfg, pin, err := resolveRemoteTLS(hostPort, "", "", func(fp string) bool { offered = fp; return true })
+	if err != nil || pin != fingerprint || offered != fingerprint {
+		t.Fatalf("Expected pin %s, got %s (offered %s, err %v)", fingerprint, pin, offered, err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Pinned request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// A stored pin is used without asking, and a different certificate is rejected
+	wrong := normalizeFingerprint(strings.Repeat("ab", 32))
+	cfg, _, err = resolveRemoteTLS(hostPort, wrong, "", func(string) bool {
+		t.Error("Should not prompt when a pin is stored")
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	_, err = client.Get(server.URL)
+	var mismatch *fingerprintMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Got != fingerprint {
+		t.Errorf("Expected fingerprint mismatch, got %v", err)
+	}
+
+	// An explicit fingerprint replaces the stored pin
+	if _, pin, err := resolveRemoteTLS(hostPort, wrong, strings.ReplaceAll(strings.ToLower(fingerprint), ":", ""), nil); err != nil || pin != fingerprint {
+		t.Errorf("Expected explicit pin %s, got %s (%v)", fingerprint, pin, err)
+	}
+	if _, _, err := resolveRemoteTLS(hostPort, "", "not-hex", nil); err == nil {
+		t.Error("Expected invalid fingerprint to be rejected")
+	}
+}
+
+func TestRemotesStore(t *testing.T) {
+	setupIsolatedCmdState(t)
+
+	if err := saveRemote("nas:1700", remoteEntry{Fingerprint: "AA", Token: "secret"}); err != nil {
+		t.Fatalf("saveRemote failed: %v", err)
+	}
+	if err := saveRemote("other:443", remoteEntry{Token: "t2"}); err != nil {
+		t.Fatalf("saveRemote failed: %v", err)
+	}
+	remotes, err := loadRemotes()
+	if err != nil || len(remotes) != 2 || remotes["nas:1700"].Token != "secret" || remotes["nas:1700"].Fingerprint != "AA" {
+		t.Errorf("Unexpected remotes %+v (%v)", remotes, err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(remotesFile()); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected remotes mode 0600, got %v (%v)", info.Mode().Perm(), err)
+		}
+	}
+
+	var out bytes.Buffer
+	if !confirmFingerprint(strings.NewReader("yes\n"), &out, "nas:1700", "AA") || !strings.Contains(out.String(), "AA") {
+		t.Errorf("Expected confirmation, prompt was %q", out.String())
+	}
+	if confirmFingerprint(strings.NewReader("\n"), &out, "nas:1700", "AA") {
+		t.Error("Expected empty answer to refuse")
+	}
+}
@@ -1,20 +1,194 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
 )
 
 var tokenCmd = &cobra.Command{
 	Use:   "token",
 	Short: "Print the auth token used by the Surge daemon",
+	Long: `Print the daemon's root auth token, which has full control.
+
+Use the create, list and revoke subcommands to manage named tokens with
+limited scopes for individual clients.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		token := ensureAuthToken()
 		fmt.Println(token)
 	},
 }
 
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a named API token",
+	Long: `Create a named API token and print its secret. The secret is shown only once.
+
+Scopes:
+  read  list downloads and history, stream events and files
+  add   queue new downloads only
+  full  everything, including pause, resume and delete`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		scope, _ := cmd.Flags().GetString("scope")
+		expires, _ := cmd.Flags().GetString("expires")
+		dirs, _ := cmd.Flags().GetStringArray("dir")
+
+		secret, tok, err := createToken(args[0], scope, expires, dirs, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(secret)
+		fmt.Fprintf(os.Stderr, "Created %s token %q. Store the secret now: it cannot be shown again.\n", tok.Scope, tok.Name)
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List named API tokens",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		tokens, err := state.ListTokens()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No named tokens.")
+			return
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NAME\tSCOPE\tEXPIRES\tLAST USED\tDIRECTORIES")
+		for _, t := range tokens {
+			dirs := "any"
+			if len(t.AllowedDirs) > 0 {
+				dirs = strings.Join(t.AllowedDirs, ", ")
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Name, t.Scope, formatExpiry(t.ExpiresAt, now), formatUnix(t.LastUsedAt, "never"), dirs)
+		}
+		_ = w.Flush()
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <name>",
+	Short: "Revoke a named API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		ok, err := state.RevokeToken(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no token named %q\n", args[0])
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked token %q\n", args[0])
+	},
+}
+
+// createToken validates the options, stores a new token and returns its secret
+func createToken(name, scope, expires string, dirs []string, now time.Time) (string, *state.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("token name is required")
+	}
+	if !validScope(scope) {
+		return "", nil, fmt.Errorf("invalid scope %q (want read, add or full)", scope)
+	}
+
+	var expiresAt int64
+	if expires != "" {
+		d, err := parseExpiry(expires)
+		if err != nil {
+			return "", nil, err
+		}
+		expiresAt = now.Add(d).Unix()
+	}
+
+	allowed := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		allowed = append(allowed, utils.EnsureAbsPath(dir))
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	tok := &state.APIToken{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Hash:        hashToken(secret),
+		Scope:       scope,
+		AllowedDirs: allowed,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now.Unix(),
+	}
+	if err := state.CreateToken(*tok); err != nil {
+		return "", nil, err
+	}
+	return secret, tok, nil
+}
+
+// parseExpiry parses a token lifetime: a Go duration such as "12h", or a number of days such as "30d"
+func parseExpiry(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid expiry %q (use e.g. 12h or 30d)", s)
+	}
+	return d, nil
+}
+
+func formatExpiry(expiresAt int64, now time.Time) string {
+	if expiresAt == 0 {
+		return "never"
+	}
+	if now.Unix() >= expiresAt {
+		return "expired"
+	}
+	return formatUnix(expiresAt, "")
+}
+
+func formatUnix(ts int64, zero string) string {
+	if ts == 0 {
+		return zero
+	}
+	return time.Unix(ts, 0).Format("2006-01-02 15:04")
+}
+
 func init() {
+	tokenCreateCmd.Flags().String("scope", scopeRead, "Token scope: read, add or full")
+	tokenCreateCmd.Flags().String("expires", "", "Lifetime, e.g. 12h or 30d (default: never expires)")
+	tokenCreateCmd.Flags().StringArray("dir", nil, "Directory downloads may be saved under (repeatable; default: any)")
+
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
 	rootCmd.AddCommand(tokenCmd)
 }
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -11,7 +10,10 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/surge-downloader/surge/internal/batch"
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
@@ -29,23 +31,49 @@ func readActivePort() int {
 	return port
 }
 
-// readURLsFromFile reads URLs from a file, one per line
-func readURLsFromFile(filepath string) ([]string, error) {
-	file, err := os.Open(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+// listStatuses lists downloads like service.List, except that those still waiting
+// in pool for a worker are reported as "queued" rather than "downloading"
+func listStatuses(service core.DownloadService, pool *download.WorkerPool) ([]types.DownloadStatus, error) {
+	statuses, err := service.List()
+	if err != nil || pool == nil {
+		return statuses, err
+	}
+	queued := pool.QueuedIDs()
+	for i := range statuses {
+		if statuses[i].Status == "downloading" && queued[statuses[i].ID] {
+			statuses[i].Status = "queued"
+		}
 	}
-	defer func() { _ = file.Close() }()
+	return statuses, nil
+}
 
-	var urls []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			urls = append(urls, line)
+// collectEntries gathers downloads from URL arguments and an optional batch
+// file, expanding URL patterns. name, if set, is the output name template for
+// the arguments. With globoff, the arguments are taken literally. Entries
+// collected before an error are still returned.
+func collectEntries(args []string, name, batchFile string, globoff bool) ([]batch.Entry, error) {
+	var entries []batch.Entry
+	for _, arg := range args {
+		entry := batch.ParseURIs(arg)
+		if entry.URL == "" {
+			continue
+		}
+		entry.Filename = name
+		entry.Literal = globoff
+		expanded, err := entry.Finish()
+		if err != nil {
+			return entries, err
 		}
+		entries = append(entries, expanded...)
 	}
-	return urls, scanner.Err()
+	if batchFile != "" {
+		fileEntries, err := batch.ReadFile(batchFile)
+		if err != nil {
+			return entries, fmt.Errorf("batch file %s: %w", batchFile, err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
 }
 
 // ParseURLArg parses a command line argument that might contain comma-separated mirrors
@@ -66,20 +94,28 @@ func ParseURLArg(arg string) (string, []string) {
 
 // sendToServer sends a download request to a running surge server
 func sendToServer(url string, mirrors []string, outPath string, port int) error {
-	reqBody := DownloadRequest{
+	_, _, err := sendDownloadRequest(DownloadRequest{
 		URL:     url,
 		Mirrors: mirrors,
 		Path:    outPath,
-	}
+	}, port)
+	return err
+}
+
+// sendDownloadRequest posts a fully populated download request to a running
+// surge server and returns the ID the server assigned. pending reports that
+// the request is waiting for approval in the TUI, which gives the download a
+// new ID if it is accepted.
+func sendDownloadRequest(reqBody DownloadRequest, port int) (id string, pending bool, err error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", false, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	serverURL := fmt.Sprintf("http://127.0.0.1:%d/download", port)
-	resp, err := http.Post(serverURL, "application/json", bytes.NewBuffer(jsonData))
+	baseURL, client := localClient(port)
+	resp, err := client.Post(baseURL+"/download", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to connect to server: %w", err)
+		return "", false, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -89,27 +125,22 @@ func sendToServer(url string, mirrors []string, outPath string, port int) error
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server error: %s - %s", resp.Status, string(body))
+		return "", false, fmt.Errorf("server error: %s - %s", resp.Status, string(body))
 	}
 
-	// Optional: Print response info (ID etc) if needed, but usually caller handles success msg
-	// Or we can parse ID here and return it?
-	// The caller (add.go/root.go) might want to know ID.
-	// For now, keep it simple as error/nil.
-
 	var respData map[string]interface{}
-	_ = json.NewDecoder(resp.Body).Decode(&respData) // Ignore error? safely
-	if id, ok := respData["id"].(string); ok {
-		// Could log debug
-		_ = id
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		utils.Debug("Failed to decode download response: %v", err)
 	}
-
-	return nil
+	id, _ = respData["id"].(string)
+	status, _ := respData["status"].(string)
+	return id, status == "pending_approval", nil
 }
 
 // GetRemoteDownloads fetches all downloads from the running server
 func GetRemoteDownloads(port int) ([]types.DownloadStatus, error) {
-	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/list", port))
+	baseURL, client := localClient(port)
+	resp, err := client.Get(baseURL + "/list")
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +173,7 @@ func resolveDownloadID(partialID string) (string, error) {
 	var candidates []string
 
 	// 1. Try to get candidates from running server
-	port := readActivePort()
+	port := daemonPort()
 	if port > 0 {
 		remoteDownloads, err := GetRemoteDownloads(port)
 		if err == nil {
@@ -185,3 +216,103 @@ func resolveDownloadID(partialID string) (string, error) {
 
 	return partialID, nil // No match, use as-is (will fail with "not found" later)
 }
+
+// parseRangeFlags turns the --range START-END / --head SIZE flags into download options.
+// Bounds accept size suffixes (e.g. "1GB-1.5GB"); END is inclusive and may be omitted.
+func parseRangeFlags(rangeFlag, headFlag string) (types.DownloadOptions, error) {
+	var opts types.DownloadOptions
+
+	if rangeFlag != "" && headFlag != "" {
+		return opts, fmt.Errorf("--range and --head cannot be used together")
+	}
+
+	if headFlag != "" {
+		n, err := utils.ParseSize(headFlag)
+		if err != nil {
+			return opts, fmt.Errorf("invalid --head: %w", err)
+		}
+		if n <= 0 {
+			return opts, fmt.Errorf("invalid --head: size must be positive")
+		}
+		opts.RangeLength = n
+		return opts, nil
+	}
+
+	if rangeFlag == "" {
+		return opts, nil
+	}
+
+	first, last, ok := strings.Cut(rangeFlag, "-")
+	if !ok || strings.TrimSpace(first) == "" {
+		return opts, fmt.Errorf("invalid --range %q: expected START-END", rangeFlag)
+	}
+
+	start, err := utils.ParseSize(first)
+	if err != nil {
+		return opts, fmt.Errorf("invalid --range start: %w", err)
+	}
+	opts.RangeStart = start
+
+	if strings.TrimSpace(last) != "" {
+		end, err := utils.ParseSize(last)
+		if err != nil {
+			return opts, fmt.Errorf("invalid --range end: %w", err)
+		}
+		if end < start {
+			return opts, fmt.Errorf("invalid --range %q: end is before start", rangeFlag)
+		}
+		opts.RangeLength = end - start + 1
+	}
+	return opts, nil
+}
+
+// parseHeaderFlags turns repeated "Key: Value" flags into a header map
+func parseHeaderFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		key, val, ok := strings.Cut(v, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Key: Value\"", v)
+		}
+		headers[key] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
+
+// isLocalControl reports whether a delta control source is a local path rather than a URL
+func isLocalControl(control string) bool {
+	return control != "" && !strings.HasPrefix(control, "http://") && !strings.HasPrefix(control, "https://")
+}
+
+// parseDeltaFlags applies the --seed/--zsync flags to opts. Local paths are made
+// absolute since the daemon may run from a different working directory.
+func parseDeltaFlags(opts *types.DownloadOptions, seeds []string, control string) error {
+	if control != "" && len(seeds) == 0 {
+		return fmt.Errorf("--zsync requires at least one --seed")
+	}
+
+	for _, seed := range seeds {
+		abs, err := filepath.Abs(seed)
+		if err != nil {
+			return fmt.Errorf("invalid --seed %q: %w", seed, err)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("invalid --seed: %w", err)
+		}
+		opts.Seeds = append(opts.Seeds, abs)
+	}
+
+	if isLocalControl(control) {
+		abs, err := filepath.Abs(control)
+		if err != nil {
+			return fmt.Errorf("invalid --zsync %q: %w", control, err)
+		}
+		control = abs
+	}
+	opts.DeltaControl = control
+	return nil
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// Exit codes of surge wait and surge add --wait
+const (
+	waitExitFailed  = 1   // A download failed, was removed or doesn't exist
+	waitExitTimeout = 124 // --timeout passed first, as with timeout(1)
+)
+
+// waitPollInterval is how often statuses are re-read while waiting. The
+// event stream is the fast path; polling catches anything that finished
+// before it connected.
+const waitPollInterval = 5 * time.Second
+
+// waitOutcome is how a waited-on download ended
+type waitOutcome struct {
+	ID   string
+	Path string // Final file path of a completed download
+	Err  string // Why it failed; empty on success
+}
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <ID>...",
+	Short: "Wait for downloads to finish",
+	Long: `Block until the given downloads have completed or failed, printing the
+final path of each one as it completes.
+
+Exits 0 when all of them completed, 1 when any failed, was removed or does
+not exist, and 124 when --timeout passes first.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		port := daemonPort()
+		if port == 0 {
+			fmt.Fprintln(os.Stderr, "Error: Surge is not running.")
+			os.Exit(1)
+		}
+
+		ids := make([]string, 0, len(args))
+		for _, arg := range args {
+			id, err := resolveDownloadID(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			ids = append(ids, id)
+		}
+		os.Exit(runWait(localService(port), ids, false, timeout))
+	},
+}
+
+// runWait waits for ids on service, printing each outcome, and returns the exit code
+func runWait(service core.DownloadService, ids []string, allowUnknown bool, timeout time.Duration) int {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	failed := 0
+	remaining := len(ids)
+	err := waitForDownloads(ctx, service, ids, allowUnknown, func(o waitOutcome) {
+		remaining--
+		if o.Err != "" {
+			failed++
+			fmt.Fprintf(os.Stderr, "Error: download %s failed: %s\n", shortID(o.ID), o.Err)
+			return
+		}
+		fmt.Println(o.Path)
+	})
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "Error: timed out waiting for %d download(s)\n", remaining)
+		return waitExitTimeout
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return waitExitFailed
+	case failed > 0:
+		return waitExitFailed
+	}
+	return 0
+}
+
+// waitForDownloads follows service's event stream until every download in
+// ids has completed or failed, calling report once for each. Downloads the
+// service doesn't know fail, unless allowUnknown is set for IDs just returned
+// by an add: then they are taken to be not listed yet. An empty ID fails at
+// once. It returns ctx's error if ctx ends first.
+func waitForDownloads(ctx context.Context, service core.DownloadService, ids []string, allowUnknown bool, report func(waitOutcome)) error {
+	pending := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		pending[id] = true
+	}
+	finish := func(o waitOutcome) {
+		if pending[o.ID] {
+			delete(pending, o.ID)
+			report(o)
+		}
+	}
+	if pending[""] {
+		finish(waitOutcome{Err: "no download ID"})
+	}
+
+	// apply settles every pending download whose status shows it has ended
+	apply := func(statuses []types.DownloadStatus) {
+		byID := make(map[string]types.DownloadStatus, len(statuses))
+		for _, s := range statuses {
+			byID[s.ID] = s
+		}
+		for id := range pending {
+			s, ok := byID[id]
+			switch {
+			case !ok && !allowUnknown:
+				finish(waitOutcome{ID: id, Err: "download not found"})
+			case s.Status == "completed":
+				finish(waitOutcome{ID: id, Path: statusPath(s)})
+			case s.Status == "error":
+				finish(waitOutcome{ID: id, Err: orDefault(s.Error, "unknown error")})
+			}
+		}
+	}
+	check := func() error {
+		statuses, err := service.List()
+		if err != nil {
+			return fmt.Errorf("failed to list downloads: %w", err)
+		}
+		apply(statuses)
+		return nil
+	}
+
+	streamCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	stream, cleanup, err := service.StreamEvents(streamCtx)
+	if err != nil {
+		return fmt.Errorf("failed to follow events: %w", err)
+	}
+	defer cleanup()
+
+	if err := check(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := check(); err != nil {
+				return err
+			}
+		case msg, ok := <-stream:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return errors.New("event stream closed")
+			}
+			switch m := msg.(type) {
+			case events.DownloadCompleteMsg:
+				if !pending[m.DownloadID] {
+					continue
+				}
+				// The event carries only the file name; the status has the full path
+				path := m.Filename
+				if s, err := service.GetStatus(m.DownloadID); err == nil && s != nil {
+					path = statusPath(*s)
+				}
+				finish(waitOutcome{ID: m.DownloadID, Path: path})
+			case events.DownloadErrorMsg:
+				reason := "unknown error"
+				if m.Err != nil {
+					reason = m.Err.Error()
+				}
+				finish(waitOutcome{ID: m.DownloadID, Err: reason})
+			case events.DownloadRemovedMsg:
+				finish(waitOutcome{ID: m.DownloadID, Err: "download was removed"})
+			case events.SnapshotMsg:
+				apply(m.Downloads)
+			}
+		}
+	}
+	return nil
+}
+
+// statusPath returns the best known location of a finished download
+func statusPath(s types.DownloadStatus) string {
+	if s.DestPath != "" {
+		return s.DestPath
+	}
+	return s.Filename
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// localService returns a client for the daemon running on this machine,
+// over the same transport as localClient
+func localService(port int) *core.RemoteDownloadService {
+	baseURL, client := localClient(port)
+	service := core.NewRemoteDownloadService(baseURL, ensureAuthToken())
+	service.Client = &http.Client{Transport: client.Transport, Timeout: 30 * time.Second}
+	service.SSEClient = &http.Client{Transport: client.Transport}
+	return service
+}
+
+func init() {
+	waitCmd.Flags().Duration("timeout", 0, "Give up after this long (e.g. 30m; default: wait forever)")
+	rootCmd.AddCommand(waitCmd)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func seedWaitDownloads(t *testing.T) {
+	t.Helper()
+	for _, e := range []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/alpha.iso", Filename: "alpha.iso", DestPath: "/tmp/alpha.iso", Status: "completed"},
+		{ID: "c", URL: "https://example.com/gamma.zip", Filename: "gamma.zip", DestPath: "/tmp/gamma.zip", Status: "paused"},
+		{ID: "d", URL: "https://example.com/delta.zip", Filename: "delta.zip", DestPath: "/tmp/delta.zip", Status: "paused"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("Failed to seed download: %v", err)
+		}
+	}
+}
+
+func TestWaitForDownloads_FollowsEventStream(t *testing.T) {
+	setupIsolatedCmdState(t)
+	seedWaitDownloads(t)
+	svc := core.NewLocalDownloadService(nil)
+	defer func() { _ = svc.Shutdown() }()
+
+	l := newEventLog(eventLogSize, false)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) { handleEvents(w, r, l, svc) })
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) { writeJSON(w, http.StatusOK, mustList(t, svc)) })
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) { handleDownload(w, r, "", svc) })
+	server := httptest.NewServer(authMiddleware("secret", newAuthThrottle(), mux))
+	t.Cleanup(server.Close)
+	remote := core.NewRemoteDownloadService(server.URL, "secret")
+	defer func() { _ = remote.Shutdown() }()
+
+	// Events published before the stream connects are lost, so keep
+	// publishing until the wait is over: outcomes are reported only once
+	done := make(chan struct{})
+	go func() {
+		for {
+			for _, msg := range []interface{}{
+				events.DownloadCompleteMsg{DownloadID: "c", Filename: "gamma.zip"},
+				events.DownloadErrorMsg{DownloadID: "d", Err: errors.New("server went away")},
+			} {
+				for _, e := range toSSEEvents(msg) {
+					l.publish(e)
+				}
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := map[string]waitOutcome{}
+	err := waitForDownloads(ctx, remote, []string{"a", "c", "d"}, false, func(o waitOutcome) {
+		if _, dup := got[o.ID]; dup {
+			t.Errorf("Download %s reported twice", o.ID)
+		}
+		got[o.ID] = o
+	})
+	close(done)
+	if err != nil {
+		t.Fatalf("waitForDownloads failed: %v", err)
+	}
+
+	want := map[string]waitOutcome{
+		"a": {ID: "a", Path: "/tmp/alpha.iso"}, // Already complete
+		"c": {ID: "c", Path: "/tmp/gamma.zip"}, // Path looked up after the event
+		"d": {ID: "d", Err: "server went away"},
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("Outcome of %s = %+v, want %+v", id, got[id], w)
+		}
+	}
+}
+
+func TestWaitForDownloads_UnknownAndTimeout(t *testing.T) {
+	setupIsolatedCmdState(t)
+	seedWaitDownloads(t)
+	svc := core.NewLocalDownloadService(nil)
+	defer func() { _ = svc.Shutdown() }()
+
+	var got []waitOutcome
+	report := func(o waitOutcome) { got = append(got, o) }
+
+	if err := waitForDownloads(context.Background(), svc, []string{"missing"}, false, report); err != nil {
+		t.Fatalf("waitForDownloads failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Err != "download not found" {
+		t.Fatalf("Expected a not found failure, got %+v", got)
+	}
+
+	// An empty ID never shows up, so it fails at once
+	got = nil
+	if err := waitForDownloads(context.Background(), svc, []string{""}, true, report); err != nil {
+		t.Fatalf("waitForDownloads failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Err != "no download ID" {
+		t.Fatalf("Expected an empty ID to fail, got %+v", got)
+	}
+
+	// Unknown IDs may not be listed yet when allowed; paused ones wait too
+	got = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := waitForDownloads(ctx, svc, []string{"missing", "c"}, true, report)
+	if !errors.Is(err, context.DeadlineExceeded) || len(got) != 0 {
+		t.Fatalf("Expected a timeout with nothing reported, got %v and %+v", err, got)
+	}
+}
+
+func mustList(t *testing.T, svc core.DownloadService) []types.DownloadStatus {
+	statuses, err := svc.List()
+	if err != nil {
+		t.Errorf("List failed: %v", err)
+	}
+	return statuses
+}
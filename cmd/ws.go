@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// wsPingInterval keeps idle control connections alive through proxies
+const wsPingInterval = 30 * time.Second
+
+// wsUpgrader accepts any origin: like the rest of the API, /ws is protected by
+// the auth token rather than by origin
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsRequest is a command sent by a /ws client. ID is echoed in the response.
+type wsRequest struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// wsMessage is sent to /ws clients: a command response, a download event, or
+// the initial hello carrying the current event sequence number
+type wsMessage struct {
+	Type   string      `json:"type"` // "hello", "event" or "response"
+	ID     string      `json:"id,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Event  string      `json:"event,omitempty"`
+	Seq    int64       `json:"seq,omitempty"` // Event ID, as in SSE; progress events have none
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// wsConn serialises writes to a websocket connection
+type wsConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *wsConn) send(msg wsMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+}
+
+// handleWebSocket serves /ws: the /events stream (with the same replay rules,
+// resuming from the last_event_id query parameter) plus commands on one connection
+func handleWebSocket(w http.ResponseWriter, r *http.Request, elog *eventLog, defaultOutputDir string, service core.DownloadService, pool *download.WorkerPool) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		utils.Debug("WebSocket upgrade failed: %v", err)
+		return
+	}
+	c := &wsConn{conn: conn}
+	client := clientFrom(r.Context())
+	defer func() { _ = conn.Close() }()
+
+	lastSeen, resume := lastEventID(r)
+	stream, missed, current, ok := elog.subscribe(lastSeen, resume)
+	defer elog.unsubscribe(stream)
+
+	switch {
+	case !resume:
+		err = c.send(wsMessage{Type: "hello", Seq: current})
+	case !ok:
+		statuses, listErr := service.List()
+		if err = listErr; err == nil {
+			err = c.send(wsMessage{Type: "event", Event: "snapshot", Seq: current, Data: statuses})
+		}
+	default:
+		for _, e := range missed {
+			if err = c.send(eventMessage(e)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		utils.Debug("WebSocket: failed to start stream: %v", err)
+		return
+	}
+
+	// Events and keepalive pings are written from their own goroutine
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.ping(); err != nil {
+					return
+				}
+			case e, ok := <-stream:
+				if !ok {
+					// Dropped for falling behind: close so the client resumes with last_event_id
+					_ = conn.Close()
+					return
+				}
+				if err := c.send(eventMessage(e)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var req wsRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				utils.Debug("WebSocket read: %v", err)
+			}
+			return
+		}
+
+		resp := wsMessage{Type: "response", ID: req.ID}
+		result, err := handleWSCommand(req, defaultOutputDir, service, pool, client)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Result = result
+		}
+		if err := c.send(resp); err != nil {
+			return
+		}
+	}
+}
+
+// eventMessage wraps a logged event for /ws clients
+func eventMessage(e sseEvent) wsMessage {
+	return wsMessage{Type: "event", Event: e.Name, Seq: e.ID, Data: json.RawMessage(e.Data)}
+}
+
+// wsCommandScope returns the token scope a /ws command needs
+func wsCommandScope(method string) string {
+	switch method {
+	case "add":
+		return scopeAdd
+	case "list":
+		return scopeRead
+	default:
+		return scopeFull
+	}
+}
+
+// handleWSCommand runs one /ws command for client and returns its result
+func handleWSCommand(req wsRequest, defaultOutputDir string, service core.DownloadService, pool *download.WorkerPool, client *apiClient) (interface{}, error) {
+	if !client.allows(wsCommandScope(req.Method)) {
+		return nil, errors.New("token scope does not allow this command")
+	}
+
+	var params struct {
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+		Max      int    `json:"max_concurrent_downloads"`
+	}
+	decode := func(v interface{}) error {
+		if len(req.Params) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(req.Params, v); err != nil {
+			return fmt.Errorf("invalid params: %w", err)
+		}
+		return nil
+	}
+
+	switch req.Method {
+	case "add":
+		var dl DownloadRequest
+		if err := decode(&dl); err != nil {
+			return nil, err
+		}
+		status, resp, err := queueDownload(dl, defaultOutputDir, service, client)
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusConflict {
+			return nil, errors.New(resp["message"])
+		}
+		return resp, nil
+
+	case "list":
+		return service.List()
+
+	case "pause", "resume", "delete":
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if params.ID == "" {
+			return nil, errors.New("missing id")
+		}
+		var err error
+		switch req.Method {
+		case "pause":
+			err = auditedChange(service, client, auditPause, params.ID, service.Pause)
+		case "resume":
+			err = auditedChange(service, client, auditResume, params.ID, service.Resume)
+		case "delete":
+			err = auditedChange(service, client, auditDelete, params.ID, service.Delete)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"id": params.ID}, nil
+
+	case "reorder":
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if pool == nil {
+			return nil, errors.New("queue is not available")
+		}
+		if !pool.Reorder(params.ID, params.Position) {
+			return nil, fmt.Errorf("download %s is not waiting in the queue", params.ID)
+		}
+		return map[string]interface{}{"queue": pool.PendingIDs()}, nil
+
+	case "set_limit":
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if pool == nil {
+			return nil, errors.New("queue is not available")
+		}
+		if err := config.CheckRange("max_concurrent_downloads", float64(params.Max)); err != nil {
+			return nil, err
+		}
+		pool.SetMaxDownloads(params.Max)
+		return map[string]int{"max_concurrent_downloads": pool.MaxDownloads()}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown method %q", req.Method)
+	}
+}
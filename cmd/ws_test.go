@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+)
+
+func dialTestWS(t *testing.T, l *eventLog, pool *download.WorkerPool, query string) *websocket.Conn {
+	t.Helper()
+	svc := core.NewLocalDownloadService(pool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(w, r, l, "", svc, pool)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+query, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestWebSocket_EventsAndCommands(t *testing.T) {
+	setupIsolatedCmdState(t)
+	l := newEventLog(eventLogSize, false)
+	publishLifecycle(l, 2)
+	pool := download.NewWorkerPool(nil, 2)
+	conn := dialTestWS(t, l, pool, "")
+
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "hello" || msg.Seq != 2 {
+		t.Fatalf("Expected hello at seq 2, got %+v (%v)", msg, err)
+	}
+
+	// Live events arrive as they are published
+	publishLifecycle(l, 1)
+	msg = wsMessage{}
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "event" || msg.Event != "queued" || msg.Seq != 3 {
+		t.Fatalf("Expected queued event 3, got %+v (%v)", msg, err)
+	}
+
+	tests := []struct {
+		request   string
+		wantError string
+		wantIn    string
+	}{
+		{`{"id":"1","method":"set_limit","params":{"max_concurrent_downloads":5}}`, "", `"max_concurrent_downloads":5`},
+		{`{"id":"2","method":"set_limit","params":{"max_concurrent_downloads":0}}`, "between 1 and 10", ""},
+		{`{"id":"3","method":"set_limit","params":{"max_concurrent_downloads":11}}`, "between 1 and 10", ""},
+		{`{"id":"4","method":"reorder","params":{"id":"missing","position":0}}`, "not waiting", ""},
+		{`{"id":"5","method":"pause","params":{}}`, "missing id", ""},
+		{`{"id":"6","method":"frobnicate"}`, "unknown method", ""},
+		{`{"id":"7","method":"add","params":{"url":""}}`, "URL is required", ""},
+	}
+	for _, tt := range tests {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		var raw map[string]interface{}
+		if err := conn.ReadJSON(&raw); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if raw["type"] != "response" {
+			t.Fatalf("Expected response to %s, got %v", tt.request, raw)
+		}
+		errMsg, _ := raw["error"].(string)
+		if tt.wantError != "" && !strings.Contains(errMsg, tt.wantError) {
+			t.Errorf("%s: error = %q, want %q", tt.request, errMsg, tt.wantError)
+		}
+		if tt.wantError == "" && errMsg != "" {
+			t.Errorf("%s: unexpected error %q", tt.request, errMsg)
+		}
+	}
+
+	if pool.MaxDownloads() != 5 {
+		t.Errorf("Expected limit 5 after set_limit, got %d", pool.MaxDownloads())
+	}
+}
+
+func TestWebSocket_ResumeReplaysMissedEvents(t *testing.T) {
+	setupIsolatedCmdState(t)
+	l := newEventLog(eventLogSize, false)
+	publishLifecycle(l, 3)
+	conn := dialTestWS(t, l, nil, "?last_event_id=1")
+
+	for _, want := range []int64{2, 3} {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != "event" || msg.Seq != want {
+			t.Fatalf("Expected replayed event %d, got %+v (%v)", want, msg, err)
+		}
+	}
+}
+
+func TestAuthMiddleware_WebSocketQueryToken(t *testing.T) {
+	handler := authMiddleware("secret-token", newAuthThrottle(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for path, want := range map[string]int{
+		"/ws?token=secret-token":   http.StatusOK,
+		"/ws?token=wrong":          http.StatusUnauthorized,
+		"/list?token=secret-token": http.StatusUnauthorized,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d", path, rec.Code, want)
+		}
+	}
+}
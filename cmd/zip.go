@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/remotezip"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var zipCmd = &cobra.Command{
+	Use:   "zip",
+	Short: "Browse and extract remote ZIP archives without downloading them",
+	Long: `Read the central directory of a remote ZIP archive with range requests,
+then download and inflate only the members you need.`,
+}
+
+var zipLsCmd = &cobra.Command{
+	Use:   "ls <url>",
+	Short: "List the entries of a remote ZIP archive",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		archive, err := openRemoteZip(cmd, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SIZE\tCOMPRESSED\tMODIFIED\tNAME")
+		_, _ = fmt.Fprintln(w, "----\t----------\t--------\t----")
+		var total int64
+		for _, m := range archive.Members {
+			size := "-"
+			compressed := "-"
+			if !m.IsDir {
+				size = utils.ConvertBytesToHumanReadable(m.UncompressedSize)
+				compressed = utils.ConvertBytesToHumanReadable(m.CompressedSize)
+				total += m.UncompressedSize
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", size, compressed, m.Modified.Format("2006-01-02 15:04"), m.Name)
+		}
+		_ = w.Flush()
+		fmt.Printf("\n%d entries, %s uncompressed, archive %s\n",
+			len(archive.Members), utils.ConvertBytesToHumanReadable(total), utils.ConvertBytesToHumanReadable(archive.Size))
+	},
+}
+
+var zipGetCmd = &cobra.Command{
+	Use:   "get <url> <member>...",
+	Short: "Download and extract selected members of a remote ZIP archive",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		output, _ := cmd.Flags().GetString("output")
+		keepPaths, _ := cmd.Flags().GetBool("paths")
+		if output == "" {
+			output = "."
+		}
+
+		archive, err := openRemoteZip(cmd, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		failed := 0
+		for _, name := range args[1:] {
+			member, err := archive.Lookup(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed++
+				continue
+			}
+
+			destPath, err := zipMemberPath(output, member.Name, keepPaths)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed++
+				continue
+			}
+
+			if err := archive.Extract(context.Background(), member, destPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error extracting %s: %v\n", member.Name, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Extracted %s -> %s (%s)\n", member.Name, destPath, utils.ConvertBytesToHumanReadable(member.UncompressedSize))
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// openRemoteZip reads the archive directory using the command's header flags,
+// mirror list (comma-separated URL argument) and engine settings
+func openRemoteZip(cmd *cobra.Command, arg string) (*remotezip.Archive, error) {
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+	headers, err := parseHeaderFlags(headerFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	url, mirrors := ParseURLArg(arg)
+	if url == "" {
+		return nil, fmt.Errorf("no URL given")
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = config.DefaultSettings()
+	}
+
+	return remotezip.Open(context.Background(), url, remotezip.Options{
+		Headers: headers,
+		Mirrors: mirrors,
+		Runtime: types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+	})
+}
+
+// zipMemberPath returns where a member is written: its base name in outputDir,
+// or its full archive path when keepPaths is set (rejecting paths that escape outputDir)
+func zipMemberPath(outputDir, name string, keepPaths bool) (string, error) {
+	if !keepPaths {
+		return filepath.Join(outputDir, filepath.Base(name)), nil
+	}
+
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to extract %s outside the output directory", name)
+	}
+	return filepath.Join(outputDir, clean), nil
+}
+
+func init() {
+	rootCmd.AddCommand(zipCmd)
+	zipCmd.AddCommand(zipLsCmd)
+	zipCmd.AddCommand(zipGetCmd)
+
+	zipCmd.PersistentFlags().StringArrayP("header", "H", nil, "Extra HTTP header as \"Key: Value\" (repeatable)")
+	zipGetCmd.Flags().StringP("output", "o", "", "Output directory (default: current directory)")
+	zipGetCmd.Flags().Bool("paths", false, "Keep the member's directory structure inside the output directory")
+}
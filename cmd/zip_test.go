@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestZipMemberPath(t *testing.T) {
+	out := filepath.Join("out")
+	tests := []struct {
+		name      string
+		member    string
+		keepPaths bool
+		want      string
+		wantErr   bool
+	}{
+		{"flattened", "data/2024/file.csv", false, filepath.Join(out, "file.csv"), false},
+		{"flattened traversal", "../../etc/passwd", false, filepath.Join(out, "passwd"), false},
+		{"kept paths", "data/2024/file.csv", true, filepath.Join(out, "data", "2024", "file.csv"), false},
+		{"kept traversal", "../evil.sh", true, "", true},
+		{"kept nested traversal", "a/../../evil.sh", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := zipMemberPath(out, tt.member, tt.keepPaths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("zipMemberPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeaderFlags(t *testing.T) {
+	headers, err := parseHeaderFlags([]string{"Authorization: Bearer abc", "Cookie:session=1"})
+	if err != nil {
+		t.Fatalf("parseHeaderFlags failed: %v", err)
+	}
+	if headers["Authorization"] != "Bearer abc" || headers["Cookie"] != "session=1" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+
+	if _, err := parseHeaderFlags([]string{"no-colon"}); err == nil {
+		t.Error("Expected error for header without colon")
+	}
+	if h, err := parseHeaderFlags(nil); err != nil || h != nil {
+		t.Errorf("Expected nil map for no flags, got %v, %v", h, err)
+	}
+}
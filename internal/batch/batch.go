@@ -0,0 +1,239 @@
+// Package batch reads batch files of downloads.
+//
+// Two formats are accepted, and may be mixed. Surge's own has one download
+// per line, with mirrors separated by commas. aria2's input-file format
+// separates a download's URIs with tabs and follows them with indented
+// "name=value" option lines:
+//
+//	https://example.com/a.bin	https://mirror.example.org/a.bin
+//	  dir=/data/set1
+//	  out=a-renamed.bin
+//	  header=Authorization: Bearer abc
+//	  checksum=sha-256=<hex digest>
+//	  max-connection-per-server=4
+//
+// Blank lines and lines starting with '#' are skipped. URIs may contain
+// range and list patterns, see Expand.
+package batch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Entry is one download of a batch file
+type Entry struct {
+	URL      string
+	Mirrors  []string // All URIs, including URL
+	Dir      string   // Output directory; relative paths are resolved by OutputDir
+	Filename string   // Output filename; empty to detect it from the server
+	Headers  map[string]string
+	Options  types.DownloadOptions // Checksum, MaxConnections and IfModified
+	Line     int                   // Line of the URIs in the file
+	Literal  bool                  // Brackets and braces in the URIs are not patterns, as with curl --globoff
+}
+
+// OutputDir returns the directory to save the entry in: its own dir if
+// absolute, joined to base if relative, else base
+func (e Entry) OutputDir(base string) string {
+	if e.Dir == "" {
+		return base
+	}
+	if filepath.IsAbs(e.Dir) {
+		return e.Dir
+	}
+	return filepath.Join(base, e.Dir)
+}
+
+// ignoredOptions are aria2 options Surge accepts but decides itself
+var ignoredOptions = map[string]bool{
+	"split":              true,
+	"min-split-size":     true,
+	"continue":           true,
+	"allow-overwrite":    true,
+	"auto-file-renaming": true,
+}
+
+// optionLine matches an indented aria2 option such as "  out=file.bin"
+var optionLine = regexp.MustCompile(`^\s+([a-z][a-z0-9-]*)=(.*)$`)
+
+// ReadFile parses the batch file at path
+func ReadFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	return Parse(file)
+}
+
+// Parse reads batch entries from r
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := optionLine.FindStringSubmatch(raw); m != nil {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: option %q comes before any URI", lineNo, m[1])
+			}
+			if err := current.setOption(m[1], strings.TrimSpace(m[2])); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		}
+
+		entry := ParseURIs(line)
+		if entry.URL == "" {
+			continue
+		}
+		entry.Line = lineNo
+		entries = append(entries, entry)
+		current = &entries[len(entries)-1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var expanded []Entry
+	for _, e := range entries {
+		finished, err := e.Finish()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", e.Line, err)
+		}
+		expanded = append(expanded, finished...)
+	}
+	return expanded, nil
+}
+
+// Finish expands the entry's patterns and checks its output names. A
+// Filename holding a path inside Dir is split into the two, as aria2 allows.
+func (e Entry) Finish() ([]Entry, error) {
+	entries, err := e.Expand()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Filename == "" {
+			continue
+		}
+		clean := filepath.Clean(filepath.FromSlash(entries[i].Filename))
+		if filepath.IsAbs(clean) || strings.HasPrefix(clean, string(filepath.Separator)) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("invalid output name %q: must be a file name or a path inside dir", entries[i].Filename)
+		}
+		if sub := filepath.Dir(clean); sub != "." {
+			entries[i].Dir = filepath.Join(entries[i].Dir, sub)
+		}
+		entries[i].Filename = filepath.Base(clean)
+	}
+	return entries, nil
+}
+
+// ParseURIs builds an entry from a line of URIs separated by tabs or commas.
+// The first is the URL and all of them are mirrors, as with ParseURLArg.
+// Commas inside a "{a,b}" list don't separate URIs.
+func ParseURIs(line string) Entry {
+	var uris []string
+	add := func(field string) {
+		if uri := strings.TrimSpace(field); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '{':
+			if end := strings.IndexByte(line[i:], '}'); end > 0 {
+				i += end
+			}
+		case '\t', ',':
+			add(line[start:i])
+			start = i + 1
+		}
+	}
+	add(line[start:])
+
+	if len(uris) == 0 {
+		return Entry{}
+	}
+	return Entry{URL: uris[0], Mirrors: uris}
+}
+
+// setOption applies one aria2 option to the entry
+func (e *Entry) setOption(name, value string) error {
+	switch name {
+	case "dir":
+		e.Dir = value
+	case "out":
+		// Checked and split into dir and file name by Finish
+		if value == "" {
+			return fmt.Errorf("invalid out: empty file name")
+		}
+		e.Filename = value
+	case "header":
+		key, val, ok := strings.Cut(value, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("invalid header %q: expected \"Key: Value\"", value)
+		}
+		e.setHeader(key, strings.TrimSpace(val))
+	case "referer":
+		e.setHeader("Referer", value)
+	case "user-agent":
+		e.setHeader("User-Agent", value)
+	case "checksum":
+		if _, _, err := utils.ParseChecksum(value); err != nil {
+			return err
+		}
+		e.Options.Checksum = value
+	case "max-connection-per-server":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid max-connection-per-server %q: must be a positive number", value)
+		}
+		e.Options.MaxConnections = n
+	case "conditional-get":
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid conditional-get %q: must be true or false", value)
+		}
+		e.Options.IfModified = on
+	case "globoff":
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid globoff %q: must be true or false", value)
+		}
+		e.Literal = on
+	default:
+		if !ignoredOptions[name] {
+			utils.Debug("Batch file: ignoring unsupported option %s", name)
+		}
+	}
+	return nil
+}
+
+func (e *Entry) setHeader(key, value string) {
+	if e.Headers == nil {
+		e.Headers = make(map[string]string)
+	}
+	e.Headers[key] = value
+}
@@ -0,0 +1,109 @@
+package batch
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_Aria2Options(t *testing.T) {
+	input := strings.Join([]string{
+		"# dataset shard list",
+		"https://example.com/a.bin\thttps://mirror.example.org/a.bin",
+		"  dir=/data/set1",
+		"  out=a-renamed.bin",
+		"  header=Authorization: Bearer abc",
+		"  header=X-Shard: 1",
+		"  checksum=sha-256=2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"  max-connection-per-server=4",
+		"  split=16",
+		"",
+		"https://example.com/b.bin,https://mirror.example.org/b.bin",
+		"https://example.com/c.bin",
+		"\tout=parts/c.bin",
+		"\tdir=rel",
+		"\treferer=https://example.com/",
+		"\tconditional-get=true",
+	}, "\n")
+
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+
+	a := entries[0]
+	if a.URL != "https://example.com/a.bin" || len(a.Mirrors) != 2 || a.Line != 2 {
+		t.Errorf("Unexpected URIs for a: %+v", a)
+	}
+	if a.Dir != "/data/set1" || a.Filename != "a-renamed.bin" {
+		t.Errorf("Unexpected dir/out for a: %q %q", a.Dir, a.Filename)
+	}
+	wantHeaders := map[string]string{"Authorization": "Bearer abc", "X-Shard": "1"}
+	if !reflect.DeepEqual(a.Headers, wantHeaders) {
+		t.Errorf("Headers = %v, want %v", a.Headers, wantHeaders)
+	}
+	if !strings.HasPrefix(a.Options.Checksum, "sha-256=") || a.Options.MaxConnections != 4 {
+		t.Errorf("Unexpected options for a: %+v", a.Options)
+	}
+
+	b := entries[1]
+	if b.URL != "https://example.com/b.bin" || len(b.Mirrors) != 2 || b.Headers != nil || b.Filename != "" {
+		t.Errorf("Unexpected plain entry b: %+v", b)
+	}
+
+	// out's subdirectory lands under dir even when dir comes later
+	c := entries[2]
+	if c.Dir != filepath.Join("rel", "parts") || c.Filename != "c.bin" {
+		t.Errorf("Unexpected dir/out for c: %q %q", c.Dir, c.Filename)
+	}
+	if c.Headers["Referer"] != "https://example.com/" {
+		t.Errorf("Referer not set: %v", c.Headers)
+	}
+	if !c.Options.IfModified || a.Options.IfModified {
+		t.Errorf("conditional-get should apply to c only: %+v %+v", a.Options, c.Options)
+	}
+	if got := c.OutputDir("/base"); got != filepath.Join("/base", "rel", "parts") {
+		t.Errorf("OutputDir = %q", got)
+	}
+	if got := a.OutputDir("/base"); got != "/data/set1" {
+		t.Errorf("OutputDir of absolute dir = %q", got)
+	}
+}
+
+func TestParse_IndentedURLIsNotAnOption(t *testing.T) {
+	entries, err := Parse(strings.NewReader("  https://example.com/a.zip  \nhttps://example.com/b.zip\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].URL != "https://example.com/a.zip" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"option before URI", "  out=a.bin\nhttps://example.com/a\n", "line 1"},
+		{"bad checksum", "https://example.com/a\n  checksum=sha-256=abc\n", "line 2"},
+		{"bad connections", "https://example.com/a\n  max-connection-per-server=0\n", "max-connection-per-server"},
+		{"escaping out", "https://example.com/a\n  out=../a.bin\n", "invalid output name"},
+		{"absolute out", "https://example.com/a\n  out=/etc/a.bin\n", "invalid output name"},
+		{"bad header", "https://example.com/a\n  header=NoColon\n", "invalid header"},
+		{"bad conditional-get", "https://example.com/a\n  conditional-get=maybe\n", "conditional-get"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
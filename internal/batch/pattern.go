@@ -0,0 +1,243 @@
+package batch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxExpansions caps how many URLs a single pattern may generate
+const maxExpansions = 10000
+
+// rangePattern matches the inside of a "[...]" range: numeric with optional
+// zero padding, or a single letter range, with an optional ":step". Anything
+// else, such as an IPv6 host, is kept literally.
+var rangePattern = regexp.MustCompile(`^(?:(\d+)-(\d+)|([a-z])-([a-z])|([A-Z])-([A-Z]))(?::(\d+))?$`)
+
+// Expansion is one URL generated from a pattern
+type Expansion struct {
+	URL    string
+	Values []string // Values matched by each range or list, in order
+}
+
+// segment is a literal part of a pattern, or the values of a range or list
+type segment struct {
+	literal string
+	values  []string
+}
+
+// HasPattern reports whether s contains a range or list pattern
+func HasPattern(s string) bool {
+	segments, err := parsePattern(s)
+	if err != nil {
+		return true // Malformed patterns are still patterns, and fail in Expand
+	}
+	for _, seg := range segments {
+		if seg.values != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand generates every URL matched by pattern. "[001-120]" counts with
+// zero padding, "[a-z]" runs through letters, ":step" skips values, and
+// "{a,b,c}" lists alternatives. The leftmost pattern varies slowest.
+func Expand(pattern string) ([]Expansion, error) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 1
+	for _, seg := range segments {
+		if seg.values == nil {
+			continue
+		}
+		total *= len(seg.values)
+		if total > maxExpansions {
+			return nil, fmt.Errorf("pattern %q expands to more than %d URLs", pattern, maxExpansions)
+		}
+	}
+
+	expansions := []Expansion{{}}
+	for _, seg := range segments {
+		if seg.values == nil {
+			for i := range expansions {
+				expansions[i].URL += seg.literal
+			}
+			continue
+		}
+		next := make([]Expansion, 0, len(expansions)*len(seg.values))
+		for _, exp := range expansions {
+			for _, v := range seg.values {
+				values := append(append([]string(nil), exp.Values...), v)
+				next = append(next, Expansion{URL: exp.URL + v, Values: values})
+			}
+		}
+		expansions = next
+	}
+	return expansions, nil
+}
+
+// ApplyTemplate replaces #1, #2... in tmpl with the matching values. A
+// reference past the last value is kept literally.
+func ApplyTemplate(tmpl string, values []string) string {
+	if len(values) == 0 || !strings.Contains(tmpl, "#") {
+		return tmpl
+	}
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '#' {
+			b.WriteByte(tmpl[i])
+			continue
+		}
+		j := i + 1
+		for j < len(tmpl) && tmpl[j] >= '0' && tmpl[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(tmpl[i+1 : j])
+		if err != nil || n < 1 || n > len(values) {
+			b.WriteByte('#')
+			continue
+		}
+		b.WriteString(values[n-1])
+		i = j - 1
+	}
+	return b.String()
+}
+
+// Expand returns one entry per URL generated by the entry's patterns, with
+// #1, #2... in Dir and Filename replaced by the matched values. Every mirror
+// must expand to as many URLs as the first. A literal entry, or one without
+// patterns, is returned unchanged.
+func (e Entry) Expand() ([]Entry, error) {
+	if e.Literal || !HasPattern(e.URL) {
+		return []Entry{e}, nil
+	}
+
+	primary, err := Expand(e.URL)
+	if err != nil {
+		return nil, err
+	}
+	mirrors := make([][]Expansion, len(e.Mirrors))
+	for i, m := range e.Mirrors {
+		if mirrors[i], err = Expand(m); err != nil {
+			return nil, err
+		}
+		if len(mirrors[i]) != len(primary) {
+			return nil, fmt.Errorf("mirror %q expands to %d URLs, but %q to %d", m, len(mirrors[i]), e.URL, len(primary))
+		}
+	}
+
+	entries := make([]Entry, len(primary))
+	for i, exp := range primary {
+		entry := e
+		entry.URL = exp.URL
+		entry.Mirrors = nil
+		for _, m := range mirrors {
+			entry.Mirrors = append(entry.Mirrors, m[i].URL)
+		}
+		entry.Dir = ApplyTemplate(e.Dir, exp.Values)
+		entry.Filename = ApplyTemplate(e.Filename, exp.Values)
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// parsePattern splits s into literals, ranges and lists
+func parsePattern(s string) ([]segment, error) {
+	var segments []segment
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, segment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 || !strings.Contains(s[i:i+end], ",") {
+				break
+			}
+			flush()
+			segments = append(segments, segment{values: strings.Split(s[i+1:i+end], ",")})
+			i += end
+			continue
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				break
+			}
+			m := rangePattern.FindStringSubmatch(s[i+1 : i+end])
+			if m == nil {
+				break
+			}
+			values, err := rangeValues(m)
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %w", s[i:i+end+1], err)
+			}
+			flush()
+			segments = append(segments, segment{values: values})
+			i += end
+			continue
+		}
+		literal.WriteByte(s[i])
+	}
+	flush()
+	return segments, nil
+}
+
+// rangeValues lists the values of a range matched by rangePattern
+func rangeValues(m []string) ([]string, error) {
+	step := 1
+	if m[7] != "" {
+		n, err := strconv.Atoi(m[7])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("step must be a positive number")
+		}
+		step = n
+	}
+
+	var values []string
+	if m[1] != "" {
+		start, err1 := strconv.Atoi(m[1])
+		end, err2 := strconv.Atoi(m[2])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("number out of range")
+		}
+		if start > end {
+			return nil, fmt.Errorf("start is after end")
+		}
+		if (end-start)/step >= maxExpansions {
+			return nil, fmt.Errorf("more than %d values", maxExpansions)
+		}
+		// A leading zero pads every value to the width of the start, as curl does
+		width := 0
+		if len(m[1]) > 1 && m[1][0] == '0' {
+			width = len(m[1])
+		}
+		// Counting by index keeps start+k*step within end, so ranges near
+		// the largest int cannot overflow
+		for k := 0; k <= (end-start)/step; k++ {
+			values = append(values, fmt.Sprintf("%0*d", width, start+k*step))
+		}
+		return values, nil
+	}
+
+	from, to := m[3], m[4]
+	if from == "" {
+		from, to = m[5], m[6]
+	}
+	if from[0] > to[0] {
+		return nil, fmt.Errorf("start is after end")
+	}
+	for k := 0; k <= int(to[0]-from[0])/step; k++ {
+		values = append(values, string(rune(int(from[0])+k*step)))
+	}
+	return values, nil
+}
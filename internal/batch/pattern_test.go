@@ -0,0 +1,123 @@
+package batch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func expandURLs(t *testing.T, pattern string) []string {
+	t.Helper()
+	expansions, err := Expand(pattern)
+	if err != nil {
+		t.Fatalf("Expand(%q) failed: %v", pattern, err)
+	}
+	var urls []string
+	for _, exp := range expansions {
+		urls = append(urls, exp.URL)
+	}
+	return urls
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"https://h/part[008-011].bin", []string{"https://h/part008.bin", "https://h/part009.bin", "https://h/part010.bin", "https://h/part011.bin"}},
+		{"https://h/[0-10:5]", []string{"https://h/0", "https://h/5", "https://h/10"}},
+		{"https://h/[a-c]", []string{"https://h/a", "https://h/b", "https://h/c"}},
+		{"https://h/[A-E:2]", []string{"https://h/A", "https://h/C", "https://h/E"}},
+		{"https://h/img-{a,b,c}.png", []string{"https://h/img-a.png", "https://h/img-b.png", "https://h/img-c.png"}},
+		{"https://h/{x,y}/[1-2]", []string{"https://h/x/1", "https://h/x/2", "https://h/y/1", "https://h/y/2"}},
+		// Not patterns: an IPv6 host and braces without a comma
+		{"http://[::1]:8080/{file}.bin", []string{"http://[::1]:8080/{file}.bin"}},
+		// Ranges ending near the largest int, where stepping past the end overflows
+		{"https://h/[9223372036854775806-9223372036854775807:5]", []string{"https://h/9223372036854775806"}},
+		{"https://h/[9223372036854775805-9223372036854775807]", []string{"https://h/9223372036854775805", "https://h/9223372036854775806", "https://h/9223372036854775807"}},
+		{"https://h/[y-z:9223372036854775807]", []string{"https://h/y"}},
+	}
+	for _, tt := range tests {
+		if got := expandURLs(t, tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Expand(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+
+	expansions, _ := Expand("https://h/{x,y}/[1-2]")
+	if want := []string{"y", "1"}; !reflect.DeepEqual(expansions[2].Values, want) {
+		t.Errorf("Values = %v, want %v", expansions[2].Values, want)
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	for _, pattern := range []string{
+		"https://h/[9-1]",
+		"https://h/[z-a]",
+		"https://h/[1-5:0]",
+		"https://h/[0-99999]",
+		"https://h/[0-999]/[0-99]",
+	} {
+		if _, err := Expand(pattern); err == nil {
+			t.Errorf("Expand(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	tests := map[string]string{
+		"part-#1.bin":  "part-a.bin",
+		"#2/#1-#10":    "b/a-j",
+		"#11 and #x #": "#11 and #x #",
+		"plain.bin":    "plain.bin",
+	}
+	for tmpl, want := range tests {
+		if got := ApplyTemplate(tmpl, values); got != want {
+			t.Errorf("ApplyTemplate(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
+
+func TestEntryExpand_Mirrors(t *testing.T) {
+	e := ParseURIs("https://a/p[1-2].bin,https://b/p[1-2].bin")
+	e.Filename = "shard-#1.bin"
+	entries, err := e.Expand()
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[1].URL != "https://a/p2.bin" || !reflect.DeepEqual(entries[1].Mirrors, []string{"https://a/p2.bin", "https://b/p2.bin"}) || entries[1].Filename != "shard-2.bin" {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+
+	e = ParseURIs("https://a/p[1-2].bin,https://b/p[1-3].bin")
+	if _, err := e.Expand(); err == nil || !strings.Contains(err.Error(), "expands to 3") {
+		t.Errorf("Expected a mirror count error, got %v", err)
+	}
+}
+
+func TestParse_Patterns(t *testing.T) {
+	input := "https://h/img-{a,b}.png\thttps://m/img-{a,b}.png\n  out=#1/image.png\n"
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[1].URL != "https://h/img-b.png" || len(entries[1].Mirrors) != 2 || entries[1].Dir != "b" || entries[1].Filename != "image.png" {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+
+	if _, err := Parse(strings.NewReader("https://h/{..,x}.png\n  out=#1/a.png\n")); err == nil {
+		t.Error("Expected an error for a template escaping dir")
+	}
+
+	// globoff takes the URL literally
+	entries, err = Parse(strings.NewReader("https://h/img-{a,b}[1-2].png\n  globoff=true\n"))
+	if err != nil || len(entries) != 1 || entries[0].URL != "https://h/img-{a,b}[1-2].png" {
+		t.Errorf("Expected one literal entry, got %+v (%v)", entries, err)
+	}
+}
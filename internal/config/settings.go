@@ -25,6 +25,12 @@ type GeneralSettings struct {
 	ClipboardMonitor  bool `json:"clipboard_monitor"`
 	Theme             int  `json:"theme"`
 	LogRetentionCount int  `json:"log_retention_count"`
+
+	MinFreeDiskSpace int64 `json:"min_free_disk_space"`
+	PersistEvents    bool  `json:"persist_events"`
+
+	// AllowedDownloadDirs limits where API clients may save downloads. Empty allows any directory.
+	AllowedDownloadDirs []string `json:"allowed_download_dirs,omitempty"`
 }
 
 const (
@@ -88,7 +94,7 @@ type SettingMeta struct {
 	Key         string // JSON key name
 	Label       string // Human-readable label
 	Description string // Help text displayed in right pane
-	Type        string // "string", "int", "int64", "bool", "duration", "float64"
+	Type        string // "string", "int", "int64", "bool", "duration", "float64", "list"
 }
 
 // GetSettingsMetadata returns metadata for all settings organized by category.
@@ -104,15 +110,18 @@ func GetSettingsMetadata() map[string][]SettingMeta {
 			{Key: "clipboard_monitor", Label: "Clipboard Monitor", Description: "Watch clipboard for URLs and prompt to download them.", Type: "bool"},
 			{Key: "theme", Label: "App Theme", Description: "UI Theme (System, Light, Dark).", Type: "int"},
 			{Key: "log_retention_count", Label: "Log Retention Count", Description: "Number of recent log files to keep.", Type: "int"},
+			{Key: "min_free_disk_space", Label: "Min Free Disk Space", Description: "Pause downloads when free space on their drive drops below this size (e.g. 512MB; a bare number is in MB). 0 disables.", Type: "int64"},
+			{Key: "persist_events", Label: "Persist Events", Description: "Keep the server's event log in the state database, so event IDs continue across restarts. Requires restart.", Type: "bool"},
+			{Key: "allowed_download_dirs", Label: "Allowed Download Dirs", Description: "Directories API clients may save downloads under, separated by '" + string(filepath.ListSeparator) + "'. Leave empty to allow any directory.", Type: "list"},
 		},
 		"Network": {
 			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host (1-64).", Type: "int"},
-			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Requires restart.", Type: "int"},
+			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10).", Type: "int"},
 			{Key: "user_agent", Label: "User Agent", Description: "Custom User-Agent string for HTTP requests. Leave empty for default.", Type: "string"},
 			{Key: "proxy_url", Label: "Proxy URL", Description: "HTTP/HTTPS proxy URL (e.g. http://127.0.0.1:1700). Leave empty to use system default.", Type: "string"},
 			{Key: "sequential_download", Label: "Sequential Download", Description: "Download pieces in order (Streaming Mode). May be slower.", Type: "bool"},
-			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size in MB (e.g., 2).", Type: "int64"},
-			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker in KB (e.g., 512).", Type: "int"},
+			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size (e.g. 2MB; a bare number is in MB).", Type: "int64"},
+			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker (e.g. 512KB; a bare number is in KB).", Type: "int"},
 		},
 		"Performance": {
 			{Key: "max_task_retries", Label: "Max Task Retries", Description: "Number of times to retry a failed chunk before giving up.", Type: "int"},
@@ -132,6 +141,8 @@ func CategoryOrder() []string {
 const (
 	KB = 1024
 	MB = 1024 * KB
+	GB = 1024 * MB
+	TB = 1024 * GB
 )
 
 // DefaultSettings returns a new Settings instance with sensible defaults.
@@ -165,6 +176,9 @@ func DefaultSettings() *Settings {
 			ClipboardMonitor:  true,
 			Theme:             ThemeAdaptive,
 			LogRetentionCount: 5,
+
+			MinFreeDiskSpace: 0, // Opt-in: existing setups keep downloading until the disk is full
+			PersistEvents:    true,
 		},
 		Network: NetworkSettings{
 			MaxConnectionsPerHost:  32,
@@ -247,6 +261,7 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	MinFreeDiskSpace      int64
 }
 
 // ToRuntimeConfig creates a RuntimeConfig from user Settings
@@ -263,5 +278,6 @@ func (s *Settings) ToRuntimeConfig() *RuntimeConfig {
 		SlowWorkerGracePeriod: s.Performance.SlowWorkerGracePeriod,
 		StallTimeout:          s.Performance.StallTimeout,
 		SpeedEmaAlpha:         s.Performance.SpeedEmaAlpha,
+		MinFreeDiskSpace:      s.General.MinFreeDiskSpace,
 	}
 }
@@ -34,6 +34,9 @@ func TestDefaultSettings(t *testing.T) {
 		if settings.General.AutoResume {
 			t.Error("AutoResume should be false by default")
 		}
+		if !settings.General.PersistEvents {
+			t.Error("PersistEvents should be true by default")
+		}
 	})
 
 	// Verify Connection settings
@@ -340,7 +343,7 @@ func TestGetSettingsMetadata(t *testing.T) {
 			// Verify Type is valid
 			validTypes := map[string]bool{
 				"string": true, "int": true, "int64": true,
-				"bool": true, "duration": true, "float64": true,
+				"bool": true, "duration": true, "float64": true, "list": true,
 			}
 			if !validTypes[setting.Type] {
 				t.Errorf("Category %s, key %s: Invalid type %q", category, setting.Key, setting.Type)
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settingLimits bounds numeric settings, inclusive. Numeric settings not
+// listed here only have to be non-negative.
+var settingLimits = map[string][2]float64{
+	"theme":                    {ThemeAdaptive, ThemeDark},
+	"max_connections_per_host": {1, 64},
+	"max_concurrent_downloads": {1, 10},
+	"slow_worker_threshold":    {0, 1},
+	"speed_ema_alpha":          {0, 1},
+}
+
+// settingUnits gives the unit of a bare number for size settings, the unit
+// the TUI edits them in. Values with a suffix (512KB, 1.5GB) are taken as is.
+var settingUnits = map[string]int64{
+	"min_free_disk_space": MB,
+	"min_chunk_size":      MB,
+	"worker_buffer_size":  KB,
+}
+
+// sizeUnits maps size suffixes to their multiplier. Units are binary, as in
+// the rest of Surge.
+var sizeUnits = map[string]int64{
+	"B": 1,
+	"K": KB, "KB": KB, "KIB": KB,
+	"M": MB, "MB": MB, "MIB": MB,
+	"G": GB, "GB": GB, "GIB": GB,
+	"T": TB, "TB": TB, "TIB": TB,
+}
+
+// LookupSetting returns the category and metadata of the setting with key
+func LookupSetting(key string) (string, SettingMeta, bool) {
+	metadata := GetSettingsMetadata()
+	for _, category := range CategoryOrder() {
+		for _, meta := range metadata[category] {
+			if meta.Key == key {
+				return category, meta, true
+			}
+		}
+	}
+	return "", SettingMeta{}, false
+}
+
+// Value returns the current value of the setting with key
+func (s *Settings) Value(key string) (interface{}, error) {
+	field, err := s.settingField(key)
+	if err != nil {
+		return nil, err
+	}
+	return field.Interface(), nil
+}
+
+// Set parses value according to the type of the setting with key, checks
+// it, and stores it. Durations may be given as bare seconds, sizes take a
+// unit suffix or are in the setting's own unit, and lists are separated by
+// the OS list separator.
+func (s *Settings) Set(key, value string) error {
+	field, err := s.settingField(key)
+	if err != nil {
+		return err
+	}
+	_, meta, _ := LookupSetting(key)
+
+	var number float64
+	var parsed interface{}
+	switch meta.Type {
+	case "string":
+		parsed = value
+	case "list":
+		var items []string
+		for _, item := range filepath.SplitList(value) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		parsed = items
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		parsed = b
+	case "int", "int64":
+		var n int64
+		var err error
+		if unit, ok := settingUnits[key]; ok {
+			if n, err = parseSize(value, unit); err != nil {
+				return fmt.Errorf("%s must be a size such as %s", key, formatSize(2*unit))
+			}
+		} else if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%s must be a whole number", key)
+		}
+		number = float64(n)
+		if meta.Type == "int" {
+			parsed = int(n)
+		} else {
+			parsed = n
+		}
+	case "float64":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		number, parsed = f, f
+	case "duration":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			value += "s"
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 5s or 1m30s", key)
+		}
+		number, parsed = d.Seconds(), d
+	default:
+		return fmt.Errorf("setting %s has unsupported type %q", key, meta.Type)
+	}
+
+	if err := CheckRange(key, number); err != nil {
+		return err
+	}
+
+	field.Set(reflect.ValueOf(parsed))
+	return nil
+}
+
+// CheckRange checks number against the bounds of the numeric setting with key,
+// for callers that change a setting's effect without going through Set
+func CheckRange(key string, number float64) error {
+	if limits, ok := settingLimits[key]; ok {
+		if number < limits[0] || number > limits[1] {
+			return fmt.Errorf("%s must be between %g and %g", key, limits[0], limits[1])
+		}
+	} else if number < 0 {
+		return fmt.Errorf("%s must not be negative", key)
+	}
+	return nil
+}
+
+// Reset restores the setting with key to its default
+func (s *Settings) Reset(key string) error {
+	field, err := s.settingField(key)
+	if err != nil {
+		return err
+	}
+	def, err := DefaultSettings().settingField(key)
+	if err != nil {
+		return err
+	}
+	field.Set(def)
+	return nil
+}
+
+// FormatValue renders the value of the setting with key the way Set accepts it
+func FormatValue(key string, value interface{}) string {
+	if _, ok := settingUnits[key]; ok {
+		if v := reflect.ValueOf(value); v.CanInt() {
+			return formatSize(v.Int())
+		}
+	}
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, string(filepath.ListSeparator))
+	case time.Duration:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// settingField finds the struct field holding the setting with key, by
+// its category and JSON tag
+func (s *Settings) settingField(key string) (reflect.Value, error) {
+	category, _, ok := LookupSetting(key)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown setting %q", key)
+	}
+	group := reflect.ValueOf(s).Elem().FieldByName(category)
+	for i := 0; i < group.NumField(); i++ {
+		tag, _, _ := strings.Cut(group.Type().Field(i).Tag.Get("json"), ",")
+		if tag == key {
+			return group.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("setting %q has no field in %s", key, category)
+}
+
+// parseSize parses a size such as 512KB or 1.5G into bytes. A bare number is
+// in unit.
+func parseSize(value string, unit int64) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, err
+	}
+	if suffix := strings.TrimSpace(s[i:]); suffix != "" {
+		var ok bool
+		if unit, ok = sizeUnits[suffix]; !ok {
+			return 0, fmt.Errorf("unknown unit %q", suffix)
+		}
+	}
+	bytes := n * float64(unit)
+	if bytes >= math.MaxInt64 {
+		return 0, errors.New("size too large")
+	}
+	return int64(bytes), nil
+}
+
+// formatSize renders bytes in the largest unit that divides it exactly
+func formatSize(bytes int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"TB", TB}, {"GB", GB}, {"MB", MB}, {"KB", KB}} {
+		if bytes != 0 && bytes%u.size == 0 {
+			return fmt.Sprintf("%d%s", bytes/u.size, u.suffix)
+		}
+	}
+	if bytes == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%dB", bytes)
+}
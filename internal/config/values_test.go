@@ -0,0 +1,110 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettingsValue_EveryKeyResolves(t *testing.T) {
+	s := DefaultSettings()
+	for _, category := range CategoryOrder() {
+		for _, meta := range GetSettingsMetadata()[category] {
+			v, err := s.Value(meta.Key)
+			if err != nil {
+				t.Errorf("Value(%s) failed: %v", meta.Key, err)
+				continue
+			}
+			// Formatting and parsing back must round-trip
+			if err := s.Set(meta.Key, FormatValue(meta.Key, v)); err != nil {
+				t.Errorf("Set(%s, %q) failed: %v", meta.Key, FormatValue(meta.Key, v), err)
+			} else if after, _ := s.Value(meta.Key); !reflect.DeepEqual(after, v) {
+				t.Errorf("Set(%s, %q) stored %v, want %v", meta.Key, FormatValue(meta.Key, v), after, v)
+			}
+		}
+	}
+}
+
+func TestSettingsSet(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       interface{}
+		wantErr    string
+	}{
+		{"stall_timeout", "1m30s", 90 * time.Second, ""},
+		{"stall_timeout", "10", 10 * time.Second, ""},
+		{"stall_timeout", "soon", nil, "duration"},
+		{"slow_worker_threshold", "0.25", 0.25, ""},
+		{"slow_worker_threshold", "1.5", nil, "between 0 and 1"},
+		{"min_free_disk_space", "1GB", int64(1 << 30), ""},
+		{"min_free_disk_space", "512", int64(512 << 20), ""},
+		{"min_free_disk_space", "-1", nil, "size such as 2MB"},
+		{"min_chunk_size", "2", int64(2 << 20), ""},
+		{"min_chunk_size", "2.5", int64(5 << 19), ""},
+		{"min_chunk_size", "256 kb", int64(256 << 10), ""},
+		{"min_chunk_size", "2XB", nil, "size such as 2MB"},
+		{"worker_buffer_size", "512", 512 << 10, ""},
+		{"worker_buffer_size", "1M", 1 << 20, ""},
+		{"max_task_retries", "2.5", nil, "whole number"},
+		{"max_concurrent_downloads", "11", nil, "between 1 and 10"},
+		{"sequential_download", "true", true, ""},
+		{"sequential_download", "maybe", nil, "true or false"},
+		{"user_agent", "surge-test", "surge-test", ""},
+		{"no_such_key", "1", nil, "unknown setting"},
+	}
+
+	for _, tt := range tests {
+		s := DefaultSettings()
+		err := s.Set(tt.key, tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Set(%s, %q) error = %v, want %q", tt.key, tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Set(%s, %q) failed: %v", tt.key, tt.value, err)
+			continue
+		}
+		if got, _ := s.Value(tt.key); got != tt.want {
+			t.Errorf("Set(%s, %q) stored %v, want %v", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSettingsReset(t *testing.T) {
+	s := DefaultSettings()
+	s.Network.MaxConnectionsPerHost = 3
+	s.General.AllowedDownloadDirs = []string{"/srv"}
+
+	for _, key := range []string{"max_connections_per_host", "allowed_download_dirs"} {
+		if err := s.Reset(key); err != nil {
+			t.Fatalf("Reset(%s) failed: %v", key, err)
+		}
+	}
+	def := DefaultSettings()
+	if s.Network.MaxConnectionsPerHost != def.Network.MaxConnectionsPerHost || len(s.General.AllowedDownloadDirs) != 0 {
+		t.Errorf("Reset left %d connections and dirs %v", s.Network.MaxConnectionsPerHost, s.General.AllowedDownloadDirs)
+	}
+}
+
+func TestFormatValue_Sizes(t *testing.T) {
+	tests := []struct {
+		key   string
+		value interface{}
+		want  string
+	}{
+		{"min_chunk_size", int64(2 * MB), "2MB"},
+		{"min_free_disk_space", int64(0), "0"},
+		{"min_free_disk_space", int64(3 * GB), "3GB"},
+		{"worker_buffer_size", 512 * KB, "512KB"},
+		{"min_chunk_size", int64(1500), "1500B"},
+		{"max_task_retries", 3, "3"},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.key, tt.value); got != tt.want {
+			t.Errorf("FormatValue(%s, %v) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
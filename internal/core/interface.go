@@ -19,6 +19,9 @@ type DownloadService interface {
 	// Add queues a new download.
 	Add(url string, path string, filename string, mirrors []string, headers map[string]string) (string, error)
 
+	// AddWithOptions queues a new download with optional per-download parameters.
+	AddWithOptions(url string, path string, filename string, mirrors []string, headers map[string]string, opts types.DownloadOptions) (string, error)
+
 	// Pause pauses an active download.
 	Pause(id string) error
 
@@ -30,15 +30,22 @@ func completedSpeedMBps(entry types.DownloadEntry) float64 {
 	return 0
 }
 
-// ReloadSettings reloads settings from disk
+// ReloadSettings reloads settings from disk. A changed download limit is
+// applied to the pool; an unchanged one leaves a limit set at runtime alone.
 func (s *LocalDownloadService) ReloadSettings() error {
 	settings, err := config.LoadSettings()
 	if err != nil {
 		return err
 	}
 	s.settingsMu.Lock()
+	old := s.settings
 	s.settings = settings
 	s.settingsMu.Unlock()
+
+	limit := settings.Network.MaxConcurrentDownloads
+	if s.Pool != nil && (old == nil || old.Network.MaxConcurrentDownloads != limit) {
+		s.Pool.SetMaxDownloads(limit)
+	}
 	return nil
 }
 
@@ -414,9 +421,17 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 
 // Add queues a new download.
 func (s *LocalDownloadService) Add(url string, path string, filename string, mirrors []string, headers map[string]string) (string, error) {
+	return s.AddWithOptions(url, path, filename, mirrors, headers, types.DownloadOptions{})
+}
+
+// AddWithOptions queues a new download with optional per-download parameters.
+func (s *LocalDownloadService) AddWithOptions(url string, path string, filename string, mirrors []string, headers map[string]string, opts types.DownloadOptions) (string, error) {
 	if s.Pool == nil {
 		return "", fmt.Errorf("worker pool not initialized")
 	}
+	if opts.RangeStart < 0 || opts.RangeLength < 0 {
+		return "", fmt.Errorf("%w: negative range", types.ErrRangeNotSatisfiable)
+	}
 
 	s.settingsMu.RLock()
 	settings := s.settings
@@ -449,6 +464,17 @@ func (s *LocalDownloadService) Add(url string, path string, filename string, mir
 		State:      state,
 		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
 		Headers:    headers,
+
+		RangeStart:  opts.RangeStart,
+		RangeLength: opts.RangeLength,
+
+		Seeds:        opts.Seeds,
+		DeltaControl: opts.DeltaControl,
+
+		Checksum:       opts.Checksum,
+		MaxConnections: opts.MaxConnections,
+
+		IfModified: opts.IfModified,
 	}
 
 	s.Pool.Add(cfg)
@@ -555,6 +581,14 @@ func (s *LocalDownloadService) Resume(id string) error {
 		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
 		Mirrors:    mirrorURLs,
 	}
+	if savedState != nil {
+		cfg.RangeStart = savedState.RangeStart
+		cfg.RangeLength = savedState.RangeLength
+		cfg.Headers = savedState.Headers
+		cfg.Checksum = savedState.Checksum
+		cfg.MaxConnections = savedState.MaxConnections
+		cfg.IfModified = savedState.IfModified
+	}
 
 	s.Pool.Add(cfg)
 	if s.InputCh != nil {
@@ -657,6 +691,14 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 			SavedState: savedState, // Pass loaded state to avoid re-query
 			Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
 			Mirrors:    mirrorURLs,
+			Headers:    savedState.Headers,
+
+			RangeStart:  savedState.RangeStart,
+			RangeLength: savedState.RangeLength,
+
+			Checksum:       savedState.Checksum,
+			MaxConnections: savedState.MaxConnections,
+			IfModified:     savedState.IfModified,
 		}
 
 		s.Pool.Add(cfg)
@@ -728,6 +770,7 @@ func (s *LocalDownloadService) GetStatus(id string) (*types.DownloadStatus, erro
 			ID:         entry.ID,
 			URL:        entry.URL,
 			Filename:   entry.Filename,
+			DestPath:   entry.DestPath,
 			TotalSize:  entry.TotalSize,
 			Downloaded: entry.Downloaded,
 			Progress:   progress,
@@ -747,3 +790,37 @@ func (s *LocalDownloadService) History() ([]types.DownloadEntry, error) {
 	// For local service, we can directly access the state DB
 	return state.LoadCompletedDownloads()
 }
+
+// ActiveConfigs returns the configs of downloads that are running or waiting
+// for a worker: running ones first, then waiting ones in the order they will
+// start. Paused downloads are left out, as their state is in the database.
+func (s *LocalDownloadService) ActiveConfigs() []types.DownloadConfig {
+	if s.Pool == nil {
+		return nil
+	}
+
+	queued := s.Pool.QueuedIDs()
+	waiting := make(map[string]types.DownloadConfig)
+	var configs []types.DownloadConfig
+	for _, cfg := range s.Pool.GetAll() {
+		if queued[cfg.ID] {
+			waiting[cfg.ID] = cfg
+			continue
+		}
+		if cfg.State != nil && (cfg.State.IsPaused() || cfg.State.IsPausing() || cfg.State.Done.Load()) {
+			continue
+		}
+		configs = append(configs, cfg)
+	}
+	for _, id := range s.Pool.PendingIDs() {
+		if cfg, ok := waiting[id]; ok {
+			configs = append(configs, cfg)
+			delete(waiting, id)
+		}
+	}
+	// Handed to a worker but not started yet
+	for _, cfg := range waiting {
+		configs = append(configs, cfg)
+	}
+	return configs
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
@@ -148,3 +149,35 @@ func TestLocalDownloadService_BatchProgress(t *testing.T) {
 		}
 	}
 }
+
+func TestLocalDownloadService_ReloadSettings_AppliesChangedLimit(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	settings := config.DefaultSettings()
+	if err := config.SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := download.NewWorkerPool(nil, settings.Network.MaxConcurrentDownloads)
+	svc := NewLocalDownloadService(pool)
+	defer func() { _ = svc.Shutdown() }()
+
+	// An unchanged setting keeps a limit changed at runtime
+	pool.SetMaxDownloads(7)
+	if err := svc.ReloadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if got := pool.MaxDownloads(); got != 7 {
+		t.Errorf("Expected the runtime limit 7 to stay, got %d", got)
+	}
+
+	settings.Network.MaxConcurrentDownloads = 5
+	if err := config.SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ReloadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if got := pool.MaxDownloads(); got != 5 {
+		t.Errorf("Expected the reloaded limit 5, got %d", got)
+	}
+}
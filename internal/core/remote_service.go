@@ -121,6 +121,11 @@ func (s *RemoteDownloadService) GetStatus(id string) (*types.DownloadStatus, err
 
 // Add queues a new download.
 func (s *RemoteDownloadService) Add(url string, path string, filename string, mirrors []string, headers map[string]string) (string, error) {
+	return s.AddWithOptions(url, path, filename, mirrors, headers, types.DownloadOptions{})
+}
+
+// AddWithOptions queues a new download with optional per-download parameters.
+func (s *RemoteDownloadService) AddWithOptions(url string, path string, filename string, mirrors []string, headers map[string]string, opts types.DownloadOptions) (string, error) {
 	req := map[string]interface{}{
 		"url":           url,
 		"path":          path,
@@ -129,6 +134,27 @@ func (s *RemoteDownloadService) Add(url string, path string, filename string, mi
 		"headers":       headers,
 		"skip_approval": true,
 	}
+	if opts.RangeStart > 0 {
+		req["range_start"] = opts.RangeStart
+	}
+	if opts.RangeLength > 0 {
+		req["range_length"] = opts.RangeLength
+	}
+	if len(opts.Seeds) > 0 {
+		req["seeds"] = opts.Seeds
+	}
+	if opts.DeltaControl != "" {
+		req["delta_control"] = opts.DeltaControl
+	}
+	if opts.Checksum != "" {
+		req["checksum"] = opts.Checksum
+	}
+	if opts.MaxConnections > 0 {
+		req["max_connections"] = opts.MaxConnections
+	}
+	if opts.IfModified {
+		req["if_modified"] = true
+	}
 
 	resp, err := s.doRequest("POST", "/download", req)
 	if err != nil {
@@ -209,6 +235,9 @@ func (s *RemoteDownloadService) Publish(msg interface{}) error {
 func (s *RemoteDownloadService) streamWithReconnect(ctx context.Context, ch chan interface{}) {
 	defer close(ch)
 	backoff := 1 * time.Second
+	// ID of the last event received, sent on reconnect so the daemon replays
+	// what was missed (or sends a snapshot)
+	lastEventID := ""
 	for {
 		select {
 		case <-s.ctx.Done():
@@ -218,7 +247,7 @@ func (s *RemoteDownloadService) streamWithReconnect(ctx context.Context, ch chan
 		default:
 		}
 
-		err := s.connectSSE(ctx, ch)
+		err := s.connectSSE(ctx, ch, &lastEventID)
 		if err == nil {
 			return // Clean shutdown (e.g. server closed stream cleanly or context canceled during request)
 		}
@@ -238,7 +267,7 @@ func (s *RemoteDownloadService) streamWithReconnect(ctx context.Context, ch chan
 	}
 }
 
-func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interface{}) error {
+func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interface{}, lastEventID *string) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/events", nil)
 	if err != nil {
 		return err
@@ -248,6 +277,9 @@ func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interfac
 	req.Header.Set("Accept", "text/event-stream")
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Connection", "keep-alive")
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
 
 	resp, err := s.SSEClient.Do(req)
 	if err != nil {
@@ -262,6 +294,7 @@ func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interfac
 	reader := bufio.NewReader(resp.Body)
 	for {
 		eventType := ""
+		eventID := ""
 		var dataLines []string
 
 		for {
@@ -287,8 +320,17 @@ func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interfac
 				dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
 				continue
 			}
+			if strings.HasPrefix(line, "id:") {
+				eventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+				continue
+			}
 		}
 
+		// The ID is tracked even for events without data (the daemon sends a bare
+		// ID on connect)
+		if eventID != "" {
+			*lastEventID = eventID
+		}
 		if eventType == "" || len(dataLines) == 0 {
 			continue
 		}
@@ -350,10 +392,28 @@ func (s *RemoteDownloadService) connectSSE(ctx context.Context, ch chan interfac
 				continue
 			}
 			msg = m
+		case "snapshot":
+			var m events.SnapshotMsg
+			if err := json.Unmarshal([]byte(jsonData), &m.Downloads); err != nil {
+				continue
+			}
+			msg = m
 		default:
 			continue
 		}
 
+		if eventType != "progress" {
+			// Lifecycle events must not be lost; only progress may be dropped
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return nil
+			case <-s.ctx.Done():
+				return nil
+			}
+			continue
+		}
+
 		// Non-blocking send
 		select {
 		case ch <- msg:
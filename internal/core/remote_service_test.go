@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+)
+
+func TestRemoteStreamEvents_ResumesWithLastEventID(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Last-Event-ID"))
+		attempt := len(seen)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if attempt == 1 {
+			// Send one event, then drop the connection
+			_, _ = fmt.Fprint(w, "id: 7\nevent: queued\ndata: {\"DownloadID\":\"a\",\"Filename\":\"a.bin\"}\n\n")
+			return
+		}
+		_, _ = fmt.Fprint(w, "id: 9\nevent: snapshot\ndata: [{\"id\":\"a\",\"status\":\"completed\"}]\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	svc := NewRemoteDownloadService(server.URL, "token")
+	defer func() { _ = svc.Shutdown() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, _, err := svc.StreamEvents(ctx)
+	if err != nil {
+		t.Fatalf("StreamEvents failed: %v", err)
+	}
+
+	if msg := <-stream; msg.(events.DownloadQueuedMsg).DownloadID != "a" {
+		t.Fatalf("Unexpected first event: %#v", msg)
+	}
+	msg := <-stream
+	snapshot, ok := msg.(events.SnapshotMsg)
+	if !ok || len(snapshot.Downloads) != 1 || snapshot.Downloads[0].Status != "completed" {
+		t.Fatalf("Expected snapshot after reconnect, got %#v", msg)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if seen[0] != "" || seen[1] != "7" {
+		t.Errorf("Last-Event-ID headers = %q, want [\"\" \"7\"]", seen)
+	}
+}
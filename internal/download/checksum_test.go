@@ -0,0 +1,83 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+func runOptionsDownload(t *testing.T, url, outputDir, filename, checksum string, maxConns int) error {
+	t.Helper()
+	progState := types.NewProgressState(uuid.New().String(), 0)
+	cfg := types.DownloadConfig{
+		URL:            url,
+		OutputPath:     outputDir,
+		Filename:       filename,
+		ID:             progState.ID,
+		ProgressCh:     make(chan any, 1000),
+		State:          progState,
+		Runtime:        &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+		Checksum:       checksum,
+		MaxConnections: maxConns,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return download.TUIDownload(ctx, &cfg)
+}
+
+func TestTUIDownload_ChecksumAndConnectionCap(t *testing.T) {
+	tmpDir := setupRangeTestDB(t)
+
+	payload := rangeTestPayload(16 * 1024 * 1024) // Large enough for several connections
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(payload)
+	good := "sha-256=" + hex.EncodeToString(sum[:])
+	if err := runOptionsDownload(t, server.URL+"/data.bin", tmpDir, "good.bin", good, 1); err != nil {
+		t.Fatalf("Download with a matching checksum failed: %v", err)
+	}
+	// The probe and the single worker never overlap
+	if p := peak.Load(); p > 1 {
+		t.Errorf("Expected at most 1 connection at a time, saw %d", p)
+	}
+
+	bad := "sha-256=" + hex.EncodeToString(make([]byte, sha256.Size))
+	err := runOptionsDownload(t, server.URL+"/data.bin", tmpDir, "bad.bin", bad, 0)
+	if !errors.Is(err, utils.ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	// Checked before the rename: nothing is left under either name
+	for _, name := range []string{"bad.bin", "bad.bin" + types.IncompleteSuffix} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s after a checksum mismatch, got %v", name, err)
+		}
+	}
+}
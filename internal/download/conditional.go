@@ -0,0 +1,59 @@
+package download
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// notModified reports whether the file at destPath is still the remote file
+// described by probe, so that a conditional download can be skipped. Sizes
+// must match; then the ETag or Last-Modified recorded when url was last
+// downloaded to destPath decide. Without a record, the file is current if the
+// server's Last-Modified is not newer than its modification time, as with
+// wget -N.
+func notModified(probe *engine.ProbeResult, url, destPath string) bool {
+	info, err := os.Stat(destPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if probe.FileSize > 0 && probe.FileSize != info.Size() {
+		utils.Debug("Conditional: %s changed size (%d -> %d)", destPath, info.Size(), probe.FileSize)
+		return false
+	}
+
+	rec, err := state.LastCompleted(url, destPath)
+	if err != nil {
+		utils.Debug("Conditional: no history for %s: %v", destPath, err)
+	}
+	if rec != nil && rec.TotalSize == info.Size() {
+		switch {
+		case probe.ETag != "" && rec.ETag != "":
+			return probe.ETag == rec.ETag
+		case probe.LastModified != "" && rec.LastModified != "":
+			return probe.LastModified == rec.LastModified
+		}
+	}
+
+	remote, err := http.ParseTime(probe.LastModified)
+	if err != nil || probe.FileSize <= 0 {
+		return false // Nothing to compare; download to be safe
+	}
+	return !remote.After(info.ModTime())
+}
+
+// setRemoteModTime stamps destPath with the server's Last-Modified, so a later
+// conditional download without a history record can compare against it
+func setRemoteModTime(destPath, lastModified string) {
+	remote, err := http.ParseTime(lastModified)
+	if err != nil {
+		return
+	}
+	if err := os.Chtimes(destPath, time.Now(), remote); err != nil {
+		utils.Debug("Conditional: failed to set the modification time of %s: %v", destPath, err)
+	}
+}
@@ -0,0 +1,183 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// versionedServer serves one file whose content and validators can change
+type versionedServer struct {
+	mu       sync.Mutex
+	payload  []byte
+	etag     string
+	modified time.Time
+	fetches  atomic.Int32 // Requests other than the probe
+}
+
+func (s *versionedServer) set(payload []byte, etag string, modified time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payload, s.etag, s.modified = payload, etag, modified
+}
+
+func (s *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	payload, etag, modified := s.payload, s.etag, s.modified
+	s.mu.Unlock()
+	if r.Header.Get("Range") != "bytes=0-0" {
+		s.fetches.Add(1)
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	http.ServeContent(w, r, "data.bin", modified, bytes.NewReader(payload))
+}
+
+// runConditionalDownload downloads url into outputDir with IfModified set,
+// reporting whether the download was skipped as not modified
+func runConditionalDownload(t *testing.T, url, outputDir, filename string) bool {
+	t.Helper()
+	progressCh := make(chan any, 1000)
+	progState := types.NewProgressState(uuid.New().String(), 0)
+	cfg := types.DownloadConfig{
+		URL:        url,
+		OutputPath: outputDir,
+		Filename:   filename,
+		ID:         progState.ID,
+		ProgressCh: progressCh,
+		State:      progState,
+		Runtime:    &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+		IfModified: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := download.TUIDownload(ctx, &cfg); err != nil {
+		t.Fatalf("Conditional download failed: %v", err)
+	}
+	close(progressCh)
+	for msg := range progressCh {
+		if done, ok := msg.(events.DownloadCompleteMsg); ok {
+			return done.NotModified
+		}
+	}
+	t.Fatal("No completion event")
+	return false
+}
+
+func assertOnlyFile(t *testing.T, dir string, want []byte) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 1 || names[0] != "data.bin" {
+		t.Fatalf("Expected only data.bin, got %v", names)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "data.bin"))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("data.bin does not hold the expected content (err %v)", err)
+	}
+}
+
+func TestTUIDownload_IfModified_ETag(t *testing.T) {
+	setupRangeTestDB(t)
+	outDir := t.TempDir()
+
+	v1 := rangeTestPayload(256 * 1024)
+	server := &versionedServer{}
+	server.set(v1, `"v1"`, time.Time{})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	url := ts.URL + "/data.bin"
+
+	if runConditionalDownload(t, url, outDir, "data.bin") {
+		t.Fatal("First download reported as not modified")
+	}
+	assertOnlyFile(t, outDir, v1)
+
+	fetches := server.fetches.Load()
+	for i := 0; i < 2; i++ {
+		if !runConditionalDownload(t, url, outDir, "data.bin") {
+			t.Error("Unchanged file was downloaded again")
+		}
+	}
+	if server.fetches.Load() != fetches {
+		t.Error("Expected no transfer for an unchanged file")
+	}
+	assertOnlyFile(t, outDir, v1)
+	// Checks renew the history entry instead of adding one each
+	entries, err := state.ListAllDownloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].TotalSize != int64(len(v1)) {
+		t.Errorf("Expected one history entry, got %+v", entries)
+	}
+	if rec, err := state.LastCompleted(url, filepath.Join(outDir, "data.bin")); err != nil || rec == nil || rec.ETag != `"v1"` {
+		t.Errorf("Expected the entry to keep its ETag, got %+v (%v)", rec, err)
+	}
+
+	// Same size, new ETag: replaced in place
+	v2 := bytes.Repeat([]byte{7}, len(v1))
+	server.set(v2, `"v2"`, time.Time{})
+	if runConditionalDownload(t, url, outDir, "data.bin") {
+		t.Fatal("Changed file reported as not modified")
+	}
+	assertOnlyFile(t, outDir, v2)
+}
+
+func TestTUIDownload_IfModified_ModTime(t *testing.T) {
+	setupRangeTestDB(t)
+	outDir := t.TempDir()
+
+	// A copy made before Surge knew about it: no history, only its mtime
+	old := rangeTestPayload(64 * 1024)
+	local := filepath.Join(outDir, "data.bin")
+	if err := os.WriteFile(local, old, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := &versionedServer{}
+	server.set(bytes.Repeat([]byte{1}, len(old)), "", time.Now().Add(-time.Hour))
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	url := ts.URL + "/data.bin"
+
+	if !runConditionalDownload(t, url, outDir, "data.bin") {
+		t.Error("Expected a local copy newer than the server's to be kept")
+	}
+	assertOnlyFile(t, outDir, old)
+
+	modified := time.Now().Add(time.Hour).Truncate(time.Second)
+	newer := bytes.Repeat([]byte{2}, len(old))
+	server.set(newer, "", modified)
+	if runConditionalDownload(t, url, outDir, "data.bin") {
+		t.Fatal("Newer remote file reported as not modified")
+	}
+	assertOnlyFile(t, outDir, newer)
+	info, err := os.Stat(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modified) {
+		t.Errorf("Expected the server's Last-Modified as mtime, got %v", info.ModTime())
+	}
+}
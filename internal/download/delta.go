@@ -0,0 +1,61 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/surge-downloader/surge/internal/engine/delta"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// defaultControlURL returns the conventional location of a .zsync file: next to the target
+func defaultControlURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl + ".zsync"
+	}
+	u.Path += ".zsync"
+	u.RawPath = ""
+	return u.String()
+}
+
+// loadDeltaControl loads the control file at source and checks that it describes
+// a target of fileSize bytes
+func loadDeltaControl(ctx context.Context, source string, headers map[string]string, fileSize int64) (*delta.Control, error) {
+	control, err := delta.LoadControl(ctx, source, headers)
+	if err != nil {
+		return nil, err
+	}
+	if control.Length != fileSize {
+		return nil, fmt.Errorf("%w: control file describes %d bytes, server reports %d", delta.ErrInvalidControl, control.Length, fileSize)
+	}
+	return control, nil
+}
+
+// prepareDelta copies the blocks found in cfg.Seeds into the working file and
+// returns the control file, its location and the ranges that still have to be downloaded
+func prepareDelta(ctx context.Context, cfg *types.DownloadConfig, destPath string, fileSize int64) (*delta.Control, string, []types.Task, error) {
+	source := cfg.DeltaControl
+	if source == "" {
+		source = defaultControlURL(cfg.URL)
+	}
+
+	control, err := loadDeltaControl(ctx, source, cfg.Headers, fileSize)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	plan, err := delta.Match(control, cfg.Seeds)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if err := plan.Apply(destPath + types.IncompleteSuffix); err != nil {
+		return nil, "", nil, err
+	}
+
+	missing := plan.Missing()
+	utils.Debug("Delta update: reusing %s from seeds, fetching %d ranges", utils.ConvertBytesToHumanReadable(plan.Reused), len(missing))
+	return control, source, missing, nil
+}
@@ -0,0 +1,251 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestDefaultControlURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/images/nightly.iso", "https://example.com/images/nightly.iso.zsync"},
+		{"https://example.com/nightly.iso?sig=abc", "https://example.com/nightly.iso.zsync?sig=abc"},
+	}
+	for _, tt := range tests {
+		if got := defaultControlURL(tt.in); got != tt.want {
+			t.Errorf("defaultControlURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// blockHashList builds a block hash list control file for data
+func blockHashList(data []byte, blockSize int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Blocksize: %d\nLength: %d\nSHA-256: %x\n\n", blockSize, len(data), sha256.Sum256(data))
+	for off := 0; off < len(data); off += blockSize {
+		sum := sha256.Sum256(data[off:min(off+blockSize, len(data))])
+		fmt.Fprintln(&buf, hex.EncodeToString(sum[:]))
+	}
+	return buf.Bytes()
+}
+
+func TestTUIDownload_DeltaFromSeed(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	// The new image differs from the old one in a single 64KB block
+	const blockSize = 64 * 1024
+	seed := make([]byte, 2*1024*1024)
+	rand.New(rand.NewSource(1)).Read(seed)
+	target := append([]byte{}, seed...)
+	rand.New(rand.NewSource(2)).Read(target[5*blockSize : 6*blockSize])
+
+	seedPath := filepath.Join(tmpDir, "old.img")
+	if err := os.WriteFile(seedPath, seed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var served atomic.Int64
+	control := blockHashList(target, blockSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/new.img.zsync":
+			_, _ = w.Write(control)
+		case "/new.img":
+			cw := &countingResponseWriter{ResponseWriter: w, n: &served}
+			http.ServeContent(cw, r, "new.img", time.Time{}, bytes.NewReader(target))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	outDir := filepath.Join(tmpDir, "out")
+	progState := types.NewProgressState(uuid.New().String(), 0)
+	cfg := types.DownloadConfig{
+		URL:        server.URL + "/new.img",
+		OutputPath: outDir,
+		Filename:   "new.img",
+		ID:         progState.ID,
+		ProgressCh: make(chan any, 1000),
+		State:      progState,
+		Runtime:    &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+		Seeds:      []string{seedPath},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := TUIDownload(ctx, &cfg); err != nil {
+		t.Fatalf("TUIDownload failed: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(outDir, "new.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Fatal("Assembled file does not match target")
+	}
+
+	// Only the changed block (plus the probe) should have come over the network
+	if n := served.Load(); n > 4*blockSize {
+		t.Errorf("Transferred %d bytes, expected about %d", n, blockSize)
+	}
+}
+
+func TestTUIDownload_DeltaVerifyFailureKeepsDestination(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	const blockSize = 64 * 1024
+	seed := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(1)).Read(seed)
+	target := append([]byte{}, seed...)
+	rand.New(rand.NewSource(2)).Read(target[3*blockSize : 4*blockSize])
+	// The server's bytes for the changed block don't match the control file
+	served := append([]byte{}, target...)
+	served[3*blockSize] ^= 0xff
+
+	seedPath := filepath.Join(tmpDir, "old.img")
+	if err := os.WriteFile(seedPath, seed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	control := blockHashList(target, blockSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/new.img.zsync" {
+			_, _ = w.Write(control)
+			return
+		}
+		http.ServeContent(w, r, "new.img", time.Time{}, bytes.NewReader(served))
+	}))
+	defer server.Close()
+
+	outDir := filepath.Join(tmpDir, "out")
+	progState := types.NewProgressState(uuid.New().String(), 0)
+	cfg := types.DownloadConfig{
+		URL:        server.URL + "/new.img",
+		OutputPath: outDir,
+		Filename:   "new.img",
+		ID:         progState.ID,
+		ProgressCh: make(chan any, 1000),
+		State:      progState,
+		Runtime:    &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+		Seeds:      []string{seedPath},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := TUIDownload(ctx, &cfg); err == nil {
+		t.Fatal("Expected the delta verification to fail")
+	}
+	for _, name := range []string{"new.img", "new.img" + types.IncompleteSuffix} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s after a failed verification, got %v", name, err)
+		}
+	}
+}
+
+func TestTUIDownload_DeltaResumeVerifies(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	defer state.CloseDB()
+
+	const blockSize = 64 * 1024
+	target := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(1)).Read(target)
+	control := blockHashList(target, blockSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/new.img.zsync" {
+			_, _ = w.Write(control)
+			return
+		}
+		http.ServeContent(w, r, "new.img", time.Time{}, bytes.NewReader(target))
+	}))
+	defer server.Close()
+
+	// A delta update paused with one block left to fetch, after a seeded
+	// block went bad in the working file
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	destPath := filepath.Join(outDir, "new.img")
+	working := append([]byte{}, target...)
+	working[0] ^= 0xff
+	clear(working[3*blockSize : 4*blockSize])
+	if err := os.WriteFile(destPath+types.IncompleteSuffix, working, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	url := server.URL + "/new.img"
+	id := uuid.New().String()
+	if err := state.SaveState(url, destPath, &types.DownloadState{
+		ID:           id,
+		URL:          url,
+		DestPath:     destPath,
+		Filename:     "new.img",
+		TotalSize:    int64(len(target)),
+		Downloaded:   int64(len(target) - blockSize),
+		Tasks:        []types.Task{{Offset: 3 * blockSize, Length: blockSize}},
+		DeltaControl: url + ".zsync",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	progState := types.NewProgressState(id, 0)
+	cfg := types.DownloadConfig{
+		URL:        url,
+		OutputPath: outDir,
+		DestPath:   destPath,
+		Filename:   "new.img",
+		ID:         id,
+		ProgressCh: make(chan any, 1000),
+		State:      progState,
+		Runtime:    &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+		IsResume:   true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := TUIDownload(ctx, &cfg); err == nil {
+		t.Fatal("Expected the resumed delta update to fail verification")
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no %s after a failed verification, got %v", destPath, err)
+	}
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return c.ResponseWriter.Write(p)
+}
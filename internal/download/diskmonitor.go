@@ -0,0 +1,130 @@
+package download
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/concurrent"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// DiskCheckInterval is how often the pool checks free space on active downloads' filesystems
+const DiskCheckInterval = 5 * time.Second
+
+// diskPauseReason is attached to pause events emitted by the disk space monitor
+const diskPauseReason = "low disk space"
+
+// diskMonitor periodically pauses and resumes downloads based on free disk space,
+// until the pool shuts down
+func (p *WorkerPool) diskMonitor() {
+	ticker := time.NewTicker(DiskCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkDiskSpace()
+		}
+	}
+}
+
+// checkDiskSpace pauses every download whose destination filesystem has dropped below
+// its configured free space floor. It resumes the ones it paused once free space
+// recovers to twice the floor (the margin avoids flapping around the threshold) and
+// the rest of the download fits, as checked when a download starts.
+func (p *WorkerPool) checkDiskSpace() {
+	type candidate struct {
+		id         string
+		dir        string
+		workPath   string
+		total      int64
+		floor      int64
+		running    bool
+		diskPaused bool
+	}
+
+	p.mu.Lock()
+	var candidates []candidate
+	for id, ad := range p.downloads {
+		st := ad.config.State
+		if st == nil || st.Done.Load() || st.IsPausing() {
+			continue
+		}
+		floor := ad.config.Runtime.GetMinFreeDiskSpace()
+		destPath := st.GetDestPath()
+		_, total, _, _, _, _ := st.GetProgress()
+		// Downloads paused by a full disk are resumed even with the floor disabled
+		if destPath == "" || (floor <= 0 && !p.diskPaused[id]) {
+			continue
+		}
+
+		c := candidate{
+			id:         id,
+			dir:        filepath.Dir(destPath),
+			workPath:   destPath + types.IncompleteSuffix,
+			total:      total,
+			floor:      floor,
+			running:    !st.IsPaused(),
+			diskPaused: p.diskPaused[id],
+		}
+		// Someone resumed it by hand: it's no longer ours to resume
+		if c.diskPaused && c.running {
+			delete(p.diskPaused, id)
+			c.diskPaused = false
+		}
+		candidates = append(candidates, c)
+	}
+	p.mu.Unlock()
+
+	available := make(map[string]int64)
+	for _, c := range candidates {
+		free, ok := available[c.dir]
+		if !ok {
+			var err error
+			free, err = utils.AvailableDiskSpace(c.dir)
+			if err != nil {
+				utils.Debug("Disk monitor: cannot stat %s: %v", c.dir, err)
+				continue
+			}
+			available[c.dir] = free
+		}
+
+		switch {
+		case c.running && free < c.floor:
+			utils.Debug("Disk monitor: pausing %s (%s free, floor %s)", c.id,
+				utils.ConvertBytesToHumanReadable(free), utils.ConvertBytesToHumanReadable(c.floor))
+			if p.pause(c.id, diskPauseReason) {
+				p.mu.Lock()
+				p.diskPaused[c.id] = true
+				p.mu.Unlock()
+			}
+		case c.diskPaused && free >= 2*c.floor && concurrent.Fits(c.id, c.workPath, c.total, c.floor):
+			utils.Debug("Disk monitor: resuming %s (%s free)", c.id, utils.ConvertBytesToHumanReadable(free))
+			p.mu.Lock()
+			delete(p.diskPaused, c.id)
+			p.mu.Unlock()
+			p.Resume(c.id)
+		}
+	}
+}
+
+// diskFull takes over a download that paused itself after the disk filled up
+// mid-download, so that checkDiskSpace resumes it once the rest fits again
+func (p *WorkerPool) diskFull(ad *activeDownload) {
+	p.mu.Lock()
+	p.diskPaused[ad.config.ID] = true
+	p.mu.Unlock()
+
+	if p.progressCh != nil {
+		p.progressCh <- events.DownloadPausedMsg{
+			DownloadID: ad.config.ID,
+			Filename:   ad.config.Filename,
+			Downloaded: ad.config.State.Downloaded.Load(),
+			Reason:     diskPauseReason,
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package download
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func addMonitoredDownload(pool *WorkerPool, id string, destPath string, floor int64) *types.ProgressState {
+	state := types.NewProgressState(id, 1000)
+	state.SetDestPath(destPath)
+
+	pool.mu.Lock()
+	pool.downloads[id] = &activeDownload{
+		config: types.DownloadConfig{
+			ID:      id,
+			State:   state,
+			Runtime: &types.RuntimeConfig{MinFreeDiskSpace: floor},
+		},
+	}
+	pool.mu.Unlock()
+	return state
+}
+
+func TestWorkerPool_CheckDiskSpace_PausesBelowFloor(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	state := addMonitoredDownload(pool, "low-disk", destPath, 1<<62)
+
+	pool.checkDiskSpace()
+
+	if !state.IsPaused() {
+		t.Fatal("Expected download to be paused when free space is below the floor")
+	}
+
+	select {
+	case msg := <-ch:
+		paused, ok := msg.(events.DownloadPausedMsg)
+		if !ok {
+			t.Fatalf("Expected DownloadPausedMsg, got %T", msg)
+		}
+		if paused.Reason != diskPauseReason {
+			t.Errorf("Reason = %q, want %q", paused.Reason, diskPauseReason)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Expected pause message to be sent")
+	}
+
+	pool.mu.RLock()
+	marked := pool.diskPaused["low-disk"]
+	pool.mu.RUnlock()
+	if !marked {
+		t.Error("Expected download to be marked as disk-paused")
+	}
+}
+
+func TestWorkerPool_CheckDiskSpace_ResumesWhenSpaceReturns(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	state := addMonitoredDownload(pool, "recovered", destPath, 1)
+	state.Paused.Store(true)
+
+	pool.mu.Lock()
+	pool.diskPaused["recovered"] = true
+	pool.mu.Unlock()
+
+	pool.checkDiskSpace()
+
+	if state.IsPaused() {
+		t.Fatal("Expected disk-paused download to be resumed once space is available")
+	}
+
+	pool.mu.RLock()
+	marked := pool.diskPaused["recovered"]
+	pool.mu.RUnlock()
+	if marked {
+		t.Error("Expected disk-paused mark to be cleared")
+	}
+}
+
+func TestWorkerPool_CheckDiskSpace_StaysPausedUntilDownloadFits(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	// Twice the floor is free, but the rest of the download is not
+	destPath := filepath.Join(t.TempDir(), "huge.bin")
+	state := addMonitoredDownload(pool, "huge", destPath, 1)
+	state.SetTotalSize(1 << 62)
+	state.Paused.Store(true)
+
+	pool.mu.Lock()
+	pool.diskPaused["huge"] = true
+	pool.mu.Unlock()
+
+	pool.checkDiskSpace()
+
+	if !state.IsPaused() {
+		t.Fatal("Expected a download that can't reserve its space to stay paused")
+	}
+	pool.mu.RLock()
+	marked := pool.diskPaused["huge"]
+	pool.mu.RUnlock()
+	if !marked {
+		t.Error("Expected the disk-paused mark to be kept")
+	}
+}
+
+func TestWorkerPool_CheckDiskSpace_IgnoresUserPaused(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	state := addMonitoredDownload(pool, "user-paused", destPath, 1)
+	state.Paused.Store(true)
+
+	pool.checkDiskSpace()
+
+	if !state.IsPaused() {
+		t.Error("Expected manually paused download to stay paused")
+	}
+}
+
+func TestWorkerPool_CheckDiskSpace_DisabledFloor(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	state := addMonitoredDownload(pool, "no-floor", destPath, 0)
+
+	pool.checkDiskSpace()
+
+	if state.IsPaused() {
+		t.Error("Expected download to keep running when the floor is disabled")
+	}
+}
+
+func TestWorkerPool_DiskFull_ResumedByMonitor(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	// The disk filled up mid-download with the floor disabled
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	state := addMonitoredDownload(pool, "disk-full", destPath, 0)
+	state.Paused.Store(true)
+
+	pool.mu.RLock()
+	ad := pool.downloads["disk-full"]
+	pool.mu.RUnlock()
+	pool.diskFull(ad)
+
+	select {
+	case msg := <-ch:
+		paused, ok := msg.(events.DownloadPausedMsg)
+		if !ok {
+			t.Fatalf("Expected DownloadPausedMsg, got %T", msg)
+		}
+		if paused.Reason != diskPauseReason {
+			t.Errorf("Reason = %q, want %q", paused.Reason, diskPauseReason)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Expected pause message to be sent")
+	}
+
+	// The rest of the download fits again
+	pool.checkDiskSpace()
+
+	if state.IsPaused() {
+		t.Fatal("Expected the monitor to resume a download paused by a full disk")
+	}
+}
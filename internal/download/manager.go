@@ -12,6 +12,7 @@ import (
 
 	"github.com/surge-downloader/surge/internal/engine"
 	"github.com/surge-downloader/surge/internal/engine/concurrent"
+	"github.com/surge-downloader/surge/internal/engine/delta"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/single"
 	"github.com/surge-downloader/surge/internal/engine/state"
@@ -29,6 +30,20 @@ type ProbeResult struct {
 
 // probeServer has been moved to internal/engine/probe.go
 
+// cappedRuntime returns the runtime config with the download's own connection
+// cap applied, without changing the shared settings
+func cappedRuntime(cfg *types.DownloadConfig) *types.RuntimeConfig {
+	if cfg.MaxConnections <= 0 || cfg.MaxConnections >= cfg.Runtime.GetMaxConnectionsPerHost() {
+		return cfg.Runtime
+	}
+	capped := types.RuntimeConfig{}
+	if cfg.Runtime != nil {
+		capped = *cfg.Runtime
+	}
+	capped.MaxConnectionsPerHost = cfg.MaxConnections
+	return &capped
+}
+
 // uniqueFilePath returns a unique file path by appending (1), (2), etc. if the file exists
 func uniqueFilePath(path string) string {
 	// Check if file exists (both final and incomplete)
@@ -89,6 +104,15 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	}
 	utils.Debug("TUIDownload: Probe success %d", probe.FileSize)
 
+	// Partial downloads fetch only [rangeStart, rangeStart+fileSize) of the resource
+	rangeStart, fileSize, err := cfg.ResolveRange(probe.FileSize)
+	if err != nil {
+		return err
+	}
+	if cfg.IsPartial() && !probe.SupportsRange {
+		return fmt.Errorf("%w: cannot fetch part of %s", types.ErrRangeNotSupported, cfg.URL)
+	}
+
 	// Start download timer (exclude probing time)
 	start := time.Now()
 	defer func() {
@@ -153,6 +177,15 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		// Resume: use saved destination path directly (don't generate new unique name)
 		destPath = savedState.DestPath
 		utils.Debug("Resuming download, using saved destPath: %s", destPath)
+	} else if cfg.IfModified && !cfg.IsPartial() {
+		// Keep the name: the finished download is renamed over the old file
+		if _, err := os.Stat(destPath + types.IncompleteSuffix); err == nil {
+			return fmt.Errorf("an unfinished download of %s exists", destPath)
+		}
+		if notModified(probe, cfg.URL, destPath) {
+			utils.Debug("Not modified, skipping download: %s", destPath)
+			return finishNotModified(cfg, probe, destPath)
+		}
 	} else {
 		// Fresh download without TUI-provided filename: generate unique filename if file already exists
 		destPath = uniqueFilePath(destPath)
@@ -175,7 +208,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			DownloadID: cfg.ID,
 			URL:        cfg.URL,
 			Filename:   finalFilename,
-			Total:      probe.FileSize,
+			Total:      fileSize,
 			DestPath:   destPath,
 			State:      cfg.State,
 		}
@@ -183,12 +216,19 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 	// Update shared state
 	if cfg.State != nil {
-		cfg.State.SetTotalSize(probe.FileSize)
+		cfg.State.SetTotalSize(fileSize)
+	}
+
+	// The checksum is checked on the working file, so a mismatch never
+	// replaces or leaves behind a file under the real name
+	var verifyChecksum func(path string) error
+	if cfg.Checksum != "" {
+		verifyChecksum = func(path string) error { return utils.VerifyChecksum(path, cfg.Checksum) }
 	}
 
 	// Choose downloader based on probe results
 	var downloadErr error
-	if probe.SupportsRange && probe.FileSize > 0 {
+	if probe.SupportsRange && fileSize > 0 {
 		utils.Debug("Using concurrent downloader")
 
 		// We probe all candidate mirrors (mirrors) to filter out invalid ones
@@ -213,22 +253,64 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			utils.Debug("Found %d active mirrors from %d candidates", len(activeMirrors), len(mirrors))
 		}
 
-		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
+		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cappedRuntime(cfg))
 		d.Headers = cfg.Headers // Forward custom headers from browser extension
+		d.Checksum, d.MaxConnections, d.IfModified = cfg.Checksum, cfg.MaxConnections, cfg.IfModified
+		d.Verify = verifyChecksum
+		if cfg.IsPartial() {
+			d.RangeStart = rangeStart
+			d.RangeLength = fileSize
+		}
+
+		// Delta update: copy blocks already present in local seeds, fetch only the rest
+		var control *delta.Control
+		if isResume && savedState.DeltaControl != "" {
+			// The seeded blocks were copied before the pause; they still need checking
+			control, err = loadDeltaControl(ctx, savedState.DeltaControl, cfg.Headers, fileSize)
+			if err != nil {
+				return fmt.Errorf("cannot verify resumed delta update: %w", err)
+			}
+			d.DeltaControl = savedState.DeltaControl
+		} else if len(cfg.Seeds) > 0 && !isResume && !cfg.IsPartial() {
+			var ranges []types.Task
+			control, d.DeltaControl, ranges, err = prepareDelta(ctx, cfg, destPath, fileSize)
+			if err != nil {
+				utils.Debug("Delta update unavailable, downloading in full: %v", err)
+				control = nil
+			} else {
+				d.Ranges = ranges
+			}
+		}
+		if control != nil {
+			d.Verify = func(path string) error { // Checked before the result replaces anything
+				if err := control.Verify(path); err != nil || verifyChecksum == nil {
+					return err
+				}
+				return verifyChecksum(path)
+			}
+		}
+
 		utils.Debug("Calling Download with mirrors: %v", mirrors)
-		downloadErr = d.Download(ctx, cfg.URL, mirrors, activeMirrors, destPath, probe.FileSize)
+		downloadErr = d.Download(ctx, cfg.URL, mirrors, activeMirrors, destPath, fileSize)
 	} else {
 		// Fallback to single-threaded downloader
 		utils.Debug("Using single-threaded downloader")
 		d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
 		d.Headers = cfg.Headers // Forward custom headers from browser extension
-		downloadErr = d.Download(ctx, cfg.URL, destPath, probe.FileSize, probe.Filename)
+		d.Verify = verifyChecksum
+		downloadErr = d.Download(ctx, cfg.URL, destPath, fileSize, probe.Filename)
+	}
+	if downloadErr == nil && cfg.IfModified {
+		setRemoteModTime(destPath, probe.LastModified)
 	}
 
 	// Only send completion if NO error AND not paused
 	// Check specifically for ErrPaused to avoid treating it as error
 	if errors.Is(downloadErr, types.ErrPaused) {
 		utils.Debug("Download paused cleanly")
+		if errors.Is(downloadErr, types.ErrInsufficientDiskSpace) {
+			return downloadErr // The pool hands disk-full pauses to the disk monitor
+		}
 		return nil // Return nil so worker can remove it from active map
 	}
 
@@ -244,7 +326,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		// Compute average download speed in bytes/sec
 		var avgSpeed float64
 		if elapsed.Seconds() > 0 {
-			avgSpeed = float64(probe.FileSize) / elapsed.Seconds()
+			avgSpeed = float64(fileSize) / elapsed.Seconds()
 		}
 
 		if err := state.AddToMasterList(types.DownloadEntry{
@@ -254,11 +336,14 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			DestPath:    destPath,
 			Filename:    finalFilename,
 			Status:      "completed",
-			TotalSize:   probe.FileSize,
-			Downloaded:  probe.FileSize,
+			TotalSize:   fileSize,
+			Downloaded:  fileSize,
 			CompletedAt: time.Now().Unix(),
 			TimeTaken:   elapsed.Milliseconds(),
 			AvgSpeed:    avgSpeed,
+
+			ETag:         probe.ETag,
+			LastModified: probe.LastModified,
 		}); err != nil {
 			utils.Debug("Failed to persist completed download: %v", err)
 		}
@@ -268,7 +353,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 				DownloadID: cfg.ID,
 				Filename:   finalFilename,
 				Elapsed:    elapsed,
-				Total:      probe.FileSize,
+				Total:      fileSize,
 				AvgSpeed:   avgSpeed,
 			}
 		}
@@ -287,7 +372,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			DestPath:   destPath,
 			Filename:   finalFilename,
 			Status:     "error",
-			TotalSize:  probe.FileSize,
+			TotalSize:  fileSize,
 			Downloaded: cfg.State.Downloaded.Load(),
 		}); err != nil {
 			utils.Debug("Failed to persist error state: %v", err)
@@ -297,6 +382,67 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	return downloadErr
 }
 
+// finishNotModified completes a conditional download whose local file is
+// already current, recording it in history without transferring anything
+func finishNotModified(cfg *types.DownloadConfig, probe *engine.ProbeResult, destPath string) error {
+	filename := filepath.Base(destPath)
+	if cfg.State != nil {
+		cfg.State.SetFilename(filename)
+		cfg.State.SetDestPath(destPath)
+		cfg.State.SetTotalSize(probe.FileSize)
+		cfg.State.Downloaded.Store(probe.FileSize)
+	}
+
+	entry := types.DownloadEntry{
+		ID:           cfg.ID,
+		URL:          cfg.URL,
+		URLHash:      state.URLHash(cfg.URL),
+		DestPath:     destPath,
+		Filename:     filename,
+		Status:       "completed",
+		TotalSize:    probe.FileSize,
+		Downloaded:   probe.FileSize,
+		CompletedAt:  time.Now().Unix(),
+		ETag:         probe.ETag,
+		LastModified: probe.LastModified,
+	}
+	// Renew the entry of the last download rather than adding one per check
+	renewed := false
+	if rec, _ := state.LastCompleted(cfg.URL, destPath); rec != nil {
+		if entry.TotalSize <= 0 {
+			entry.TotalSize, entry.Downloaded = rec.TotalSize, rec.Downloaded
+		}
+		if err := state.RenewCompleted(rec.ID, entry); err != nil {
+			utils.Debug("Failed to renew completed download %s: %v", rec.ID, err)
+		} else {
+			renewed = true
+		}
+	}
+	if !renewed {
+		if err := state.AddToMasterList(entry); err != nil {
+			utils.Debug("Failed to persist not modified download: %v", err)
+		}
+	}
+
+	if cfg.ProgressCh != nil {
+		cfg.ProgressCh <- events.DownloadStartedMsg{
+			DownloadID: cfg.ID,
+			URL:        cfg.URL,
+			Filename:   filename,
+			Total:      probe.FileSize,
+			DestPath:   destPath,
+			State:      cfg.State,
+		}
+		cfg.ProgressCh <- events.DownloadCompleteMsg{
+			DownloadID:  cfg.ID,
+			Filename:    filename,
+			Total:       probe.FileSize,
+			NotModified: true,
+		}
+	}
+	return nil
+}
+
 // Download is the CLI entry point (non-TUI) - convenience wrapper
 func Download(ctx context.Context, url string, outPath string, progressCh chan<- any, id string) error {
 	cfg := types.DownloadConfig{
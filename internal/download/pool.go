@@ -2,6 +2,7 @@ package download
 
 import (
 	"context"
+	"errors"
 	"os"
 	"sync"
 	"time"
@@ -19,13 +20,19 @@ type activeDownload struct {
 }
 
 type WorkerPool struct {
-	taskChan     chan types.DownloadConfig
+	taskChan     chan types.DownloadConfig // Unbuffered: hands the next pending download to a free worker
 	progressCh   chan<- any
 	downloads    map[string]*activeDownload      // Track active downloads for pause/resume
 	queued       map[string]types.DownloadConfig // Track queued downloads
+	pending      []types.DownloadConfig          // Queued downloads not yet handed to a worker, in start order
+	pendingCond  *sync.Cond                      // Signalled when pending grows
 	mu           sync.RWMutex
 	wg           sync.WaitGroup // We use this to wait for all active downloads to pause before exiting the program
 	maxDownloads int
+	workers      int             // Running worker goroutines; above maxDownloads after the limit is lowered
+	diskPaused   map[string]bool // Downloads paused by the disk space monitor, resumed when space returns
+	stop         chan struct{}   // Closed on shutdown to stop background loops
+	stopOnce     sync.Once
 }
 
 func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
@@ -33,15 +40,21 @@ func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
 		maxDownloads = 3 // Default to 3 if invalid
 	}
 	pool := &WorkerPool{
-		taskChan:     make(chan types.DownloadConfig, 100), // We make it buffered to avoid blocking add
+		taskChan:     make(chan types.DownloadConfig),
 		progressCh:   progressCh,
 		downloads:    make(map[string]*activeDownload),
 		queued:       make(map[string]types.DownloadConfig),
 		maxDownloads: maxDownloads,
+		workers:      maxDownloads,
+		diskPaused:   make(map[string]bool),
+		stop:         make(chan struct{}),
 	}
+	pool.pendingCond = sync.NewCond(&pool.mu)
 	for i := 0; i < maxDownloads; i++ {
 		go pool.worker()
 	}
+	go pool.dispatch()
+	go pool.diskMonitor()
 	return pool
 }
 
@@ -49,6 +62,8 @@ func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
 func (p *WorkerPool) Add(cfg types.DownloadConfig) {
 	p.mu.Lock()
 	p.queued[cfg.ID] = cfg
+	p.pending = append(p.pending, cfg)
+	p.pendingCond.Signal()
 	p.mu.Unlock()
 
 	if p.progressCh != nil && !cfg.IsResume {
@@ -57,8 +72,96 @@ func (p *WorkerPool) Add(cfg types.DownloadConfig) {
 			Filename:   cfg.Filename,
 		}
 	}
+}
+
+// dispatch hands pending downloads to workers in order. Downloads stay in
+// pending (and can be reordered) until a worker is free to take them.
+func (p *WorkerPool) dispatch() {
+	for {
+		p.mu.Lock()
+		for len(p.pending) == 0 {
+			p.pendingCond.Wait()
+		}
+		cfg := p.pending[0]
+		p.pending = p.pending[1:]
+		p.mu.Unlock()
+
+		p.taskChan <- cfg
+	}
+}
+
+// Reorder moves a queued download to position in the pending queue (0 starts
+// next; out-of-range positions are clamped). It returns false if id is not waiting.
+func (p *WorkerPool) Reorder(id string, position int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	from := -1
+	for i, cfg := range p.pending {
+		if cfg.ID == id {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return false
+	}
+
+	cfg := p.pending[from]
+	p.pending = append(p.pending[:from], p.pending[from+1:]...)
+	position = max(0, min(position, len(p.pending)))
+	p.pending = append(p.pending[:position], append([]types.DownloadConfig{cfg}, p.pending[position:]...)...)
+	return true
+}
+
+// PendingIDs returns the IDs of queued downloads in the order they will start
+func (p *WorkerPool) PendingIDs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ids := make([]string, len(p.pending))
+	for i, cfg := range p.pending {
+		ids[i] = cfg.ID
+	}
+	return ids
+}
+
+// SetMaxDownloads changes how many downloads run at once. Lowering the limit
+// lets running downloads finish; no new ones start until below it.
+func (p *WorkerPool) SetMaxDownloads(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	p.taskChan <- cfg
+	p.maxDownloads = n
+	for p.workers < n {
+		p.workers++
+		go p.worker()
+	}
+}
+
+// MaxDownloads returns the current concurrent download limit
+func (p *WorkerPool) MaxDownloads() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.maxDownloads
+}
+
+// retireWorker reports whether the calling worker is surplus after the limit
+// was lowered, in which case cfg goes back to the front of the queue
+func (p *WorkerPool) retireWorker(cfg types.DownloadConfig) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.workers <= p.maxDownloads {
+		return false
+	}
+	p.workers--
+	p.pending = append([]types.DownloadConfig{cfg}, p.pending...)
+	p.pendingCond.Signal()
+	return true
 }
 
 // HasDownload checks if a download with the given URL already exists
@@ -100,6 +203,18 @@ func (p *WorkerPool) ActiveCount() int {
 	return count
 }
 
+// QueuedIDs returns the IDs of downloads waiting for a free worker
+func (p *WorkerPool) QueuedIDs() map[string]bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ids := make(map[string]bool, len(p.queued))
+	for id := range p.queued {
+		ids[id] = true
+	}
+	return ids
+}
+
 // GetAll returns all active download configs (for listing)
 func (p *WorkerPool) GetAll() []types.DownloadConfig {
 	p.mu.RLock()
@@ -133,6 +248,11 @@ func (p *WorkerPool) GetAll() []types.DownloadConfig {
 
 // Pause pauses a specific download by ID. Returns true if found and pause initiated (or already paused), false otherwise.
 func (p *WorkerPool) Pause(downloadID string) bool {
+	return p.pause(downloadID, "")
+}
+
+// pause pauses a download, attaching reason to the paused event when Surge initiated it
+func (p *WorkerPool) pause(downloadID string, reason string) bool {
 	p.mu.RLock()
 	ad, exists := p.downloads[downloadID]
 	p.mu.RUnlock()
@@ -161,6 +281,7 @@ func (p *WorkerPool) Pause(downloadID string) bool {
 			DownloadID: downloadID,
 			Filename:   ad.config.Filename,
 			Downloaded: downloaded,
+			Reason:     reason,
 		}
 	}
 	return true
@@ -190,6 +311,7 @@ func (p *WorkerPool) Cancel(downloadID string) {
 	if exists {
 		delete(p.downloads, downloadID)
 	}
+	delete(p.diskPaused, downloadID)
 	p.mu.Unlock()
 
 	if !exists || ad == nil {
@@ -265,6 +387,9 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 
 func (p *WorkerPool) worker() {
 	for cfg := range p.taskChan {
+		if p.retireWorker(cfg) {
+			return
+		}
 		p.wg.Add(1)
 		// Create cancellable context
 		ctx, cancel := context.WithCancel(context.Background())
@@ -294,6 +419,9 @@ func (p *WorkerPool) worker() {
 
 		if isPaused {
 			utils.Debug("WorkerPool: Download %s paused cleanly", cfg.ID)
+			if errors.Is(err, types.ErrInsufficientDiskSpace) {
+				p.diskFull(ad)
+			}
 			// If paused, we keep it in downloads map for potential resume
 		} else if err != nil {
 			if cfg.State != nil {
@@ -328,6 +456,18 @@ func (p *WorkerPool) worker() {
 	}
 }
 
+// GetConfig returns the config of an active download, with its live ProgressState
+func (p *WorkerPool) GetConfig(id string) (types.DownloadConfig, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ad, exists := p.downloads[id]
+	if !exists {
+		return types.DownloadConfig{}, false
+	}
+	return ad.config, true
+}
+
 // GetStatus returns the status of an active download
 func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	p.mu.RLock()
@@ -368,6 +508,7 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 		ID:         id,
 		URL:        ad.config.URL,
 		Filename:   filename,
+		DestPath:   state.GetDestPath(),
 		TotalSize:  totalSize,
 		Downloaded: downloaded,
 		Status:     "downloading",
@@ -403,7 +544,8 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 
 // GracefulShutdown pauses all downloads and waits for them to save state
 func (p *WorkerPool) GracefulShutdown() {
-	// ... existing implementation
+	// Stop the disk monitor first, so it can't resume what is being paused
+	p.stopOnce.Do(func() { close(p.stop) })
 	p.PauseAll()
 
 	// Wait for any downloads in "Pausing" state to finish transitioning
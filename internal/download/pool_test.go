@@ -782,3 +782,80 @@ func TestWorkerPool_PauseResume_Idempotency(t *testing.T) {
 		// OK
 	}
 }
+
+// newIdlePool builds a pool with queued downloads but no worker or dispatch goroutines
+func newIdlePool(ids ...string) *WorkerPool {
+	p := &WorkerPool{
+		taskChan:     make(chan types.DownloadConfig),
+		downloads:    make(map[string]*activeDownload),
+		queued:       make(map[string]types.DownloadConfig),
+		maxDownloads: 1,
+		workers:      1,
+		diskPaused:   make(map[string]bool),
+	}
+	p.pendingCond = sync.NewCond(&p.mu)
+	for _, id := range ids {
+		cfg := types.DownloadConfig{ID: id}
+		p.queued[id] = cfg
+		p.pending = append(p.pending, cfg)
+	}
+	return p
+}
+
+func TestWorkerPool_Reorder(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		position int
+		want     []string
+		found    bool
+	}{
+		{"to front", "c", 0, []string{"c", "a", "b", "d"}, true},
+		{"to back", "a", 3, []string{"b", "c", "d", "a"}, true},
+		{"clamped high", "b", 99, []string{"a", "c", "d", "b"}, true},
+		{"clamped low", "d", -5, []string{"d", "a", "b", "c"}, true},
+		{"unknown", "x", 0, []string{"a", "b", "c", "d"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newIdlePool("a", "b", "c", "d")
+			if found := p.Reorder(tt.id, tt.position); found != tt.found {
+				t.Fatalf("Reorder found = %v, want %v", found, tt.found)
+			}
+			got := p.PendingIDs()
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("PendingIDs = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerPool_SetMaxDownloads(t *testing.T) {
+	p := newIdlePool("a")
+
+	p.SetMaxDownloads(3)
+	if p.MaxDownloads() != 3 || p.workers != 3 {
+		t.Fatalf("Expected limit 3 with 3 workers, got %d with %d", p.MaxDownloads(), p.workers)
+	}
+
+	// Lowering the limit retires surplus workers as they pick up work
+	p.SetMaxDownloads(1)
+	if p.workers != 3 {
+		t.Fatalf("Workers should not be stopped eagerly, got %d", p.workers)
+	}
+	if !p.retireWorker(types.DownloadConfig{ID: "b"}) || !p.retireWorker(types.DownloadConfig{ID: "c"}) {
+		t.Fatal("Expected surplus workers to retire")
+	}
+	if p.retireWorker(types.DownloadConfig{ID: "d"}) {
+		t.Fatal("Worker within the limit must not retire")
+	}
+
+	// Work taken by a retiring worker goes back to the front of the queue
+	got := p.PendingIDs()
+	if len(got) != 3 || got[0] != "c" || got[1] != "b" || got[2] != "a" {
+		t.Errorf("PendingIDs = %v, want [c b a]", got)
+	}
+}
@@ -0,0 +1,121 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func setupRangeTestDB(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	if _, err := state.GetDB(); err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	t.Cleanup(state.CloseDB)
+	return tmpDir
+}
+
+func rangeTestPayload(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func runRangeDownload(t *testing.T, url, outputDir string, start, length int64) error {
+	t.Helper()
+	progState := types.NewProgressState(uuid.New().String(), 0)
+	cfg := types.DownloadConfig{
+		URL:         url,
+		OutputPath:  outputDir,
+		Filename:    "slice.bin",
+		ID:          progState.ID,
+		ProgressCh:  make(chan any, 1000),
+		State:       progState,
+		Runtime:     &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+		RangeStart:  start,
+		RangeLength: length,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return download.TUIDownload(ctx, &cfg)
+}
+
+func TestTUIDownload_PartialRange(t *testing.T) {
+	tmpDir := setupRangeTestDB(t)
+
+	payload := rangeTestPayload(1024 * 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		start  int64
+		length int64
+		want   []byte
+	}{
+		{"head", 0, 1000, payload[:1000]},
+		{"middle slice", 300000, 400000, payload[300000:700000]},
+		{"open-ended", 1000000, 0, payload[1000000:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(tmpDir, tt.name)
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := runRangeDownload(t, server.URL+"/data.bin", outputDir, tt.start, tt.length); err != nil {
+				t.Fatalf("TUIDownload failed: %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(outputDir, "slice.bin"))
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Output size = %d, want %d", len(got), len(tt.want))
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Error("Output content does not match requested range")
+			}
+		})
+	}
+}
+
+func TestTUIDownload_PartialRangeRequiresRangeSupport(t *testing.T) {
+	tmpDir := setupRangeTestDB(t)
+
+	payload := rangeTestPayload(64 * 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "65536")
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+
+	err := runRangeDownload(t, server.URL+"/data.bin", tmpDir, 0, 1024)
+	if !errors.Is(err, types.ErrRangeNotSupported) {
+		t.Fatalf("Expected ErrRangeNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package concurrent
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// diskReservation records a running download's working file so that space
+// checks for other downloads can account for the bytes it has yet to write.
+type diskReservation struct {
+	workingPath string
+	totalSize   int64
+}
+
+var (
+	reservationsMu sync.Mutex
+	reservations   = make(map[string]diskReservation) // Keyed by download ID
+)
+
+// outstandingBytes returns how much of a preallocated file still needs disk blocks.
+// Truncate creates sparse files on most filesystems, so free space only shrinks
+// as workers write; the unwritten remainder is still owed.
+func outstandingBytes(workingPath string, totalSize int64) int64 {
+	allocated, err := utils.AllocatedSize(workingPath)
+	if err != nil {
+		return totalSize
+	}
+	if allocated >= totalSize {
+		return 0
+	}
+	return totalSize - allocated
+}
+
+// reserveDiskSpace checks that the destination filesystem can hold the rest of this
+// download on top of what other active downloads on the same filesystem still need,
+// while keeping the configured free space floor. On success the download is registered
+// until the returned release func is called.
+func (d *ConcurrentDownloader) reserveDiskSpace(workingPath string, fileSize int64) (func(), error) {
+	release := func() {
+		reservationsMu.Lock()
+		delete(reservations, d.ID)
+		reservationsMu.Unlock()
+	}
+
+	reservationsMu.Lock()
+	defer reservationsMu.Unlock()
+
+	available, err := utils.AvailableDiskSpace(workingPath)
+	if err != nil {
+		// Don't block downloads on filesystems we can't inspect
+		utils.Debug("Disk space check skipped for %s: %v", workingPath, err)
+		reservations[d.ID] = diskReservation{workingPath: workingPath, totalSize: fileSize}
+		return release, nil
+	}
+
+	needed, reserved := requiredSpace(d.ID, workingPath, fileSize)
+	floor := d.Runtime.GetMinFreeDiskSpace()
+
+	if available < needed+reserved+floor {
+		return nil, fmt.Errorf("%w: need %s (+%s reserved by other downloads, %s kept free), only %s available",
+			types.ErrInsufficientDiskSpace,
+			utils.ConvertBytesToHumanReadable(needed),
+			utils.ConvertBytesToHumanReadable(reserved),
+			utils.ConvertBytesToHumanReadable(floor),
+			utils.ConvertBytesToHumanReadable(available))
+	}
+
+	reservations[d.ID] = diskReservation{workingPath: workingPath, totalSize: fileSize}
+	return release, nil
+}
+
+// requiredSpace returns the bytes download id still has to write to workingPath,
+// and those the other registered downloads on its filesystem still need.
+// Callers hold reservationsMu.
+func requiredSpace(id, workingPath string, fileSize int64) (needed, reserved int64) {
+	fsID, _ := utils.FilesystemID(workingPath)
+	for otherID, r := range reservations {
+		if otherID == id {
+			continue
+		}
+		if otherFS, err := utils.FilesystemID(r.workingPath); err != nil || otherFS != fsID {
+			continue
+		}
+		reserved += outstandingBytes(r.workingPath, r.totalSize)
+	}
+	return outstandingBytes(workingPath, fileSize), reserved
+}
+
+// Fits reports whether download id, with its working file at workingPath, would
+// pass the space check made when it starts, keeping floor bytes free
+func Fits(id, workingPath string, fileSize, floor int64) bool {
+	reservationsMu.Lock()
+	defer reservationsMu.Unlock()
+
+	available, err := utils.AvailableDiskSpace(workingPath)
+	if err != nil {
+		return true // Starting doesn't block on this either
+	}
+	needed, reserved := requiredSpace(id, workingPath, fileSize)
+	return available >= needed+reserved+floor
+}
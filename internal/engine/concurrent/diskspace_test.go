@@ -0,0 +1,156 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+func TestConcurrentDownloader_InsufficientDiskSpace(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(1 * types.MB)
+	server := testutil.NewMockServerT(t,
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(true),
+	)
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "nospace.bin")
+	state := types.NewProgressState("nospace-id", fileSize)
+	// A floor no real disk can satisfy
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: 4, MinFreeDiskSpace: 1 << 62}
+
+	downloader := NewConcurrentDownloader("nospace-id", nil, state, runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := downloader.Download(ctx, server.URL(), nil, nil, destPath, fileSize)
+	if !errors.Is(err, types.ErrInsufficientDiskSpace) {
+		t.Fatalf("Expected ErrInsufficientDiskSpace, got %v", err)
+	}
+
+	if _, statErr := os.Stat(destPath + types.IncompleteSuffix); !os.IsNotExist(statErr) {
+		t.Errorf("Expected working file to be removed, stat err = %v", statErr)
+	}
+}
+
+func TestWorker_DiskFullPausesWithoutRetrying(t *testing.T) {
+	// Writes to /dev/full always fail with ENOSPC
+	full, err := os.OpenFile("/dev/full", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("/dev/full unavailable: %v", err)
+	}
+	defer func() { _ = full.Close() }()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Range", "bytes 0-1023/1024")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(make([]byte, 1024))
+	}))
+	defer server.Close()
+
+	mirror := server.URL + "/mirror"
+	state := types.NewProgressState("diskfull-id", 1024)
+	state.SetMirrors([]types.MirrorStatus{{URL: server.URL, Active: true}, {URL: mirror, Active: true}})
+	runtime := &types.RuntimeConfig{MaxTaskRetries: 5}
+	d := NewConcurrentDownloader("diskfull-id", nil, state, runtime)
+
+	queue := NewTaskQueue()
+	queue.Push(types.Task{Offset: 0, Length: 1024})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.SetCancelFunc(cancel)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.worker(ctx, 0, []string{server.URL, mirror}, full, queue, 1024, time.Now(), server.Client())
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		queue.Close()
+		t.Fatal("worker kept retrying the task on a full disk")
+	}
+	if !errors.Is(err, types.ErrInsufficientDiskSpace) {
+		t.Fatalf("worker error = %v, want ErrInsufficientDiskSpace", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server got %d requests, want 1 (no retries)", got)
+	}
+	for _, m := range state.GetMirrors() {
+		if m.Error {
+			t.Errorf("mirror %s marked as failed for a full disk", m.URL)
+		}
+	}
+	if !state.IsPaused() {
+		t.Error("Expected download to be paused")
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected pause to cancel the other workers")
+	}
+
+	d.activeMu.Lock()
+	_, kept := d.activeTasks[0]
+	d.activeMu.Unlock()
+	if !kept {
+		t.Error("Expected the task to be left for the pause handler to save")
+	}
+}
+
+func TestReserveDiskSpace_CountsOtherDownloads(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	available, err := utils.AvailableDiskSpace(tmpDir)
+	if err != nil {
+		t.Skipf("Cannot read free space: %v", err)
+	}
+	if available < 128*types.MB {
+		t.Skipf("Not enough free space for test: %d bytes", available)
+	}
+
+	// Another download on the same filesystem still owes half the free space
+	otherPath := filepath.Join(tmpDir, "other.bin.surge")
+	if err := os.WriteFile(otherPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other := &ConcurrentDownloader{ID: "other-id", Runtime: &types.RuntimeConfig{}}
+	releaseOther, err := other.reserveDiskSpace(otherPath, available/2)
+	if err != nil {
+		t.Fatalf("First reservation failed: %v", err)
+	}
+	defer releaseOther()
+
+	d := &ConcurrentDownloader{ID: "this-id", Runtime: &types.RuntimeConfig{}}
+	workingPath := filepath.Join(tmpDir, "this.bin.surge")
+	if err := os.WriteFile(workingPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.reserveDiskSpace(workingPath, available/2+64*types.MB); !errors.Is(err, types.ErrInsufficientDiskSpace) {
+		t.Fatalf("Expected ErrInsufficientDiskSpace while other download holds the space, got %v", err)
+	}
+
+	releaseOther()
+
+	release, err := d.reserveDiskSpace(workingPath, 64*types.MB)
+	if err != nil {
+		t.Fatalf("Expected reservation to succeed after release, got %v", err)
+	}
+	release()
+}
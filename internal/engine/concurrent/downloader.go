@@ -3,6 +3,7 @@ package concurrent
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -13,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/metrics"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
@@ -30,6 +32,27 @@ type ConcurrentDownloader struct {
 	Runtime      *types.RuntimeConfig
 	bufPool      sync.Pool
 	Headers      map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
+
+	// Partial downloads: byte 0 of the output maps to RangeStart of the remote resource.
+	// RangeLength is the requested span (0 = through the end), persisted for resume.
+	RangeStart  int64
+	RangeLength int64
+
+	// Per-download options, only persisted so a resume keeps them. The
+	// connection cap is already applied through Runtime.
+	Checksum       string
+	MaxConnections int
+	IfModified     bool
+
+	// Delta downloads: when set, only these spans are fetched. The rest of the
+	// working file must already hold its final contents (e.g. copied from a seed).
+	// DeltaControl is the control file's location, persisted so a resume can verify.
+	Ranges       []types.Task
+	DeltaControl string
+
+	// Verify, when set, checks the finished working file before it is renamed
+	// into place. On failure the working file is removed and destPath is untouched.
+	Verify func(path string) error
 }
 
 // NewConcurrentDownloader creates a new concurrent downloader with all required parameters
@@ -98,6 +121,7 @@ func (d *ConcurrentDownloader) getInitialConnections(fileSize int64) int {
 
 // ReportMirrorError marks a mirror as having an error in the state
 func (d *ConcurrentDownloader) ReportMirrorError(url string) {
+	metrics.MirrorFailures.Add(1)
 	if d.State == nil {
 		return
 	}
@@ -162,6 +186,14 @@ func (d *ConcurrentDownloader) determineChunkSize(fileSize int64, numConns int)
 	return d.calculateChunkSize(fileSize, numConns)
 }
 
+// PlanConnections returns the connection count and chunk size a download of
+// fileSize bytes starts with under runtime
+func PlanConnections(runtime *types.RuntimeConfig, fileSize int64) (int, int64) {
+	d := NewConcurrentDownloader("", nil, nil, runtime)
+	numConns := d.getInitialConnections(fileSize)
+	return numConns, d.determineChunkSize(fileSize, numConns)
+}
+
 // createTasks generates initial task queue from file size and chunk size
 func createTasks(fileSize, chunkSize int64) []types.Task {
 	if chunkSize <= 0 {
@@ -182,6 +214,30 @@ func createTasks(fileSize, chunkSize int64) []types.Task {
 	return tasks
 }
 
+// createRangeTasks splits each span into tasks of at most chunkSize
+func createRangeTasks(ranges []types.Task, chunkSize int64) []types.Task {
+	if chunkSize <= 0 {
+		return nil
+	}
+
+	tasks := make([]types.Task, 0, len(ranges))
+	for _, r := range ranges {
+		for _, t := range createTasks(r.Length, chunkSize) {
+			tasks = append(tasks, types.Task{Offset: r.Offset + t.Offset, Length: t.Length})
+		}
+	}
+	return tasks
+}
+
+// sumTaskLengths returns the total number of bytes covered by tasks
+func sumTaskLengths(tasks []types.Task) int64 {
+	var total int64
+	for _, t := range tasks {
+		total += t.Length
+	}
+	return total
+}
+
 // newConcurrentClient creates an http.Client tuned for concurrent downloads
 func (d *ConcurrentDownloader) newConcurrentClient(numConns int) *http.Client {
 	// Ensure we have enough connections per host
@@ -328,6 +384,9 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	}()
 
 	tasks := createTasks(fileSize, chunkSize)
+	if d.Ranges != nil {
+		tasks = createRangeTasks(d.Ranges, chunkSize)
+	}
 
 	// Check for saved state BEFORE truncating (resume case)
 	savedState, err := state.LoadState(rawurl, destPath)
@@ -354,7 +413,21 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			}
 		}
 		utils.Debug("Resuming from saved state: %d tasks, %d bytes downloaded", len(tasks), savedState.Downloaded)
-	} else {
+	}
+
+	// Make sure the rest of the file fits before committing to it, so a full disk
+	// surfaces as a clear error instead of a worker write failure mid-download
+	releaseSpace, err := d.reserveDiskSpace(workingPath, fileSize)
+	if err != nil {
+		if !isResume {
+			_ = outFile.Close()
+			_ = os.Remove(workingPath)
+		}
+		return err
+	}
+	defer releaseSpace()
+
+	if !isResume {
 		// Fresh download: preallocate file and create new tasks
 		if err := outFile.Truncate(fileSize); err != nil {
 			return fmt.Errorf("failed to preallocate file: %w", err)
@@ -362,6 +435,11 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		// Robustness: ensure state counter starts at 0 for fresh download
 		if d.State != nil {
 			d.State.Downloaded.Store(0)
+			if d.Ranges != nil {
+				// Bytes outside the requested spans are already in place
+				d.State.Downloaded.Store(fileSize - sumTaskLengths(tasks))
+				d.State.RecalculateProgress(tasks)
+			}
 			d.State.SyncSessionStart()
 		}
 	}
@@ -386,6 +464,13 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			case <-balancerCtx.Done():
 				return
 			case <-ticker.C:
+				// Move work a stream reader is blocked on to the front of the queue
+				if d.State != nil {
+					if offset, ok := d.State.TakeStreamOffset(); ok {
+						queue.Prioritize(offset)
+					}
+				}
+
 				// Aggressively fill idle workers
 				// Continue splitting/stealing as long as we have idle workers and are making progress
 				for queue.IdleWorkers() > 0 {
@@ -557,8 +642,15 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			Filename:        filepath.Base(destPath),
 			Elapsed:         totalElapsed.Nanoseconds(),
 			Mirrors:         candidateMirrors,
+			Headers:         d.Headers,
 			ChunkBitmap:     chunkBitmap,
 			ActualChunkSize: actualChunkSize,
+			RangeStart:      d.RangeStart,
+			RangeLength:     d.RangeLength,
+			Checksum:        d.Checksum,
+			MaxConnections:  d.MaxConnections,
+			IfModified:      d.IfModified,
+			DeltaControl:    d.DeltaControl,
 		}
 		if err := state.SaveState(d.URL, destPath, s); err != nil {
 			utils.Debug("Failed to save pause state: %v", err)
@@ -566,6 +658,9 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 
 		utils.Debug("Download paused, state saved (Downloaded=%d, RemainingTasks=%d, RemainingBytes=%d)",
 			computedDownloaded, len(remainingTasks), remainingBytes)
+		if errors.Is(downloadErr, types.ErrInsufficientDiskSpace) {
+			return fmt.Errorf("%w: %w", types.ErrPaused, downloadErr) // Paused by a full disk
+		}
 		return types.ErrPaused // Signal valid pause to caller
 	}
 
@@ -587,6 +682,14 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	// Close file before renaming
 	_ = outFile.Close()
 
+	if d.Verify != nil {
+		if err := d.Verify(workingPath); err != nil {
+			_ = os.Remove(workingPath)
+			_ = state.DeleteState(d.ID, d.URL, destPath)
+			return err
+		}
+	}
+
 	// Rename from .surge to final destination
 	if err := os.Rename(workingPath, destPath); err != nil {
 		// Check for race condition: did someone else already rename it?
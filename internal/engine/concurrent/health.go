@@ -4,6 +4,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/metrics"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -75,6 +76,7 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 					workerID, timeSinceData.Truncate(time.Millisecond))
 				if active.Cancel != nil {
 					active.Cancel()
+					metrics.HealthRestarts.Add(1)
 				}
 				continue // Already cancelled, skip speed check
 			}
@@ -92,6 +94,7 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 					workerID, workerSpeed/1024, meanSpeed/1024)
 				if active.Cancel != nil {
 					active.Cancel()
+					metrics.HealthRestarts.Add(1)
 				}
 			}
 		}
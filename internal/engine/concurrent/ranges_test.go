@@ -0,0 +1,119 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestCreateRangeTasks(t *testing.T) {
+	ranges := []types.Task{{Offset: 100, Length: 250}, {Offset: 1000, Length: 50}}
+	tasks := createRangeTasks(ranges, 100)
+
+	want := []types.Task{
+		{Offset: 100, Length: 100},
+		{Offset: 200, Length: 100},
+		{Offset: 300, Length: 50},
+		{Offset: 1000, Length: 50},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("Expected %d tasks, got %d: %v", len(want), len(tasks), tasks)
+	}
+	for i := range want {
+		if tasks[i] != want[i] {
+			t.Errorf("Task %d = %+v, want %+v", i, tasks[i], want[i])
+		}
+	}
+
+	if got := sumTaskLengths(tasks); got != 300 {
+		t.Errorf("sumTaskLengths = %d, want 300", got)
+	}
+}
+
+func TestConcurrentDownloader_RangesOnlyFetchesSpans(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(512 * types.KB)
+	payload := make([]byte, fileSize)
+	for i := range payload {
+		payload[i] = byte(i % 253)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	// Prefill the working file as a seed would, leaving a hole to fetch
+	destPath := filepath.Join(tmpDir, "delta.bin")
+	hole := types.Task{Offset: 100 * types.KB, Length: 64 * types.KB}
+	prefilled := append([]byte{}, payload...)
+	clear(prefilled[hole.Offset : hole.Offset+hole.Length])
+	if err := os.WriteFile(destPath+types.IncompleteSuffix, prefilled, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	progState := types.NewProgressState("ranges-id", fileSize)
+	downloader := NewConcurrentDownloader("ranges-id", nil, progState, &types.RuntimeConfig{MaxConnectionsPerHost: 2})
+	downloader.Ranges = []types.Task{hole}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	got, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("Downloaded file does not match payload")
+	}
+	if progState.Downloaded.Load() < fileSize {
+		t.Errorf("Downloaded = %d, want %d", progState.Downloaded.Load(), fileSize)
+	}
+}
+
+func TestConcurrentDownloader_EmptyRangesCompletes(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(64 * types.KB)
+	payload := bytes.Repeat([]byte{7}, int(fileSize))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request for %s", r.Header.Get("Range"))
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "full-seed.bin")
+	if err := os.WriteFile(destPath+types.IncompleteSuffix, payload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	downloader := NewConcurrentDownloader("empty-ranges", nil, types.NewProgressState("empty-ranges", fileSize), nil)
+	downloader.Ranges = []types.Task{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	got, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("Seeded file was modified")
+	}
+}
@@ -1,6 +1,7 @@
 package concurrent
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -86,6 +87,37 @@ func (q *TaskQueue) IdleWorkers() int64 {
 	return atomic.LoadInt64(&q.idleWorkers)
 }
 
+// Prioritize reorders pending tasks so that work at or after offset comes first,
+// in offset order. A task straddling offset is split so its tail is fetched first.
+// Used to serve stream readers that seek to a range that isn't downloaded yet.
+func (q *TaskQueue) Prioritize(offset int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head >= len(q.tasks) {
+		return
+	}
+
+	var ahead, behind []types.Task
+	for _, t := range q.tasks[q.head:] {
+		end := t.Offset + t.Length
+		switch {
+		case end <= offset:
+			behind = append(behind, t)
+		case t.Offset < offset:
+			behind = append(behind, types.Task{Offset: t.Offset, Length: offset - t.Offset})
+			ahead = append(ahead, types.Task{Offset: offset, Length: end - offset})
+		default:
+			ahead = append(ahead, t)
+		}
+	}
+
+	sort.Slice(ahead, func(i, j int) bool { return ahead[i].Offset < ahead[j].Offset })
+
+	q.tasks = append(ahead, behind...)
+	q.head = 0
+}
+
 // DrainRemaining returns all remaining tasks in the queue (used for pause/resume)
 func (q *TaskQueue) DrainRemaining() []types.Task {
 	q.mu.Lock()
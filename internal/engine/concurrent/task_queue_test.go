@@ -110,3 +110,46 @@ func TestAlignedSplitSize(t *testing.T) {
 		}
 	}
 }
+
+func TestTaskQueue_Prioritize(t *testing.T) {
+	q := NewTaskQueue()
+	q.PushMultiple([]types.Task{
+		{Offset: 0, Length: 100},
+		{Offset: 100, Length: 100},
+		{Offset: 200, Length: 100},
+		{Offset: 300, Length: 100},
+	})
+
+	// Offset 250 falls inside the third task, which should be split
+	q.Prioritize(250)
+
+	want := []types.Task{
+		{Offset: 250, Length: 50},
+		{Offset: 300, Length: 100},
+		{Offset: 0, Length: 100},
+		{Offset: 100, Length: 100},
+		{Offset: 200, Length: 50},
+	}
+
+	if q.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", q.Len(), len(want))
+	}
+	for i, w := range want {
+		got, ok := q.Pop()
+		if !ok {
+			t.Fatalf("Pop %d returned false", i)
+		}
+		if got != w {
+			t.Errorf("Pop %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestTaskQueue_Prioritize_Empty(t *testing.T) {
+	q := NewTaskQueue()
+	q.Prioritize(100)
+
+	if q.Len() != 0 {
+		t.Errorf("Len = %d, want 0", q.Len())
+	}
+}
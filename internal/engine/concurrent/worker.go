@@ -2,13 +2,16 @@ package concurrent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/metrics"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
 )
@@ -43,6 +46,7 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 		maxRetries := d.Runtime.GetMaxTaskRetries()
 		for attempt := 0; attempt < maxRetries; attempt++ {
 			if attempt > 0 {
+				metrics.TaskRetries.Add(1)
 
 				if len(mirrors) == 1 {
 					time.Sleep(time.Duration(1<<attempt) * types.RetryBaseDelay) // Exponential backoff incase of failure
@@ -103,6 +107,18 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 				return ctx.Err()
 			}
 
+			// A full disk won't recover by retrying or switching mirrors: pause the
+			// download, leaving the task for the pause handler to save, as the disk
+			// monitor would
+			if errors.Is(lastErr, types.ErrInsufficientDiskSpace) {
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+					d.State.SetPausing(true)
+					d.State.Pause()
+				}
+				return lastErr
+			}
+
 			// Check if TASK context was cancelled by Health Monitor (not by us calling taskCancel)
 			// but parent context is still fine
 			if wasExternallyCancelled && lastErr != nil {
@@ -195,7 +211,12 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 		req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
 	}
 	// Range header is always set for partial downloads (overrides any browser Range header)
-	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", task.Offset, task.Offset+task.Length-1))
+	// Task offsets are relative to the output file; shift them for partial downloads
+	remoteOffset := d.RangeStart + task.Offset
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", remoteOffset, remoteOffset+task.Length-1))
+
+	metrics.ActiveConnections.Add(1)
+	defer metrics.ActiveConnections.Add(-1)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -206,6 +227,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 			utils.Debug("Error closing response body: %v", err)
 		}
 	}()
+	hostBytes := metrics.HostCounter(req.URL.Host)
 
 	// Handle rate limiting explicitly
 	if resp.StatusCode == http.StatusTooManyRequests {
@@ -216,7 +238,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 	if resp.StatusCode == http.StatusOK {
 		// Valid only if we requested the full file
 		// If we wanted a partial range but got the whole file (200), that's an error because we can't handle the full stream at a non-zero offset
-		if task.Offset != 0 || task.Length != totalSize {
+		if d.RangeStart != 0 || task.Offset != 0 || task.Length != totalSize {
 			return fmt.Errorf("server indicated success (200) but ignored range request (expected 206)")
 		}
 	} else if resp.StatusCode != http.StatusPartialContent {
@@ -238,6 +260,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 			// Update Downloaded Counter (Atomic)
 			d.State.Downloaded.Add(pendingBytes)
+			hostBytes.Add(pendingBytes)
 
 			pendingBytes = 0
 			pendingStart = -1
@@ -303,6 +326,9 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 			_, writeErr := file.WriteAt(buf[:readSoFar], offset)
 			if writeErr != nil {
+				if errors.Is(writeErr, syscall.ENOSPC) {
+					return fmt.Errorf("%w: %v", types.ErrInsufficientDiskSpace, writeErr)
+				}
 				return fmt.Errorf("write error: %w", writeErr)
 			}
 
@@ -423,6 +449,7 @@ func (d *ConcurrentDownloader) StealWork(queue *TaskQueue) bool {
 	}
 
 	queue.Push(stolenTask)
+	metrics.StolenTasks.Add(1)
 	utils.Debug("Balancer: stole %s from worker %d (new range: %d-%d)",
 		utils.ConvertBytesToHumanReadable(stolenTask.Length), bestID, stolenTask.Offset, stolenTask.Offset+stolenTask.Length)
 
@@ -480,6 +507,7 @@ func (d *ConcurrentDownloader) HedgeWork(queue *TaskQueue) bool {
 	}
 
 	queue.Push(hedgedTask)
+	metrics.HedgedTasks.Add(1)
 	utils.Debug("Balancer: hedged %s (range: %d-%d) — idle worker will race on fresh connection",
 		utils.ConvertBytesToHumanReadable(hedgedTask.Length), hedgedTask.Offset, hedgedTask.Offset+hedgedTask.Length)
 
@@ -0,0 +1,275 @@
+// Package delta implements zsync-style delta updates: blocks of the target file
+// that already exist in a local seed are copied, and only the rest is downloaded.
+package delta
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+var (
+	ErrInvalidControl   = errors.New("invalid delta control file")
+	ErrChecksumMismatch = errors.New("file checksum does not match control file")
+)
+
+// maxControlSize bounds how much of a control file is read
+const maxControlSize = 512 * 1024 * 1024
+
+// Block holds the checksums of one target block
+type Block struct {
+	Weak   uint32 // zsync rolling checksum, truncated to RsumBytes (zsync only)
+	Strong []byte // Truncated MD4 (zsync) or SHA-256 (block hash list)
+}
+
+// Control describes the target file as a list of block checksums.
+//
+// Two formats are accepted: a zsync control file (.zsync), whose rolling checksums
+// let blocks be found at any offset of a seed, and a block hash list, a plain
+// header followed by one hex SHA-256 per block, matched at block-aligned offsets:
+//
+//	Blocksize: 1048576
+//	Length: 4294967296
+//	SHA-256: <hex digest of the whole file, optional>
+//
+//	<hex SHA-256 of block 0>
+//	<hex SHA-256 of block 1>
+type Control struct {
+	Filename      string
+	URLs          []string
+	BlockSize     int64
+	Length        int64
+	SeqMatches    int
+	RsumBytes     int
+	ChecksumBytes int
+	SHA1          string
+	SHA256        string
+	Blocks        []Block
+
+	rolling bool // zsync format: weak checksums available
+}
+
+// NumBlocks returns the number of blocks covering Length
+func (c *Control) NumBlocks() int {
+	return int((c.Length + c.BlockSize - 1) / c.BlockSize)
+}
+
+// LoadControl reads a control file from an http(s) URL or a local path
+func LoadControl(ctx context.Context, source string, headers map[string]string) (*Control, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open control file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		return ParseControl(f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, val := range headers {
+		if key != "Range" {
+			req.Header.Set(key, val)
+		}
+	}
+
+	client := &http.Client{Timeout: types.ProbeTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch control file: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch control file: server returned %d", resp.StatusCode)
+	}
+	return ParseControl(resp.Body)
+}
+
+// ParseControl parses a zsync control file or a block hash list
+func ParseControl(r io.Reader) (*Control, error) {
+	br := bufio.NewReader(io.LimitReader(r, maxControlSize))
+	c := &Control{SeqMatches: 1, RsumBytes: 4, ChecksumBytes: 16}
+
+	// Header: "Key: Value" lines up to the first blank line
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, fmt.Errorf("%w: truncated header", ErrInvalidControl)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed header line %q", ErrInvalidControl, line)
+		}
+		if err := c.setHeader(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.BlockSize <= 0 || c.Length < 0 {
+		return nil, fmt.Errorf("%w: missing Blocksize or Length", ErrInvalidControl)
+	}
+
+	var err error
+	if c.rolling {
+		err = c.readZsyncBlocks(br)
+	} else {
+		err = c.readHashList(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Control) setHeader(key, value string) error {
+	var err error
+	switch strings.ToLower(key) {
+	case "zsync":
+		c.rolling = true
+	case "filename":
+		c.Filename = value
+	case "url":
+		c.URLs = append(c.URLs, value)
+	case "blocksize":
+		c.BlockSize, err = strconv.ParseInt(value, 10, 64)
+	case "length":
+		c.Length, err = strconv.ParseInt(value, 10, 64)
+	case "sha-1":
+		c.SHA1 = strings.ToLower(value)
+	case "sha-256":
+		c.SHA256 = strings.ToLower(value)
+	case "hash-lengths":
+		parts := strings.Split(value, ",")
+		if len(parts) != 3 {
+			return fmt.Errorf("%w: bad Hash-Lengths %q", ErrInvalidControl, value)
+		}
+		lengths := make([]int, 3)
+		for i, p := range parts {
+			if lengths[i], err = strconv.Atoi(strings.TrimSpace(p)); err != nil {
+				break
+			}
+		}
+		c.SeqMatches, c.RsumBytes, c.ChecksumBytes = lengths[0], lengths[1], lengths[2]
+		if c.SeqMatches < 1 || c.SeqMatches > 2 || c.RsumBytes < 1 || c.RsumBytes > 4 || c.ChecksumBytes < 3 || c.ChecksumBytes > 16 {
+			return fmt.Errorf("%w: bad Hash-Lengths %q", ErrInvalidControl, value)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: bad %s header: %v", ErrInvalidControl, key, err)
+	}
+	return nil
+}
+
+// readZsyncBlocks reads the binary checksum table that follows a zsync header
+func (c *Control) readZsyncBlocks(r io.Reader) error {
+	n := c.NumBlocks()
+	entry := make([]byte, c.RsumBytes+c.ChecksumBytes)
+	c.Blocks = make([]Block, n)
+	for i := 0; i < n; i++ {
+		if _, err := io.ReadFull(r, entry); err != nil {
+			return fmt.Errorf("%w: checksum table truncated at block %d", ErrInvalidControl, i)
+		}
+		var weak uint32
+		for _, b := range entry[:c.RsumBytes] {
+			weak = weak<<8 | uint32(b)
+		}
+		c.Blocks[i] = Block{
+			Weak:   weak,
+			Strong: append([]byte(nil), entry[c.RsumBytes:]...),
+		}
+	}
+	return nil
+}
+
+// readHashList reads one hex SHA-256 digest per line
+func (c *Control) readHashList(r io.Reader) error {
+	n := c.NumBlocks()
+	c.Blocks = make([]Block, 0, n)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sum, err := hex.DecodeString(line)
+		if err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("%w: bad block hash %q", ErrInvalidControl, line)
+		}
+		c.Blocks = append(c.Blocks, Block{Strong: sum})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidControl, err)
+	}
+	if len(c.Blocks) != n {
+		return fmt.Errorf("%w: expected %d block hashes, got %d", ErrInvalidControl, n, len(c.Blocks))
+	}
+	return nil
+}
+
+// Verify checks the whole-file digest of path, if the control file carries one
+func (c *Control) Verify(path string) error {
+	var h hash.Hash
+	var want string
+	switch {
+	case c.SHA256 != "":
+		h, want = sha256.New(), c.SHA256
+	case c.SHA1 != "":
+		h, want = sha1.New(), c.SHA1
+	default:
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for verification: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, got, want)
+	}
+	return nil
+}
+
+// weakSum computes the zsync rolling checksum of a block
+func weakSum(block []byte) (a, b uint16) {
+	n := uint16(len(block))
+	for i, x := range block {
+		a += uint16(x)
+		b += (n - uint16(i)) * uint16(x)
+	}
+	return a, b
+}
+
+// strongSum computes the strong checksum of a block as stored in the control file
+func (c *Control) strongSum(block []byte) []byte {
+	if c.rolling {
+		sum := md4Sum(block)
+		return sum[:c.ChecksumBytes]
+	}
+	sum := sha256.Sum256(block)
+	return sum[:]
+}
@@ -0,0 +1,192 @@
+package delta
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// makeZsync builds a zsync control file for data the way zsyncmake does
+func makeZsync(data []byte, blockSize, seqMatches, rsumBytes, checksumBytes int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "zsync: 0.6.2\nFilename: target.img\nBlocksize: %d\nLength: %d\n", blockSize, len(data))
+	fmt.Fprintf(&buf, "Hash-Lengths: %d,%d,%d\nURL: target.img\nSHA-1: %x\n\n", seqMatches, rsumBytes, checksumBytes, sha1.Sum(data))
+
+	block := make([]byte, blockSize)
+	for off := 0; off < len(data); off += blockSize {
+		clear(block)
+		copy(block, data[off:])
+		a, b := weakSum(block)
+		var rsum [4]byte
+		binary.BigEndian.PutUint16(rsum[0:], a)
+		binary.BigEndian.PutUint16(rsum[2:], b)
+		buf.Write(rsum[4-rsumBytes:])
+		sum := md4Sum(block)
+		buf.Write(sum[:checksumBytes])
+	}
+	return buf.Bytes()
+}
+
+// makeHashList builds a block hash list control file for data
+func makeHashList(data []byte, blockSize int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Blocksize: %d\nLength: %d\nSHA-256: %x\n\n", blockSize, len(data), sha256.Sum256(data))
+	for off := 0; off < len(data); off += blockSize {
+		sum := sha256.Sum256(data[off:min(off+blockSize, len(data))])
+		fmt.Fprintln(&buf, hex.EncodeToString(sum[:]))
+	}
+	return buf.Bytes()
+}
+
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// buildSeedAndTarget returns an "old" image and a "new" image that shares most
+// of its content, shifted by an insertion so matches are not block-aligned
+func buildSeedAndTarget() (seed, target []byte) {
+	seed = randomBytes(1, 200*1024+123)
+	target = append([]byte{}, seed[:50000]...)
+	target = append(target, randomBytes(2, 777)...) // Inserted bytes shift the rest
+	target = append(target, seed[50000:150000]...)
+	target = append(target, randomBytes(3, 10000)...) // Replaced region
+	target = append(target, seed[160000:]...)
+	return seed, target
+}
+
+// assemble applies plan to a fresh file and fills the missing ranges from target
+func assemble(t *testing.T, plan *Plan, target []byte, path string) {
+	t.Helper()
+	if err := plan.Apply(path); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	for _, task := range plan.Missing() {
+		if _, err := f.WriteAt(target[task.Offset:task.Offset+task.Length], task.Offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDelta_Zsync(t *testing.T) {
+	seed, target := buildSeedAndTarget()
+	dir := t.TempDir()
+	seedPath := filepath.Join(dir, "old.img")
+	if err := os.WriteFile(seedPath, seed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hl := range [][3]int{{1, 4, 16}, {2, 2, 5}} {
+		t.Run(fmt.Sprintf("hash-lengths %v", hl), func(t *testing.T) {
+			c, err := ParseControl(bytes.NewReader(makeZsync(target, 2048, hl[0], hl[1], hl[2])))
+			if err != nil {
+				t.Fatalf("ParseControl failed: %v", err)
+			}
+			if c.Length != int64(len(target)) || c.BlockSize != 2048 || c.Filename != "target.img" {
+				t.Errorf("Unexpected header: %+v", c)
+			}
+
+			plan, err := Match(c, []string{seedPath})
+			if err != nil {
+				t.Fatalf("Match failed: %v", err)
+			}
+			// Everything except the inserted/replaced regions (plus block rounding) is reused
+			if plan.Reused < int64(len(target))-20*1024 {
+				t.Errorf("Reused only %d of %d bytes", plan.Reused, len(target))
+			}
+
+			out := filepath.Join(dir, fmt.Sprintf("new-%d.img", hl[0]))
+			assemble(t, plan, target, out)
+			if err := c.Verify(out); err != nil {
+				t.Errorf("Verify failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestDelta_HashListWithSeedDirectory(t *testing.T) {
+	// Block hash lists match at aligned offsets, so modify the target in place
+	seed := randomBytes(4, 64*1024+100)
+	target := append([]byte{}, seed...)
+	copy(target[8192:], randomBytes(5, 4096))
+
+	dir := t.TempDir()
+	seedDir := filepath.Join(dir, "seeds")
+	if err := os.MkdirAll(seedDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(seedDir, "unrelated.bin"), randomBytes(6, 5000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(seedDir, "old.img"), seed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseControl(bytes.NewReader(makeHashList(target, 4096)))
+	if err != nil {
+		t.Fatalf("ParseControl failed: %v", err)
+	}
+
+	plan, err := Match(c, []string{seedDir})
+	if err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+
+	missing := plan.Missing()
+	want := []types.Task{{Offset: 8192, Length: 4096}}
+	if len(missing) != 1 || missing[0] != want[0] {
+		t.Errorf("Missing = %v, want %v", missing, want)
+	}
+
+	out := filepath.Join(dir, "new.img")
+	assemble(t, plan, target, out)
+	if err := c.Verify(out); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+
+	// Corrupt the output: verification must fail
+	if err := os.WriteFile(out, seed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Verify(out); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestParseControl_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no blocksize", "Length: 10\n\n"},
+		{"truncated zsync table", "zsync: 0.6.2\nBlocksize: 4\nLength: 8\nHash-Lengths: 1,4,16\n\nshort"},
+		{"bad hash lengths", "zsync: 0.6.2\nBlocksize: 4\nLength: 8\nHash-Lengths: 1,9,16\n\n"},
+		{"wrong hash count", "Blocksize: 4\nLength: 8\n\n" + strings.Repeat("00", 32) + "\n"},
+		{"malformed header", "Blocksize 4\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseControl(strings.NewReader(tt.input)); !errors.Is(err, ErrInvalidControl) {
+				t.Errorf("Expected ErrInvalidControl, got %v", err)
+			}
+		})
+	}
+}
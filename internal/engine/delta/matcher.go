@@ -0,0 +1,300 @@
+package delta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// scanWindow is how much of a seed is read per pass
+const scanWindow = 8 * 1024 * 1024
+
+// blockSource locates a target block inside a seed file
+type blockSource struct {
+	seed   string
+	offset int64
+}
+
+// Plan records which target blocks can be copied from seeds
+type Plan struct {
+	control *Control
+	sources []*blockSource // Indexed by target block; nil = must be downloaded
+	Reused  int64          // Bytes of the target available locally
+}
+
+// Match scans the seed paths (files, or directories of candidate files) for
+// blocks of the target described by c
+func Match(c *Control, seeds []string) (*Plan, error) {
+	p := &Plan{control: c, sources: make([]*blockSource, c.NumBlocks())}
+
+	files, err := expandSeeds(seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	m := newMatcher(c, p)
+	for _, path := range files {
+		if m.remaining == 0 {
+			break
+		}
+		if err := m.scan(path); err != nil {
+			return nil, fmt.Errorf("failed to scan seed %s: %w", path, err)
+		}
+	}
+
+	for i, src := range p.sources {
+		if src != nil {
+			p.Reused += p.blockLength(i)
+		}
+	}
+	utils.Debug("delta: %d/%d blocks found in %d seeds, %d bytes reused", len(p.sources)-m.remaining, len(p.sources), len(files), p.Reused)
+	return p, nil
+}
+
+// expandSeeds resolves seed arguments into regular files; directories contribute
+// their top-level files
+func expandSeeds(seeds []string) ([]string, error) {
+	var files []string
+	for _, seed := range seeds {
+		info, err := os.Stat(seed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, seed)
+			continue
+		}
+
+		entries, err := os.ReadDir(seed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seed directory: %w", err)
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				files = append(files, filepath.Join(seed, e.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// blockLength returns the real (unpadded) length of target block i
+func (p *Plan) blockLength(i int) int64 {
+	bs := p.control.BlockSize
+	return min(bs, p.control.Length-int64(i)*bs)
+}
+
+// Missing returns the target ranges that must be downloaded, merging adjacent blocks.
+// The result is never nil, so an empty list means the seeds cover the whole file.
+func (p *Plan) Missing() []types.Task {
+	tasks := []types.Task{}
+	bs := p.control.BlockSize
+	for i, src := range p.sources {
+		if src != nil {
+			continue
+		}
+		offset := int64(i) * bs
+		length := p.blockLength(i)
+		if n := len(tasks); n > 0 && tasks[n-1].Offset+tasks[n-1].Length == offset {
+			tasks[n-1].Length += length
+			continue
+		}
+		tasks = append(tasks, types.Task{Offset: offset, Length: length})
+	}
+	return tasks
+}
+
+// Apply creates path at the target length and copies every matched block into place
+func (p *Plan) Apply(path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+
+	if err := out.Truncate(p.control.Length); err != nil {
+		return fmt.Errorf("failed to preallocate file: %w", err)
+	}
+
+	seeds := make(map[string]*os.File)
+	defer func() {
+		for _, f := range seeds {
+			_ = f.Close()
+		}
+	}()
+
+	buf := make([]byte, p.control.BlockSize)
+	for i, src := range p.sources {
+		if src == nil {
+			continue
+		}
+		f, ok := seeds[src.seed]
+		if !ok {
+			if f, err = os.Open(src.seed); err != nil {
+				return fmt.Errorf("failed to open seed: %w", err)
+			}
+			seeds[src.seed] = f
+		}
+
+		block := buf[:p.blockLength(i)]
+		// Blocks matched against the zero padding past a seed's end read short
+		n, err := f.ReadAt(block, src.offset)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read seed: %w", err)
+		}
+		clear(block[n:])
+
+		if _, err := out.WriteAt(block, int64(i)*p.control.BlockSize); err != nil {
+			return fmt.Errorf("failed to write block: %w", err)
+		}
+	}
+	return out.Sync()
+}
+
+// matcher finds target blocks within seed files
+type matcher struct {
+	control   *Control
+	plan      *Plan
+	remaining int
+	weak      map[uint32][]int // zsync: truncated rolling checksum -> block indices
+	strong    map[string][]int // Block hash list: SHA-256 -> block indices
+	weakMask  uint32
+}
+
+func newMatcher(c *Control, p *Plan) *matcher {
+	m := &matcher{control: c, plan: p, remaining: len(p.sources)}
+	if c.rolling {
+		m.weak = make(map[uint32][]int, len(c.Blocks))
+		m.weakMask = uint32(1<<(8*c.RsumBytes) - 1)
+		for i, b := range c.Blocks {
+			m.weak[b.Weak] = append(m.weak[b.Weak], i)
+		}
+	} else {
+		m.strong = make(map[string][]int, len(c.Blocks))
+		for i, b := range c.Blocks {
+			m.strong[string(b.Strong)] = append(m.strong[string(b.Strong)], i)
+		}
+	}
+	return m
+}
+
+func (m *matcher) scan(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if m.control.rolling {
+		return m.scanRolling(f, path, info.Size())
+	}
+	return m.scanAligned(f, path, info.Size())
+}
+
+// scanAligned hashes each block-aligned region of the seed
+func (m *matcher) scanAligned(f *os.File, path string, size int64) error {
+	bs := m.control.BlockSize
+	buf := make([]byte, bs)
+	for offset := int64(0); offset < size && m.remaining > 0; offset += bs {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		// Only the final target block may be short
+		sum := m.control.strongSum(buf[:n])
+		m.assign(m.strong[string(sum)], path, offset, func(i int) bool {
+			return int64(n) == m.plan.blockLength(i)
+		})
+	}
+	return nil
+}
+
+// scanRolling slides the zsync rolling checksum over every offset of the seed
+func (m *matcher) scanRolling(f *os.File, path string, size int64) error {
+	bs := int(m.control.BlockSize)
+	// Room for two blocks past the window so sequential matches can be confirmed
+	buf := make([]byte, scanWindow+2*bs)
+
+	for pos := int64(0); pos < size && m.remaining > 0; {
+		n, err := f.ReadAt(buf, pos)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		// zsync pads the last block with zeros, so the seed is too
+		clear(buf[n:])
+
+		limit := min(scanWindow, n)
+		i := 0
+		a, b := weakSum(buf[:bs])
+		for i < limit {
+			if m.tryMatch(buf, i, a, b, path, pos) {
+				i += bs
+				if i < limit {
+					a, b = weakSum(buf[i : i+bs])
+				}
+				continue
+			}
+
+			out, in := uint16(buf[i]), uint16(buf[i+bs])
+			a += in - out
+			b += a - uint16(bs)*out
+			i++
+		}
+		pos += int64(i)
+	}
+	return nil
+}
+
+// tryMatch checks whether the block starting at buf[i] is a target block
+func (m *matcher) tryMatch(buf []byte, i int, a, b uint16, path string, base int64) bool {
+	candidates := m.weak[(uint32(a)<<16|uint32(b))&m.weakMask]
+	if len(candidates) == 0 {
+		return false
+	}
+
+	bs := int(m.control.BlockSize)
+	sum := m.control.strongSum(buf[i : i+bs])
+	var next []byte
+	matched := false
+	for _, idx := range candidates {
+		if !bytes.Equal(sum, m.control.Blocks[idx].Strong) {
+			continue
+		}
+		// With seq_matches=2 the checksums are short, so require the following
+		// block to match as well before trusting the hit
+		if m.control.SeqMatches > 1 && idx+1 < len(m.control.Blocks) {
+			if next == nil {
+				next = m.control.strongSum(buf[i+bs : i+2*bs])
+			}
+			if !bytes.Equal(next, m.control.Blocks[idx+1].Strong) {
+				continue
+			}
+		}
+		matched = true
+		if m.plan.sources[idx] == nil {
+			m.plan.sources[idx] = &blockSource{seed: path, offset: base + int64(i)}
+			m.remaining--
+		}
+	}
+	return matched
+}
+
+// assign records path/offset as the source of every unmatched block in indices accepted by ok
+func (m *matcher) assign(indices []int, path string, offset int64, ok func(int) bool) {
+	for _, idx := range indices {
+		if m.plan.sources[idx] == nil && ok(idx) {
+			m.plan.sources[idx] = &blockSource{seed: path, offset: offset}
+			m.remaining--
+		}
+	}
+}
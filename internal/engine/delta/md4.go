@@ -0,0 +1,61 @@
+package delta
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// md4Sum computes the MD4 digest (RFC 1320) used by zsync for strong block checksums.
+// MD4 is not in the standard library and is only used here for compatibility.
+func md4Sum(data []byte) [16]byte {
+	a0, b0, c0, d0 := uint32(0x67452301), uint32(0xefcdab89), uint32(0x98badcfe), uint32(0x10325476)
+
+	msg := make([]byte, len(data), len(data)+72)
+	copy(msg, data)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(data))*8)
+
+	round1Shifts := [4]int{3, 7, 11, 19}
+	round2Shifts := [4]int{3, 5, 9, 13}
+	round3Shifts := [4]int{3, 9, 11, 15}
+	round3Order := [16]int{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15}
+
+	var x [16]uint32
+	for off := 0; off < len(msg); off += 64 {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(msg[off+4*i:])
+		}
+
+		a, b, c, d := a0, b0, c0, d0
+		for i := 0; i < 16; i++ {
+			f := (b & c) | (^b & d)
+			a = bits.RotateLeft32(a+f+x[i], round1Shifts[i%4])
+			a, b, c, d = d, a, b, c
+		}
+		for i := 0; i < 16; i++ {
+			g := (b & c) | (b & d) | (c & d)
+			a = bits.RotateLeft32(a+g+x[(i%4)*4+i/4]+0x5a827999, round2Shifts[i%4])
+			a, b, c, d = d, a, b, c
+		}
+		for i := 0; i < 16; i++ {
+			h := b ^ c ^ d
+			a = bits.RotateLeft32(a+h+x[round3Order[i]]+0x6ed9eba1, round3Shifts[i%4])
+			a, b, c, d = d, a, b, c
+		}
+
+		a0 += a
+		b0 += b
+		c0 += c
+		d0 += d
+	}
+
+	var sum [16]byte
+	binary.LittleEndian.PutUint32(sum[0:], a0)
+	binary.LittleEndian.PutUint32(sum[4:], b0)
+	binary.LittleEndian.PutUint32(sum[8:], c0)
+	binary.LittleEndian.PutUint32(sum[12:], d0)
+	return sum
+}
@@ -29,6 +29,8 @@ type DownloadCompleteMsg struct {
 	Elapsed    time.Duration
 	Total      int64
 	AvgSpeed   float64 // Average download speed in bytes/sec
+
+	NotModified bool // Conditional download skipped: the local file was already current
 }
 
 // DownloadErrorMsg signals that an error occurred
@@ -105,6 +107,7 @@ type DownloadPausedMsg struct {
 	DownloadID string
 	Filename   string
 	Downloaded int64
+	Reason     string `json:",omitempty"` // Set when Surge paused the download on its own (e.g. low disk space)
 }
 
 type DownloadResumedMsg struct {
@@ -134,4 +137,11 @@ type DownloadRequestMsg struct {
 	Path     string
 	Mirrors  []string
 	Headers  map[string]string
+	Options  types.DownloadOptions
+}
+
+// SnapshotMsg carries the full download list, sent by the daemon when a
+// reconnecting client missed more events than can be replayed
+type SnapshotMsg struct {
+	Downloads []types.DownloadStatus
 }
@@ -0,0 +1,53 @@
+// Package metrics holds process-wide engine counters, exported by the daemon's /metrics endpoint.
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	TaskRetries       atomic.Int64 // Task attempts retried after a failure
+	HedgedTasks       atomic.Int64 // Tasks duplicated onto an idle worker
+	StolenTasks       atomic.Int64 // Tasks split off a busy worker
+	MirrorFailures    atomic.Int64 // Mirror errors reported by workers
+	HealthRestarts    atomic.Int64 // Workers cancelled by the health monitor (stalled or slow)
+	ActiveConnections atomic.Int64 // HTTP connections currently transferring data
+)
+
+// hostBytes maps host -> *atomic.Int64 of bytes downloaded from it
+var hostBytes sync.Map
+
+// HostCounter returns the downloaded-bytes counter for host, so hot paths can
+// resolve it once and add to it without a map lookup per read
+func HostCounter(host string) *atomic.Int64 {
+	if c, ok := hostBytes.Load(host); ok {
+		return c.(*atomic.Int64)
+	}
+	c, _ := hostBytes.LoadOrStore(host, new(atomic.Int64))
+	return c.(*atomic.Int64)
+}
+
+// HostBytes returns a snapshot of bytes downloaded per host
+func HostBytes() map[string]int64 {
+	result := make(map[string]int64)
+	hostBytes.Range(func(key, value any) bool {
+		result[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return result
+}
+
+// Reset zeroes every counter (used by tests)
+func Reset() {
+	TaskRetries.Store(0)
+	HedgedTasks.Store(0)
+	StolenTasks.Store(0)
+	MirrorFailures.Store(0)
+	HealthRestarts.Store(0)
+	ActiveConnections.Store(0)
+	hostBytes.Range(func(key, _ any) bool {
+		hostBytes.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestHostCounter(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			HostCounter("example.com").Add(10)
+		}()
+	}
+	wg.Wait()
+	HostCounter("mirror.example.com").Add(5)
+
+	got := HostBytes()
+	if got["example.com"] != 80 || got["mirror.example.com"] != 5 || len(got) != 2 {
+		t.Errorf("HostBytes = %v", got)
+	}
+
+	Reset()
+	if len(HostBytes()) != 0 {
+		t.Error("Reset did not clear host counters")
+	}
+}
@@ -0,0 +1,134 @@
+package mirror
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// link is an entry of an HTML directory index
+type link struct {
+	URL  *url.URL
+	Size int64 // -1 when the index doesn't show an exact size
+}
+
+var (
+	// anchorPattern matches <a href=...>, with the href quoted or bare
+	anchorPattern = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))[^>]*>`)
+	tagPattern    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// parseIndex lists the links of an Apache or nginx autoindex page at base
+// that point below it. Sort links, parent links and other sites are dropped.
+func parseIndex(body []byte, base *url.URL) []link {
+	var links []link
+	seen := make(map[string]bool)
+	matches := anchorPattern.FindAllSubmatchIndex(body, -1)
+	for i, m := range matches {
+		href := ""
+		for g := 1; g <= 3; g++ {
+			if m[2*g] >= 0 {
+				href = html.UnescapeString(string(body[m[2*g]:m[2*g+1]]))
+				break
+			}
+		}
+		if href == "" || strings.HasPrefix(href, "?") || strings.HasPrefix(href, "#") {
+			continue
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		u.RawQuery, u.Fragment = "", ""
+		if u.Scheme != base.Scheme || u.Host != base.Host || !isBelow(u.Path, base.Path) {
+			continue
+		}
+		if seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+
+		// The rest of the row, up to the next link, may end with an exact size
+		end := len(body)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		links = append(links, link{URL: u, Size: rowSize(body[m[1]:end])})
+	}
+	return links
+}
+
+// isBelow reports whether p is a path strictly inside the directory dir
+func isBelow(p, dir string) bool {
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	return len(p) > len(dir) && strings.HasPrefix(p, dir) && !strings.Contains(p[len(dir):], "/../")
+}
+
+// rowSize reads the size at the end of an index row's first line, after the
+// link text. nginx prints exact byte counts; rounded sizes such as "1.2M"
+// are unknown (-1).
+func rowSize(row []byte) int64 {
+	i := bytes.Index(bytes.ToLower(row), []byte("</a>"))
+	if i < 0 {
+		return -1
+	}
+	row = row[i+len("</a>"):]
+	if i := bytes.IndexByte(row, '\n'); i >= 0 {
+		row = row[:i]
+	}
+	fields := strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(string(row), " ")))
+	if len(fields) == 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+	return size
+}
+
+// s3Listing is a page of an S3 ListObjects (v1 or v2) response
+type s3Listing struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Prefix                string   `xml:"Prefix"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextMarker            string   `xml:"NextMarker"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// parseS3Listing decodes body if it is an S3 bucket listing
+func parseS3Listing(body []byte) (*s3Listing, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(trimmed, []byte("<?xml")) && !bytes.HasPrefix(trimmed, []byte("<ListBucketResult")) {
+		return nil, false
+	}
+	var listing s3Listing
+	if err := xml.Unmarshal(trimmed, &listing); err != nil {
+		return nil, false
+	}
+	return &listing, true
+}
+
+// s3ObjectURL returns the URL of key in the bucket listed at endpoint
+func s3ObjectURL(endpoint *url.URL, key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	dir := strings.TrimSuffix(endpoint.EscapedPath(), "/")
+	return endpoint.Scheme + "://" + endpoint.Host + dir + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,344 @@
+// Package mirror crawls HTTP directory listings, so that a whole tree of
+// files can be queued for download. Apache and nginx autoindex pages and S3
+// bucket listings are understood.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ErrNotListing is returned when the root URL is not a directory listing
+var ErrNotListing = errors.New("not a directory listing")
+
+const (
+	// listingTimeout bounds the fetch of one listing page
+	listingTimeout = time.Minute
+	// maxListingSize caps how much of a listing page is read
+	maxListingSize = 32 << 20
+)
+
+// Options filters the files a crawl returns
+type Options struct {
+	MaxDepth int      // Levels of subdirectories to descend into; negative for no limit
+	Include  []string // Globs a file must match one of; empty for all files
+	Exclude  []string // Globs of files and directories to leave out
+	MinSize  int64    // Smallest file size; 0 for no limit
+	MaxSize  int64    // Largest file size; 0 for no limit
+	Headers  map[string]string
+}
+
+// File is a file found by a crawl
+type File struct {
+	URL  string
+	Path string // Slash-separated path below the root directory
+	Size int64  // -1 when the size is unknown
+}
+
+// Validate checks the include and exclude globs
+func (o Options) Validate() error {
+	for _, p := range append(append([]string(nil), o.Include...), o.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	if o.MaxSize > 0 && o.MinSize > o.MaxSize {
+		return fmt.Errorf("minimum size is larger than the maximum size")
+	}
+	return nil
+}
+
+// Crawl lists the files below the directory at root, recursively. Globs
+// containing a '/' are matched against a file's path below root, others
+// against its name. When a size filter is set, files whose size can't be
+// determined are left out.
+func Crawl(ctx context.Context, root string, opts Options) ([]File, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	rootURL, err := url.Parse(root)
+	if err != nil || (rootURL.Scheme != "http" && rootURL.Scheme != "https") || rootURL.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", root)
+	}
+	rootURL.Fragment = ""
+
+	c := &crawler{
+		ctx:    ctx,
+		client: &http.Client{Timeout: listingTimeout},
+		opts:   opts,
+	}
+
+	// An S3 listing is addressed by its query; a directory by its path
+	if rootURL.RawQuery == "" && !strings.HasSuffix(rootURL.Path, "/") {
+		rootURL.Path += "/"
+	}
+	body, final, err := c.fetch(rootURL)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", rootURL, err)
+	}
+	if listing, ok := parseS3Listing(body); ok {
+		err = c.crawlS3(rootURL, listing)
+	} else {
+		err = c.crawlIndex(final, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.files, nil
+}
+
+// RemoteSize probes the size of the file at rawurl, or returns -1 if the
+// server doesn't report it
+func RemoteSize(ctx context.Context, rawurl string, headers map[string]string) int64 {
+	probe, err := engine.ProbeServer(ctx, rawurl, "", headers)
+	if err != nil || probe.FileSize <= 0 {
+		utils.Debug("Mirror: no size for %s: %v", rawurl, err)
+		return -1
+	}
+	return probe.FileSize
+}
+
+type crawler struct {
+	ctx    context.Context
+	client *http.Client
+	opts   Options
+	files  []File
+}
+
+// fetch GETs a listing page, returning its body and the URL it was served from
+func (c *crawler) fetch(u *url.URL) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, val := range c.opts.Headers {
+		req.Header.Set(key, val)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &types.HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxListingSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// crawlIndex walks HTML autoindex pages breadth first, starting with the
+// already fetched root page
+func (c *crawler) crawlIndex(root *url.URL, body []byte) error {
+	type dir struct {
+		url   *url.URL
+		depth int
+	}
+	rootPath := root.Path
+	if !strings.HasSuffix(rootPath, "/") {
+		rootPath += "/"
+	}
+
+	queue := []dir{{url: root}}
+	visited := map[string]bool{root.Path: true}
+	for first := true; len(queue) > 0; first = false {
+		d := queue[0]
+		queue = queue[1:]
+
+		base := d.url
+		if !first {
+			var err error
+			if body, base, err = c.fetch(d.url); err != nil {
+				return fmt.Errorf("listing %s: %w", d.url, err)
+			}
+			// A redirect may leave the tree being mirrored
+			if !isBelow(base.Path, rootPath) {
+				utils.Debug("Mirror: skipping %s, redirected outside the root to %s", d.url, base)
+				continue
+			}
+		}
+		if !strings.HasSuffix(base.Path, "/") {
+			u := *base
+			u.Path += "/"
+			base = &u
+		}
+
+		links := parseIndex(body, base)
+		if first && len(links) == 0 {
+			return fmt.Errorf("%w: %s", ErrNotListing, root)
+		}
+		for _, l := range links {
+			rel := strings.TrimPrefix(l.URL.Path, rootPath)
+			if strings.HasSuffix(rel, "/") {
+				if c.opts.MaxDepth >= 0 && d.depth+1 > c.opts.MaxDepth {
+					continue
+				}
+				if matchAny(c.opts.Exclude, rel) || visited[l.URL.Path] {
+					continue
+				}
+				visited[l.URL.Path] = true
+				queue = append(queue, dir{url: l.URL, depth: d.depth + 1})
+				continue
+			}
+			c.addFile(l.URL.String(), rel, l.Size)
+		}
+	}
+	return nil
+}
+
+// crawlS3 pages through an S3 bucket listing, starting with the already
+// fetched first page. Listings by delimiter are followed into their prefixes.
+func (c *crawler) crawlS3(root *url.URL, firstPage *s3Listing) error {
+	endpoint := *root
+	endpoint.RawQuery = ""
+
+	// Paths are relative to the directory part of the requested prefix
+	rootQuery := root.Query()
+	base := rootQuery.Get("prefix")
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[:i+1]
+	} else {
+		base = ""
+	}
+
+	queue := []url.Values{rootQuery}
+	for len(queue) > 0 {
+		query := queue[0]
+		queue = queue[1:]
+
+		listing := firstPage
+		firstPage = nil
+		if listing == nil {
+			u := endpoint
+			u.RawQuery = query.Encode()
+			body, _, err := c.fetch(&u)
+			if err != nil {
+				return fmt.Errorf("listing %s: %w", &u, err)
+			}
+			var ok bool
+			if listing, ok = parseS3Listing(body); !ok {
+				return fmt.Errorf("listing %s: %w", &u, ErrNotListing)
+			}
+		}
+
+		lastKey := ""
+		for _, obj := range listing.Contents {
+			lastKey = obj.Key
+			if !strings.HasPrefix(obj.Key, base) || strings.HasSuffix(obj.Key, "/") {
+				continue // Outside the prefix, or a folder placeholder
+			}
+			rel := obj.Key[len(base):]
+			if c.opts.MaxDepth >= 0 && strings.Count(rel, "/") > c.opts.MaxDepth {
+				continue
+			}
+			if c.parentExcluded(rel) {
+				continue
+			}
+			c.addFile(s3ObjectURL(&endpoint, obj.Key), rel, obj.Size)
+		}
+		for _, p := range listing.CommonPrefixes {
+			if !strings.HasPrefix(p.Prefix, base) {
+				continue
+			}
+			rel := p.Prefix[len(base):]
+			if c.opts.MaxDepth >= 0 && strings.Count(rel, "/") > c.opts.MaxDepth {
+				continue
+			}
+			if c.parentExcluded(rel) || matchAny(c.opts.Exclude, rel) {
+				continue
+			}
+			next := cloneQuery(query)
+			next.Set("prefix", p.Prefix)
+			next.Del("marker")
+			next.Del("continuation-token")
+			queue = append(queue, next)
+		}
+
+		if listing.IsTruncated {
+			next := cloneQuery(query)
+			switch {
+			case listing.NextContinuationToken != "":
+				next.Set("continuation-token", listing.NextContinuationToken)
+			case listing.NextMarker != "":
+				next.Set("marker", listing.NextMarker)
+			case lastKey != "":
+				next.Set("marker", lastKey)
+			default:
+				continue // Nothing to continue from
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// addFile records a file if it passes the filters
+func (c *crawler) addFile(rawurl, rel string, size int64) {
+	// S3 keys may hold anything; only keep paths that stay below the root
+	if rel == "" || path.Clean("/"+rel) != "/"+rel {
+		utils.Debug("Mirror: skipping unsafe path %q", rel)
+		return
+	}
+	if len(c.opts.Include) > 0 && !matchAny(c.opts.Include, rel) {
+		return
+	}
+	if matchAny(c.opts.Exclude, rel) {
+		return
+	}
+	if c.opts.MinSize > 0 || c.opts.MaxSize > 0 {
+		if size < 0 {
+			size = RemoteSize(c.ctx, rawurl, c.opts.Headers)
+		}
+		if size < 0 || size < c.opts.MinSize || (c.opts.MaxSize > 0 && size > c.opts.MaxSize) {
+			return
+		}
+	}
+	c.files = append(c.files, File{URL: rawurl, Path: rel, Size: size})
+}
+
+// parentExcluded reports whether a directory above rel is excluded
+func (c *crawler) parentExcluded(rel string) bool {
+	for i := 0; i < len(rel)-1; i++ {
+		if rel[i] == '/' && matchAny(c.opts.Exclude, rel[:i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether rel matches one of patterns. Patterns with a '/'
+// match the whole path, others the last element.
+func matchAny(patterns []string, rel string) bool {
+	rel = strings.TrimSuffix(rel, "/")
+	for _, p := range patterns {
+		target := path.Base(rel)
+		if strings.Contains(strings.TrimSuffix(p, "/"), "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(strings.TrimSuffix(p, "/"), target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneQuery(q url.Values) url.Values {
+	clone := make(url.Values, len(q))
+	for k, v := range q {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
@@ -0,0 +1,172 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// indexServer serves nginx and Apache style indexes for /fw/ and a few files
+func indexServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"/fw/": `<html><head><title>Index of /fw/</title></head><body>
+<h1>Index of /fw/</h1><hr><pre><a href="../">../</a>
+<a href="a.bin">a.bin</a>                                              19-Oct-2026 10:00             100
+<a href="sub/">sub/</a>                                               19-Oct-2026 10:00               -
+<a href="old/">old/</a>                                               19-Oct-2026 10:00               -
+<a href="https://elsewhere.example/x.bin">x.bin</a>
+</pre><hr></body></html>`,
+		"/fw/sub/": `<table><tr><th><a href="?C=N;O=D">Name</a></th></tr>
+<tr><td><a href="/fw/">Parent Directory</a></td><td>&nbsp;</td></tr>
+<tr><td><a href="b%20v2.txt">b v2.txt</a></td><td align="right">2026-10-19 10:00  </td><td align="right">1.2K</td></tr>
+<tr><td><a href="deep/">deep/</a></td><td align="right">2026-10-19 10:00  </td><td align="right">  - </td></tr>
+</table>`,
+		"/fw/sub/deep/": `<ul><li><a href="c.bin">c.bin</a></li></ul>`,
+		"/fw/old/":      `<ul><li><a href="stale.bin">stale.bin</a></li></ul>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if page, ok := pages[r.URL.Path]; ok {
+			_, _ = fmt.Fprint(w, page)
+			return
+		}
+		if r.URL.Path == "/fw" {
+			http.Redirect(w, r, "/fw/", http.StatusMovedPermanently)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/fw/") {
+			_, _ = fmt.Fprint(w, strings.Repeat("x", 1229))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func crawlPaths(t *testing.T, root string, opts Options) map[string]int64 {
+	t.Helper()
+	files, err := Crawl(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+	got := make(map[string]int64)
+	for _, f := range files {
+		got[f.Path] = f.Size
+	}
+	return got
+}
+
+func TestCrawl_Index(t *testing.T) {
+	server := indexServer(t)
+	defer server.Close()
+
+	got := crawlPaths(t, server.URL+"/fw", Options{MaxDepth: -1})
+	want := map[string]int64{"a.bin": 100, "sub/b v2.txt": -1, "sub/deep/c.bin": -1, "old/stale.bin": -1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Crawl = %v, want %v", got, want)
+	}
+
+	files, _ := Crawl(context.Background(), server.URL+"/fw/", Options{MaxDepth: -1, Include: []string{"b*"}})
+	if len(files) != 1 || files[0].URL != server.URL+"/fw/sub/b%20v2.txt" {
+		t.Errorf("Expected the escaped URL of b v2.txt, got %+v", files)
+	}
+}
+
+func TestCrawl_Filters(t *testing.T) {
+	server := indexServer(t)
+	defer server.Close()
+	root := server.URL + "/fw/"
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"depth 0", Options{MaxDepth: 0}, []string{"a.bin"}},
+		{"depth 1", Options{MaxDepth: 1}, []string{"a.bin", "old/stale.bin", "sub/b v2.txt"}},
+		{"include", Options{MaxDepth: -1, Include: []string{"*.bin"}}, []string{"a.bin", "old/stale.bin", "sub/deep/c.bin"}},
+		{"exclude dir", Options{MaxDepth: -1, Exclude: []string{"old", "sub/deep"}}, []string{"a.bin", "sub/b v2.txt"}},
+		// Unknown sizes are probed
+		{"min size", Options{MaxDepth: -1, MinSize: 1000}, []string{"old/stale.bin", "sub/b v2.txt", "sub/deep/c.bin"}},
+		{"max size", Options{MaxDepth: -1, MaxSize: 1000}, []string{"a.bin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for p := range crawlPaths(t, root, tt.opts) {
+				got = append(got, p)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Crawl = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Crawl(context.Background(), root, Options{Include: []string{"[x"}}); err == nil {
+		t.Error("Expected an error for a bad pattern")
+	}
+}
+
+func TestCrawl_NotListing(t *testing.T) {
+	server := indexServer(t)
+	defer server.Close()
+
+	_, err := Crawl(context.Background(), server.URL+"/fw/sub/deep/c.bin", Options{MaxDepth: -1})
+	if !errors.Is(err, ErrNotListing) {
+		t.Errorf("Expected ErrNotListing, got %v", err)
+	}
+}
+
+func TestCrawl_S3(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		q := r.URL.Query()
+		switch {
+		case q.Get("prefix") == "fw/" && q.Get("continuation-token") == "":
+			_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Prefix>fw/</Prefix><IsTruncated>true</IsTruncated><NextContinuationToken>tok</NextContinuationToken>
+  <Contents><Key>fw/</Key><Size>0</Size></Contents>
+  <Contents><Key>fw/a.bin</Key><Size>10</Size></Contents>
+  <CommonPrefixes><Prefix>fw/v2/</Prefix></CommonPrefixes>
+</ListBucketResult>`)
+		case q.Get("prefix") == "fw/":
+			_, _ = fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>
+  <Contents><Key>fw/b c.bin</Key><Size>20</Size></Contents>
+</ListBucketResult>`)
+		case q.Get("prefix") == "fw/v2/":
+			_, _ = fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>
+  <Contents><Key>fw/v2/c.bin</Key><Size>30</Size></Contents>
+</ListBucketResult>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	files, err := Crawl(context.Background(), server.URL+"/bucket/?list-type=2&delimiter=/&prefix=fw/", Options{MaxDepth: -1})
+	if err != nil {
+		t.Fatalf("Crawl failed: %v", err)
+	}
+	got := make(map[string]File)
+	for _, f := range files {
+		got[f.Path] = f
+	}
+	if len(got) != 3 || got["a.bin"].Size != 10 || got["v2/c.bin"].Size != 30 {
+		t.Errorf("Unexpected files: %+v", files)
+	}
+	if u := got["b c.bin"].URL; u != server.URL+"/bucket/fw/b%20c.bin" {
+		t.Errorf("Object URL = %q", u)
+	}
+
+	files, _ = Crawl(context.Background(), server.URL+"/bucket/?list-type=2&delimiter=/&prefix=fw/", Options{MaxDepth: 0})
+	if len(files) != 2 {
+		t.Errorf("Expected 2 top-level files with depth 0, got %+v", files)
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,6 +25,15 @@ type ProbeResult struct {
 	SupportsRange bool
 	Filename      string
 	ContentType   string
+
+	// Diagnostics, shown by surge info
+	FilenameSource string   // One of the utils.FilenameFrom constants, or "given" for a filename hint
+	StatusCode     int      // Status of the final response
+	Redirects      []string // URLs redirected to, in order; the last one served the file
+	Proto          string   // HTTP version of the final response, e.g. "HTTP/2.0"
+	TLSVersion     string   // e.g. "TLS 1.3"; empty for plain HTTP
+	ETag           string
+	LastModified   string
 }
 
 // ProbeServer sends GET with Range: bytes=0-0 to determine server capabilities
@@ -152,23 +162,31 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 		utils.Debug("Range NOT supported (got 200), file size: %d", result.FileSize)
 
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &types.HTTPStatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Determine filename using strengthened logic
-	name, _, err := utils.DetermineFilename(rawurl, resp, false)
+	name, source, _, err := utils.DetermineFilenameSource(rawurl, resp, false)
 	if err != nil {
 		utils.Debug("Error determining filename: %v", err)
-		name = "download.bin"
+		name, source = "download.bin", utils.FilenameFromDefault
 	}
 
 	if filenameHint != "" {
-		result.Filename = filenameHint
+		result.Filename, result.FilenameSource = filenameHint, "given"
 	} else {
-		result.Filename = name
+		result.Filename, result.FilenameSource = name, source
 	}
 
 	result.ContentType = resp.Header.Get("Content-Type")
+	result.StatusCode = resp.StatusCode
+	result.Redirects = redirectChain(resp)
+	result.Proto = resp.Proto
+	if resp.TLS != nil {
+		result.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+	result.ETag = resp.Header.Get("ETag")
+	result.LastModified = resp.Header.Get("Last-Modified")
 
 	utils.Debug("Probe complete - filename: %s, size: %d, range: %v",
 		result.Filename, result.FileSize, result.SupportsRange)
@@ -176,6 +194,16 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 	return result, nil
 }
 
+// redirectChain lists the URLs resp was redirected through, ending with the
+// one that served it. It is empty when no redirect was followed.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
+	}
+	return chain
+}
+
 // ProbeMirrors concurrently checks a list of mirrors and returns valid ones and errors
 func ProbeMirrors(ctx context.Context, mirrors []string) (valid []string, errors map[string]error) {
 	// Deduplicate
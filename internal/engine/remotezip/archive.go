@@ -0,0 +1,265 @@
+// Package remotezip reads ZIP archives over HTTP range requests, so single
+// members can be listed and extracted without downloading the whole archive.
+package remotezip
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/engine"
+	"github.com/surge-downloader/surge/internal/engine/concurrent"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var (
+	ErrMemberNotFound    = errors.New("member not found in archive")
+	ErrUnsupportedMethod = errors.New("unsupported compression method")
+	ErrEncryptedMember   = errors.New("encrypted members are not supported")
+	ErrChecksumMismatch  = errors.New("member checksum mismatch")
+)
+
+const (
+	// compressedDataSuffix marks the temporary file holding a member's raw compressed bytes
+	compressedDataSuffix = ".zipdata"
+	// encryptedFlag is bit 0 of the general purpose flags
+	encryptedFlag uint16 = 0x1
+)
+
+// Options configures access to a remote archive
+type Options struct {
+	Headers map[string]string    // Custom HTTP headers (cookies, auth, etc.)
+	Mirrors []string             // Alternate URLs serving the same archive
+	Runtime *types.RuntimeConfig // Engine settings used for member downloads
+}
+
+// Member describes a single entry of a remote archive
+type Member struct {
+	Name             string
+	Method           uint16
+	CompressedSize   int64
+	UncompressedSize int64
+	Modified         time.Time
+	CRC32            uint32
+	IsDir            bool
+
+	file *zip.File
+}
+
+// Archive is a ZIP file whose central directory has been read over HTTP
+type Archive struct {
+	URL     string
+	Size    int64
+	Members []*Member
+
+	opts   Options
+	reader *rangeReader
+}
+
+// Open probes url and reads the archive's central directory using range requests
+func Open(ctx context.Context, url string, opts Options) (*Archive, error) {
+	probe, err := engine.ProbeServer(ctx, url, "", opts.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if !probe.SupportsRange {
+		return nil, fmt.Errorf("%w: cannot read remote archive %s", types.ErrRangeNotSupported, url)
+	}
+	if probe.FileSize <= 0 {
+		return nil, fmt.Errorf("server did not report the size of %s", url)
+	}
+
+	reader := newRangeReader(ctx, url, probe.FileSize, opts.Headers, opts.Runtime)
+	zr, err := zip.NewReader(reader, probe.FileSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip directory: %w", err)
+	}
+	utils.Debug("remotezip: read %d entries from %s using %d requests", len(zr.File), url, reader.requests)
+
+	a := &Archive{
+		URL:     url,
+		Size:    probe.FileSize,
+		Members: make([]*Member, 0, len(zr.File)),
+		opts:    opts,
+		reader:  reader,
+	}
+	for _, f := range zr.File {
+		a.Members = append(a.Members, &Member{
+			Name:             f.Name,
+			Method:           f.Method,
+			CompressedSize:   int64(f.CompressedSize64),
+			UncompressedSize: int64(f.UncompressedSize64),
+			Modified:         f.Modified,
+			CRC32:            f.CRC32,
+			IsDir:            f.FileInfo().IsDir(),
+			file:             f,
+		})
+	}
+	return a, nil
+}
+
+// Lookup returns the member with the given name
+func (a *Archive) Lookup(name string) (*Member, error) {
+	for _, m := range a.Members {
+		if m.Name == name || (m.IsDir && strings.TrimSuffix(m.Name, "/") == name) {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrMemberNotFound, name)
+}
+
+// DataRange returns the offset and length of m's compressed data within the archive
+func (a *Archive) DataRange(m *Member) (start, length int64, err error) {
+	start, err = m.file.DataOffset()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read local header of %s: %w", m.Name, err)
+	}
+	return start, m.CompressedSize, nil
+}
+
+// Extract downloads m's compressed data with the concurrent engine and writes
+// the decompressed contents to destPath
+func (a *Archive) Extract(ctx context.Context, m *Member, destPath string) error {
+	if m.IsDir {
+		return os.MkdirAll(destPath, 0o755)
+	}
+	if m.file.Flags&encryptedFlag != 0 {
+		return fmt.Errorf("%w: %s", ErrEncryptedMember, m.Name)
+	}
+	if m.Method != zip.Store && m.Method != zip.Deflate {
+		return fmt.Errorf("%w %d: %s", ErrUnsupportedMethod, m.Method, m.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	start, length, err := a.DataRange(m)
+	if err != nil {
+		return err
+	}
+	if length == 0 {
+		return writeMember(m, strings.NewReader(""), destPath)
+	}
+
+	// Stored members are already the final bytes, so fetch them in place
+	if m.Method == zip.Store {
+		if err := a.fetchRange(ctx, start, length, destPath); err != nil {
+			return err
+		}
+		if err := verifyFile(m, destPath); err != nil {
+			_ = os.Remove(destPath)
+			return err
+		}
+		return nil
+	}
+
+	dataPath := destPath + compressedDataSuffix
+	if err := a.fetchRange(ctx, start, length, dataPath); err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(dataPath) }()
+
+	data, err := os.Open(dataPath)
+	if err != nil {
+		return fmt.Errorf("failed to open compressed data: %w", err)
+	}
+	defer func() { _ = data.Close() }()
+
+	return writeMember(m, data, destPath)
+}
+
+// fetchRange downloads [start, start+length) of the archive to path
+func (a *Archive) fetchRange(ctx context.Context, start, length int64, path string) error {
+	var activeMirrors []string
+	if len(a.opts.Mirrors) > 0 {
+		valid, errs := engine.ProbeMirrors(ctx, append([]string{a.URL}, a.opts.Mirrors...))
+		for u, e := range errs {
+			utils.Debug("Mirror probe failed for %s: %v", u, e)
+		}
+		for _, v := range valid {
+			if v != a.URL {
+				activeMirrors = append(activeMirrors, v)
+			}
+		}
+	}
+
+	id := uuid.New().String()
+	d := concurrent.NewConcurrentDownloader(id, nil, types.NewProgressState(id, length), a.opts.Runtime)
+	d.Headers = a.opts.Headers
+	d.RangeStart = start
+	d.RangeLength = length
+	if err := d.Download(ctx, a.URL, a.opts.Mirrors, activeMirrors, path, length); err != nil {
+		return fmt.Errorf("failed to download member data: %w", err)
+	}
+	return nil
+}
+
+// writeMember decompresses compressed into destPath, verifying size and CRC-32
+func writeMember(m *Member, compressed io.Reader, destPath string) error {
+	src := compressed
+	if m.Method == zip.Deflate {
+		fr := flate.NewReader(compressed)
+		defer func() { _ = fr.Close() }()
+		src = fr
+	}
+
+	workingPath := destPath + types.IncompleteSuffix
+	out, err := os.Create(workingPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	hash := crc32.NewIEEE()
+	n, err := io.Copy(io.MultiWriter(out, hash), src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = checkMember(m, n, hash.Sum32())
+	}
+	if err != nil {
+		_ = os.Remove(workingPath)
+		return fmt.Errorf("failed to extract %s: %w", m.Name, err)
+	}
+
+	if err := os.Rename(workingPath, destPath); err != nil {
+		return fmt.Errorf("failed to rename extracted file: %w", err)
+	}
+	return nil
+}
+
+// verifyFile checks the size and CRC-32 of an already written member
+func verifyFile(m *Member, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	hash := crc32.NewIEEE()
+	n, err := io.Copy(hash, f)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", m.Name, err)
+	}
+	return checkMember(m, n, hash.Sum32())
+}
+
+func checkMember(m *Member, size int64, sum uint32) error {
+	if size != m.UncompressedSize {
+		return fmt.Errorf("%w: %s has %d bytes, expected %d", ErrChecksumMismatch, m.Name, size, m.UncompressedSize)
+	}
+	if sum != m.CRC32 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package remotezip
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func initTestState(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tmpDir, "surge.db"))
+	t.Cleanup(state.CloseDB)
+	return tmpDir
+}
+
+// buildTestArchive returns a ZIP with a large stored filler, a deflated text
+// member and a small stored member
+func buildTestArchive(t *testing.T) (archive []byte, text []byte, small []byte) {
+	t.Helper()
+
+	filler := make([]byte, 8*1024*1024)
+	rand.New(rand.NewSource(1)).Read(filler)
+	text = []byte(strings.Repeat("surge remote zip member\n", 20000))
+	small = []byte("hello from the end of the archive")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	members := []struct {
+		name   string
+		method uint16
+		data   []byte
+	}{
+		{"filler.bin", zip.Store, filler},
+		{"docs/readme.txt", zip.Deflate, text},
+		{"small.txt", zip.Store, small},
+	}
+	for _, m := range members {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: m.name, Method: m.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(m.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := zw.Create("docs/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), text, small
+}
+
+// newArchiveServer serves data with range support and counts bytes sent
+func newArchiveServer(data []byte, served *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w, n: served}
+		http.ServeContent(cw, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return c.ResponseWriter.Write(p)
+}
+
+func TestOpen_ListsMembers(t *testing.T) {
+	data, text, _ := buildTestArchive(t)
+	var served atomic.Int64
+	server := newArchiveServer(data, &served)
+	defer server.Close()
+
+	a, err := Open(context.Background(), server.URL+"/archive.zip", Options{})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if a.Size != int64(len(data)) {
+		t.Errorf("Size = %d, want %d", a.Size, len(data))
+	}
+	if len(a.Members) != 4 {
+		t.Fatalf("Expected 4 members, got %d", len(a.Members))
+	}
+
+	m, err := a.Lookup("docs/readme.txt")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if m.UncompressedSize != int64(len(text)) || m.Method != zip.Deflate {
+		t.Errorf("Unexpected member metadata: %+v", m)
+	}
+	if m.CompressedSize >= m.UncompressedSize {
+		t.Errorf("Expected compressed member, got %d >= %d", m.CompressedSize, m.UncompressedSize)
+	}
+
+	if dir, err := a.Lookup("docs"); err != nil || !dir.IsDir {
+		t.Errorf("Expected docs to be a directory member, got %+v, %v", dir, err)
+	}
+	if _, err := a.Lookup("missing.txt"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Expected ErrMemberNotFound, got %v", err)
+	}
+
+	if got := served.Load(); got > int64(len(data))/4 {
+		t.Errorf("Listing transferred %d of %d bytes", got, len(data))
+	}
+}
+
+func TestExtract_DownloadsOnlyMember(t *testing.T) {
+	tmpDir := initTestState(t)
+
+	data, text, small := buildTestArchive(t)
+	var served atomic.Int64
+	server := newArchiveServer(data, &served)
+	defer server.Close()
+
+	a, err := Open(context.Background(), server.URL+"/archive.zip", Options{
+		Runtime: &types.RuntimeConfig{MinChunkSize: 64 * 1024},
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want []byte
+	}{
+		{"docs/readme.txt", text},
+		{"small.txt", small},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := a.Lookup(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(tmpDir, filepath.Base(tt.name))
+			if err := a.Extract(context.Background(), m, dest); err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Error("Extracted content mismatch")
+			}
+			if _, err := os.Stat(dest + compressedDataSuffix); !os.IsNotExist(err) {
+				t.Error("Compressed data file was not cleaned up")
+			}
+		})
+	}
+
+	// The 8MB filler must never have been fetched
+	if got := served.Load(); got > int64(len(data))/2 {
+		t.Errorf("Extraction transferred %d of %d bytes", got, len(data))
+	}
+}
+
+func TestWriteMember_ChecksumMismatch(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "out.txt")
+	m := &Member{Name: "out.txt", Method: zip.Store, UncompressedSize: 5, CRC32: 1}
+
+	err := writeMember(m, strings.NewReader("hello"), dest)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("Output should not exist after a failed extraction")
+	}
+}
+
+func TestOpen_RequiresRangeSupport(t *testing.T) {
+	data, _, _ := buildTestArchive(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	_, err := Open(context.Background(), server.URL+"/archive.zip", Options{})
+	if !errors.Is(err, types.ErrRangeNotSupported) {
+		t.Fatalf("Expected ErrRangeNotSupported, got %v", err)
+	}
+}
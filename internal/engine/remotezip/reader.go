@@ -0,0 +1,135 @@
+package remotezip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// blockSize is the granularity of range requests made by rangeReader.
+// archive/zip issues many small reads while walking the central directory,
+// so reads are rounded up to whole blocks and cached.
+const blockSize = 256 * 1024
+
+// maxCachedBlocks bounds the memory used by the block cache
+const maxCachedBlocks = 64
+
+// rangeReader implements io.ReaderAt over HTTP range requests
+type rangeReader struct {
+	ctx     context.Context
+	client  *http.Client
+	url     string
+	headers map[string]string
+	runtime *types.RuntimeConfig
+	size    int64
+
+	mu       sync.Mutex
+	blocks   map[int64][]byte
+	order    []int64 // FIFO eviction order
+	requests int     // Number of HTTP requests issued (for diagnostics)
+}
+
+func newRangeReader(ctx context.Context, url string, size int64, headers map[string]string, runtime *types.RuntimeConfig) *rangeReader {
+	return &rangeReader{
+		ctx:     ctx,
+		client:  &http.Client{Timeout: types.ProbeTimeout},
+		url:     url,
+		headers: headers,
+		runtime: runtime,
+		size:    size,
+		blocks:  make(map[int64][]byte),
+	}
+}
+
+// ReadAt implements io.ReaderAt
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < r.size {
+		index := off / blockSize
+		block, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], block[off-index*blockSize:])
+		n += copied
+		off += int64(copied)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// block returns the cached block at index, fetching it if needed
+func (r *rangeReader) block(index int64) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.blocks[index]; ok {
+		return b, nil
+	}
+
+	start := index * blockSize
+	end := min(start+blockSize, r.size) - 1
+	b, err := r.fetch(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.order) >= maxCachedBlocks {
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.blocks[index] = b
+	r.order = append(r.order, index)
+	return b, nil
+}
+
+// fetch downloads the inclusive byte range [start, end]
+func (r *rangeReader) fetch(start, end int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, val := range r.headers {
+		if key != "Range" {
+			req.Header.Set(key, val)
+		}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", r.runtime.GetUserAgent())
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	r.requests++
+	utils.Debug("remotezip: fetching bytes %d-%d of %s", start, end, r.url)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("range request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("%w: server returned %d for bytes %d-%d", types.ErrRangeNotSupported, resp.StatusCode, start, end)
+	}
+
+	buf := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return nil, fmt.Errorf("short range response: %w", err)
+	}
+	return buf, nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/metrics"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
 )
@@ -22,6 +23,10 @@ type SingleDownloader struct {
 	State        *types.ProgressState // Shared state for TUI polling
 	Runtime      *types.RuntimeConfig
 	Headers      map[string]string // Custom HTTP headers (cookies, auth, etc.)
+
+	// Verify, when set, checks the finished working file before it is renamed
+	// into place. On failure the working file is removed and destPath is untouched.
+	Verify func(path string) error
 }
 
 // NewSingleDownloader creates a new single-threaded downloader with all required parameters
@@ -60,7 +65,7 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &types.HTTPStatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Use .surge extension for incomplete file
@@ -80,6 +85,9 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	}()
 
 	start := time.Now()
+	hostBytes := metrics.HostCounter(req.URL.Host)
+	metrics.ActiveConnections.Add(1)
+	defer metrics.ActiveConnections.Add(-1)
 
 	// Copy response body to file with context cancellation support
 	var written int64
@@ -99,6 +107,7 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 			nw, writeErr := outFile.Write(buf[0:nr])
 			if nw > 0 {
 				written += int64(nw)
+				hostBytes.Add(int64(nw))
 				if d.State != nil {
 					d.State.Downloaded.Store(written)
 				}
@@ -124,6 +133,11 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	if err := outFile.Close(); err != nil {
 		return fmt.Errorf("close error: %w", err)
 	}
+	if d.Verify != nil {
+		if err := d.Verify(workingPath); err != nil {
+			return err // The deferred cleanup removes the working file
+		}
+	}
 
 	// Rename .surge file to final destination
 	if err := os.Rename(workingPath, destPath); err != nil {
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// AuditEntry records one action taken through the API
+type AuditEntry struct {
+	ID         int64  `json:"id"`
+	Time       int64  `json:"time"`            // Unix time
+	Actor      string `json:"actor,omitempty"` // Token name; empty for in-process callers
+	Addr       string `json:"addr,omitempty"`  // Client IP, or "local" for the Unix socket
+	Action     string `json:"action"`
+	DownloadID string `json:"download_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Since  int64 // Unix time, inclusive
+	Until  int64 // Unix time, exclusive
+	Actor  string
+	Addr   string
+	Action string
+	Limit  int
+}
+
+// AddAuditEntry stores an entry and drops entries older than keepAfter (Unix time)
+func AddAuditEntry(e AuditEntry, keepAfter int64) error {
+	return withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO audit_log (time, actor, addr, action, download_id, url, detail) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			e.Time, e.Actor, e.Addr, e.Action, e.DownloadID, e.URL, e.Detail); err != nil {
+			return fmt.Errorf("failed to insert audit entry: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM audit_log WHERE time < ?", keepAfter); err != nil {
+			return fmt.Errorf("failed to trim audit log: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListAudit returns the entries matching f, newest first
+func ListAudit(f AuditFilter) ([]AuditEntry, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.Since > 0 {
+		add("time >= ?", f.Since)
+	}
+	if f.Until > 0 {
+		add("time < ?", f.Until)
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	if f.Addr != "" {
+		add("addr = ?", f.Addr)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+
+	query := "SELECT id, time, actor, addr, action, download_id, url, detail FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY time DESC, id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var actor, addr, id, url, detail sql.NullString
+		if err := rows.Scan(&e.ID, &e.Time, &actor, &addr, &e.Action, &id, &url, &detail); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		e.Actor, e.Addr, e.DownloadID, e.URL, e.Detail = actor.String, addr.String, id.String, url.String, detail.String
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAudit_AddListTrim(t *testing.T) {
+	tempDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	defer CloseDB()
+
+	entries := []AuditEntry{
+		{Time: 100, Actor: "old", Action: "add"},
+		{Time: 1000, Actor: "extension", Addr: "192.168.1.20", Action: "add", DownloadID: "d1", URL: "https://example.com/big.iso", Detail: "/srv/dl"},
+		{Time: 1100, Actor: "root", Addr: "local", Action: "pause", DownloadID: "d1"},
+		{Time: 1200, Actor: "extension", Addr: "192.168.1.20", Action: "delete", DownloadID: "d1"},
+	}
+	for _, e := range entries {
+		if err := AddAuditEntry(e, 500); err != nil {
+			t.Fatalf("AddAuditEntry failed: %v", err)
+		}
+	}
+
+	all, err := ListAudit(AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAudit failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected entries before keepAfter to be trimmed, got %d", len(all))
+	}
+	if all[0].Action != "delete" || all[2].URL != "https://example.com/big.iso" || all[2].Detail != "/srv/dl" {
+		t.Errorf("Unexpected order or fields: %+v", all)
+	}
+
+	byActor, err := ListAudit(AuditFilter{Actor: "extension", Action: "add"})
+	if err != nil || len(byActor) != 1 || byActor[0].DownloadID != "d1" {
+		t.Errorf("Actor/action filter: %+v (%v)", byActor, err)
+	}
+	window, err := ListAudit(AuditFilter{Since: 1100, Until: 1200})
+	if err != nil || len(window) != 1 || window[0].Action != "pause" {
+		t.Errorf("Time filter: %+v (%v)", window, err)
+	}
+	limited, err := ListAudit(AuditFilter{Limit: 2})
+	if err != nil || len(limited) != 2 {
+		t.Errorf("Limit: %+v (%v)", limited, err)
+	}
+}
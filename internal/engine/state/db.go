@@ -39,9 +39,10 @@ func initDB() error {
 
 	// Ensure directory exists - caller should perhaps do this, but safe to do here if path is provided
 
-	// Open database
+	// Open database. The busy timeout makes concurrent writers (downloads, the
+	// event log) wait for each other instead of failing with SQLITE_BUSY.
 	var err error
-	db, err = sql.Open("sqlite", dbPath)
+	db, err = sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -70,6 +71,36 @@ func initDB() error {
 		length INTEGER,
 		FOREIGN KEY(download_id) REFERENCES downloads(id) ON DELETE CASCADE
 	);
+
+	CREATE TABLE IF NOT EXISTS events (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		data BLOB,
+		created_at INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		hash TEXT NOT NULL UNIQUE,
+		scope TEXT NOT NULL,
+		allowed_dirs TEXT,
+		expires_at INTEGER,
+		created_at INTEGER,
+		last_used_at INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time INTEGER NOT NULL,
+		actor TEXT,
+		addr TEXT,
+		action TEXT NOT NULL,
+		download_id TEXT,
+		url TEXT,
+		detail TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_time ON audit_log(time);
 	`
 
 	if _, err := db.Exec(query); err != nil {
@@ -89,6 +120,23 @@ func initDB() error {
 	// Migration: Add file_hash for integrity verification of paused downloads
 	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN file_hash TEXT")
 
+	// Migration: Add byte range columns for partial downloads
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN range_start INTEGER")
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN range_length INTEGER")
+
+	// Migration: Add custom request headers (JSON object) so resumes keep auth
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN headers TEXT")
+
+	// Migration: Add the validators of completed downloads, for conditional re-downloads
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN etag TEXT")
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN last_modified TEXT")
+
+	// Migration: Add per-download options so paused downloads resume with them
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN checksum TEXT")
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN max_connections INTEGER")
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN if_modified INTEGER")
+	_, _ = db.Exec("ALTER TABLE downloads ADD COLUMN delta_control TEXT")
+
 	return nil
 }
 
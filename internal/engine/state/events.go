@@ -0,0 +1,52 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Event is a server-sent event kept for Last-Event-ID replay
+type Event struct {
+	ID   int64
+	Name string
+	Data []byte
+}
+
+// AppendEvent stores an event and drops every stored event with an ID below keepFrom
+func AppendEvent(e Event, keepFrom int64) error {
+	return withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO events (id, name, data, created_at) VALUES (?, ?, ?, ?)",
+			e.ID, e.Name, e.Data, time.Now().Unix()); err != nil {
+			return fmt.Errorf("failed to insert event: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM events WHERE id < ?", keepFrom); err != nil {
+			return fmt.Errorf("failed to trim events: %w", err)
+		}
+		return nil
+	})
+}
+
+// LoadEvents returns up to limit of the most recent stored events, oldest first
+func LoadEvents(limit int) ([]Event, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query("SELECT id, name, data FROM (SELECT id, name, data FROM events ORDER BY id DESC LIMIT ?) ORDER BY id", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Name, &e.Data); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAppendLoadEvents(t *testing.T) {
+	tempDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	defer CloseDB()
+
+	for id := int64(1); id <= 5; id++ {
+		// Keep the three most recent
+		if err := AppendEvent(Event{ID: id, Name: "complete", Data: []byte(`{"DownloadID":"x"}`)}, id-2); err != nil {
+			t.Fatalf("AppendEvent failed: %v", err)
+		}
+	}
+
+	events, err := LoadEvents(10)
+	if err != nil {
+		t.Fatalf("LoadEvents failed: %v", err)
+	}
+	if len(events) != 3 || events[0].ID != 3 || events[2].ID != 5 {
+		t.Fatalf("Expected events 3..5, got %+v", events)
+	}
+	if events[0].Name != "complete" || string(events[0].Data) != `{"DownloadID":"x"}` {
+		t.Errorf("Event not round-tripped: %+v", events[0])
+	}
+
+	// The limit keeps the newest events
+	events, err = LoadEvents(2)
+	if err != nil {
+		t.Fatalf("LoadEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != 4 || events[1].ID != 5 {
+		t.Errorf("Expected events 4..5, got %+v", events)
+	}
+}
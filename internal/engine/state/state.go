@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -47,8 +48,8 @@ func SaveState(url string, destPath string, state *types.DownloadState) error {
 		// 1. Upsert into downloads table
 		_, err := tx.Exec(`
 			INSERT INTO downloads (
-				id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, file_hash
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, file_hash, range_start, range_length, headers, checksum, max_connections, if_modified, delta_control
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -62,8 +63,15 @@ func SaveState(url string, destPath string, state *types.DownloadState) error {
 				mirrors=excluded.mirrors,
 				chunk_bitmap=excluded.chunk_bitmap,
 				actual_chunk_size=excluded.actual_chunk_size,
-				file_hash=excluded.file_hash
-		`, state.ID, state.URL, state.DestPath, state.Filename, "paused", state.TotalSize, state.Downloaded, state.URLHash, state.CreatedAt, state.PausedAt, state.Elapsed/1e6, strings.Join(state.Mirrors, ","), state.ChunkBitmap, state.ActualChunkSize, state.FileHash)
+				file_hash=excluded.file_hash,
+				range_start=excluded.range_start,
+				range_length=excluded.range_length,
+				headers=excluded.headers,
+				checksum=excluded.checksum,
+				max_connections=excluded.max_connections,
+				if_modified=excluded.if_modified,
+				delta_control=excluded.delta_control
+		`, state.ID, state.URL, state.DestPath, state.Filename, "paused", state.TotalSize, state.Downloaded, state.URLHash, state.CreatedAt, state.PausedAt, state.Elapsed/1e6, strings.Join(state.Mirrors, ","), state.ChunkBitmap, state.ActualChunkSize, state.FileHash, state.RangeStart, state.RangeLength, encodeHeaders(state.Headers), state.Checksum, state.MaxConnections, state.IfModified, state.DeltaControl)
 		if err != nil {
 			return fmt.Errorf("failed to upsert download: %w", err)
 		}
@@ -140,12 +148,14 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 	}
 
 	var state types.DownloadState
-	var timeTaken, createdAt, pausedAt, actualChunkSize sql.NullInt64 // handle null
-	var mirrors, fileHash sql.NullString                              // handle null mirrors/hash
+	var timeTaken, createdAt, pausedAt, actualChunkSize sql.NullInt64     // handle null
+	var rangeStart, rangeLength, maxConns sql.NullInt64                   // handle null (pre-migration rows)
+	var mirrors, fileHash, headers, checksum, deltaControl sql.NullString // handle null mirrors/hash/headers
+	var ifModified sql.NullBool
 	var chunkBitmap []byte
 
 	row := db.QueryRow(`
-		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, file_hash
+		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, file_hash, range_start, range_length, headers, checksum, max_connections, if_modified, delta_control
 		FROM downloads 
 		WHERE url = ? AND dest_path = ? AND status != 'completed'
 		ORDER BY paused_at DESC LIMIT 1
@@ -155,6 +165,7 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 		&state.ID, &state.URL, &state.DestPath, &state.Filename,
 		&state.TotalSize, &state.Downloaded, &state.URLHash,
 		&createdAt, &pausedAt, &timeTaken, &mirrors, &chunkBitmap, &actualChunkSize, &fileHash,
+		&rangeStart, &rangeLength, &headers, &checksum, &maxConns, &ifModified, &deltaControl,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -183,6 +194,17 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 	if fileHash.Valid {
 		state.FileHash = fileHash.String
 	}
+	if rangeStart.Valid {
+		state.RangeStart = rangeStart.Int64
+	}
+	if rangeLength.Valid {
+		state.RangeLength = rangeLength.Int64
+	}
+	state.Headers = decodeHeaders(headers)
+	state.Checksum = checksum.String
+	state.MaxConnections = int(maxConns.Int64)
+	state.IfModified = ifModified.Bool
+	state.DeltaControl = deltaControl.String
 
 	// Load tasks
 	rows, err := db.Query("SELECT offset, length FROM tasks WHERE download_id = ?", state.ID)
@@ -321,8 +343,8 @@ func AddToMasterList(entry types.DownloadEntry) error {
 	return withTx(func(tx *sql.Tx) error {
 		_, err := tx.Exec(`
 			INSERT INTO downloads (
-				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed, etag, last_modified
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -334,10 +356,13 @@ func AddToMasterList(entry types.DownloadEntry) error {
 				time_taken=excluded.time_taken,
 				url_hash=excluded.url_hash,
 				mirrors=excluded.mirrors,
-				avg_speed=excluded.avg_speed
+				avg_speed=excluded.avg_speed,
+				etag=excluded.etag,
+				last_modified=excluded.last_modified
 		`,
 			entry.ID, entry.URL, entry.DestPath, entry.Filename, entry.Status, entry.TotalSize, entry.Downloaded,
-			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","), entry.AvgSpeed)
+			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","), entry.AvgSpeed,
+			entry.ETag, entry.LastModified)
 
 		return err
 	})
@@ -363,18 +388,18 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 
 	var e types.DownloadEntry
 	var completedAt, timeTaken sql.NullInt64
-	var urlHash, filename, mirrors sql.NullString
+	var urlHash, filename, mirrors, etag, lastModified sql.NullString
 	var avgSpeed sql.NullFloat64
 
 	row := db.QueryRow(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed 
+		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed, etag, last_modified
 		FROM downloads
 		WHERE id = ?
 	`, id)
 
 	if err := row.Scan(
 		&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-		&completedAt, &timeTaken, &urlHash, &mirrors, &avgSpeed,
+		&completedAt, &timeTaken, &urlHash, &mirrors, &avgSpeed, &etag, &lastModified,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -400,6 +425,8 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 	if avgSpeed.Valid {
 		e.AvgSpeed = avgSpeed.Float64
 	}
+	e.ETag = etag.String
+	e.LastModified = lastModified.String
 
 	return &e, nil
 }
@@ -454,6 +481,59 @@ func CheckDownloadExists(url string) (bool, error) {
 	return count > 0, nil
 }
 
+// LastCompleted returns the most recent completed download of url saved to
+// destPath, or nil if there is none
+func LastCompleted(url, destPath string) (*types.DownloadEntry, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var id string
+	err := db.QueryRow(`
+		SELECT id FROM downloads
+		WHERE url = ? AND dest_path = ? AND status = 'completed'
+		ORDER BY completed_at DESC
+		LIMIT 1
+	`, url, destPath).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download: %w", err)
+	}
+	return GetDownload(id)
+}
+
+// RenewCompleted records that the completed download oldID was found
+// unchanged by the download id. Its row takes the new ID, completion time and
+// size, and any validators given, keeping its transfer statistics, so
+// repeated checks of a file leave one history entry.
+func RenewCompleted(oldID string, entry types.DownloadEntry) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		UPDATE downloads SET
+			id = ?,
+			completed_at = ?,
+			total_size = ?,
+			downloaded = ?,
+			etag = COALESCE(NULLIF(?, ''), etag),
+			last_modified = COALESCE(NULLIF(?, ''), last_modified)
+		WHERE id = ? AND status = 'completed'
+	`, entry.ID, entry.CompletedAt, entry.TotalSize, entry.Downloaded, entry.ETag, entry.LastModified, oldID)
+	if err != nil {
+		return fmt.Errorf("failed to update download: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("completed download %s not found", oldID)
+	}
+	return nil
+}
+
 // UpdateStatus updates the status of a download by ID
 func UpdateStatus(id string, status string) error {
 	db := getDBHelper()
@@ -521,6 +601,27 @@ func RemoveCompletedDownloads() (int64, error) {
 	return count, nil
 }
 
+// RemoveDownloads removes the downloads with the given IDs in one transaction
+// and returns how many were removed
+func RemoveDownloads(ids []string) (int64, error) {
+	var count int64
+	err := withTx(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			result, err := tx.Exec("DELETE FROM downloads WHERE id = ?", id)
+			if err != nil {
+				return fmt.Errorf("failed to remove download %s: %w", id, err)
+			}
+			n, _ := result.RowsAffected()
+			count += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // LoadStates loads multiple download states from SQLite in batch
 func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 	if len(ids) == 0 {
@@ -543,7 +644,7 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 
 	// 1. Load Downloads
 	query := fmt.Sprintf(`
-		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size
+		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, range_start, range_length, headers, checksum, max_connections, if_modified, delta_control
 		FROM downloads
 		WHERE id IN (%s) AND status != 'completed'
 	`, inClause)
@@ -564,13 +665,16 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 	for rows.Next() {
 		var state types.DownloadState
 		var timeTaken, createdAt, pausedAt, actualChunkSize sql.NullInt64
-		var mirrors sql.NullString
+		var rangeStart, rangeLength, maxConns sql.NullInt64
+		var mirrors, headers, checksum, deltaControl sql.NullString
+		var ifModified sql.NullBool
 		var chunkBitmap []byte
 
 		if err := rows.Scan(
 			&state.ID, &state.URL, &state.DestPath, &state.Filename,
 			&state.TotalSize, &state.Downloaded, &state.URLHash,
 			&createdAt, &pausedAt, &timeTaken, &mirrors, &chunkBitmap, &actualChunkSize,
+			&rangeStart, &rangeLength, &headers, &checksum, &maxConns, &ifModified, &deltaControl,
 		); err != nil {
 			return nil, err
 		}
@@ -591,6 +695,17 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 			state.ActualChunkSize = actualChunkSize.Int64
 		}
 		state.ChunkBitmap = chunkBitmap
+		if rangeStart.Valid {
+			state.RangeStart = rangeStart.Int64
+		}
+		if rangeLength.Valid {
+			state.RangeLength = rangeLength.Int64
+		}
+		state.Headers = decodeHeaders(headers)
+		state.Checksum = checksum.String
+		state.MaxConnections = int(maxConns.Int64)
+		state.IfModified = ifModified.Bool
+		state.DeltaControl = deltaControl.String
 
 		states[state.ID] = &state
 	}
@@ -621,6 +736,30 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 	return states, nil
 }
 
+// encodeHeaders stores request headers as a JSON object, or NULL when there are none
+func encodeHeaders(headers map[string]string) interface{} {
+	if len(headers) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+func decodeHeaders(raw sql.NullString) map[string]string {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(raw.String), &headers); err != nil {
+		utils.Debug("Ignoring unreadable saved headers: %v", err)
+		return nil
+	}
+	return headers
+}
+
 // computeFileHash computes SHA-256 hash of a file for integrity verification.
 // Returns the hex-encoded hash or empty string on error.
 func computeFileHash(path string) (string, error) {
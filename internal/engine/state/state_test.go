@@ -533,6 +533,30 @@ func TestRemoveCompletedDownloads(t *testing.T) {
 	}
 }
 
+func TestRemoveDownloads(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, id := range []string{"keep", "drop-1", "drop-2"} {
+		if err := AddToMasterList(types.DownloadEntry{ID: id, URL: "https://d.com/" + id, DestPath: "/tmp/" + id, Status: "completed"}); err != nil {
+			t.Fatalf("AddToMasterList failed: %v", err)
+		}
+	}
+
+	count, err := RemoveDownloads([]string{"drop-1", "drop-2", "missing"})
+	if err != nil {
+		t.Fatalf("RemoveDownloads failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("RemoveDownloads returned count = %d, want 2", count)
+	}
+	downloads, _ := ListAllDownloads()
+	if len(downloads) != 1 || downloads[0].ID != "keep" {
+		t.Errorf("Expected only 'keep' to remain, got %+v", downloads)
+	}
+}
+
 func TestMirrorsPersistence(t *testing.T) {
 	tmpDir := setupTestDB(t)
 	defer func() { _ = os.RemoveAll(tmpDir) }()
@@ -613,6 +637,122 @@ func TestMirrorsPersistence(t *testing.T) {
 	}
 }
 
+func TestRangePersistence(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	testURL := "https://example.com/dataset.bin"
+	testDestPath := filepath.Join(tmpDir, "dataset.bin")
+
+	state := &types.DownloadState{
+		ID:          "range-state-id",
+		URL:         testURL,
+		DestPath:    testDestPath,
+		TotalSize:   4096,
+		Downloaded:  1024,
+		Filename:    "dataset.bin",
+		Tasks:       []types.Task{{Offset: 1024, Length: 3072}},
+		RangeStart:  1 << 30,
+		RangeLength: 4096,
+	}
+
+	if err := SaveState(testURL, testDestPath, state); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	loaded, err := LoadState(testURL, testDestPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if loaded.RangeStart != state.RangeStart || loaded.RangeLength != state.RangeLength {
+		t.Errorf("LoadState range = (%d, %d), want (%d, %d)", loaded.RangeStart, loaded.RangeLength, state.RangeStart, state.RangeLength)
+	}
+
+	batch, err := LoadStates([]string{state.ID})
+	if err != nil {
+		t.Fatalf("LoadStates failed: %v", err)
+	}
+	if got := batch[state.ID]; got == nil || got.RangeStart != state.RangeStart || got.RangeLength != state.RangeLength {
+		t.Errorf("LoadStates range mismatch: %+v", got)
+	}
+}
+
+func TestHeadersPersistence(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	testURL := "https://example.com/private.bin"
+	testDestPath := filepath.Join(tmpDir, "private.bin")
+
+	state := &types.DownloadState{
+		ID:       "headers-state-id",
+		URL:      testURL,
+		DestPath: testDestPath,
+		Filename: "private.bin",
+		Headers:  map[string]string{"Authorization": "Bearer abc", "Cookie": "session=1"},
+	}
+	if err := SaveState(testURL, testDestPath, state); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	loaded, err := LoadState(testURL, testDestPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if loaded.Headers["Authorization"] != "Bearer abc" || loaded.Headers["Cookie"] != "session=1" {
+		t.Errorf("LoadState headers = %v", loaded.Headers)
+	}
+
+	batch, err := LoadStates([]string{state.ID})
+	if err != nil {
+		t.Fatalf("LoadStates failed: %v", err)
+	}
+	if got := batch[state.ID]; got == nil || len(got.Headers) != 2 {
+		t.Errorf("LoadStates headers mismatch: %+v", got)
+	}
+}
+
+func TestOptionsPersistence(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	testURL := "https://example.com/checked.iso"
+	testDestPath := filepath.Join(tmpDir, "checked.iso")
+
+	state := &types.DownloadState{
+		ID:             "options-state-id",
+		URL:            testURL,
+		DestPath:       testDestPath,
+		Filename:       "checked.iso",
+		Checksum:       "sha-256=abcd",
+		MaxConnections: 2,
+		IfModified:     true,
+		DeltaControl:   "https://example.com/checked.iso.zsync",
+	}
+	if err := SaveState(testURL, testDestPath, state); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	loaded, err := LoadState(testURL, testDestPath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if loaded.Checksum != state.Checksum || loaded.MaxConnections != state.MaxConnections || !loaded.IfModified || loaded.DeltaControl != state.DeltaControl {
+		t.Errorf("LoadState options = %q, %d, %v, %q", loaded.Checksum, loaded.MaxConnections, loaded.IfModified, loaded.DeltaControl)
+	}
+
+	batch, err := LoadStates([]string{state.ID})
+	if err != nil {
+		t.Fatalf("LoadStates failed: %v", err)
+	}
+	if got := batch[state.ID]; got == nil || got.Checksum != state.Checksum || got.MaxConnections != state.MaxConnections || !got.IfModified || got.DeltaControl != state.DeltaControl {
+		t.Errorf("LoadStates options mismatch: %+v", got)
+	}
+}
+
 // =============================================================================
 // ValidateIntegrity Tests
 // =============================================================================
@@ -857,3 +997,65 @@ func TestAvgSpeedPersistence(t *testing.T) {
 		t.Error("Entry not found in master list")
 	}
 }
+
+func TestLastCompleted(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	url, dest := "https://d.com/nightly.tar", "/data/nightly.tar"
+	for _, e := range []types.DownloadEntry{
+		{ID: "old", URL: url, DestPath: dest, Status: "completed", CompletedAt: 100, ETag: `"a"`},
+		{ID: "new", URL: url, DestPath: dest, Status: "completed", CompletedAt: 200, ETag: `"b"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{ID: "failed", URL: url, DestPath: dest, Status: "error", CompletedAt: 300},
+		{ID: "elsewhere", URL: url, DestPath: "/other/nightly.tar", Status: "completed", CompletedAt: 400},
+	} {
+		if err := AddToMasterList(e); err != nil {
+			t.Fatalf("AddToMasterList failed: %v", err)
+		}
+	}
+
+	got, err := LastCompleted(url, dest)
+	if err != nil {
+		t.Fatalf("LastCompleted failed: %v", err)
+	}
+	if got == nil || got.ID != "new" || got.ETag != `"b"` || got.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("LastCompleted = %+v, want the newest completed entry with its validators", got)
+	}
+
+	if got, err := LastCompleted(url, "/missing"); err != nil || got != nil {
+		t.Errorf("Expected no entry for an unknown path, got %+v, %v", got, err)
+	}
+}
+
+func TestRenewCompleted(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	url, dest := "https://d.com/nightly.tar", "/data/nightly.tar"
+	if err := AddToMasterList(types.DownloadEntry{
+		ID: "first", URL: url, DestPath: dest, Status: "completed", CompletedAt: 100,
+		TotalSize: 10, TimeTaken: 5000, ETag: `"a"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+	}); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	if err := RenewCompleted("first", types.DownloadEntry{ID: "check", CompletedAt: 200, TotalSize: 10, Downloaded: 10, ETag: `"a"`}); err != nil {
+		t.Fatalf("RenewCompleted failed: %v", err)
+	}
+	got, err := LastCompleted(url, dest)
+	if err != nil || got == nil {
+		t.Fatalf("LastCompleted = %+v, %v", got, err)
+	}
+	if got.ID != "check" || got.CompletedAt != 200 || got.TimeTaken != 5000 || got.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("Expected the renewed entry with its statistics and validators kept, got %+v", got)
+	}
+	if old, _ := GetDownload("first"); old != nil {
+		t.Errorf("Expected the old ID to be gone, got %+v", old)
+	}
+
+	if err := RenewCompleted("missing", types.DownloadEntry{ID: "x"}); err == nil {
+		t.Error("Expected an error for an unknown download")
+	}
+}
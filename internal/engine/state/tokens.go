@@ -0,0 +1,134 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// APIToken is a named API client credential. Only a hash of the secret is stored.
+type APIToken struct {
+	ID          string
+	Name        string
+	Hash        string
+	Scope       string
+	AllowedDirs []string // Empty means any directory
+	ExpiresAt   int64    // Unix time; 0 means never
+	CreatedAt   int64
+	LastUsedAt  int64
+}
+
+// ErrTokenExists is returned when creating a token whose name is taken
+var ErrTokenExists = errors.New("a token with that name already exists")
+
+const tokenColumns = "id, name, hash, scope, allowed_dirs, expires_at, created_at, last_used_at"
+
+// CreateToken stores a new API token
+func CreateToken(t APIToken) error {
+	dirs, err := json.Marshal(t.AllowedDirs)
+	if err != nil {
+		return fmt.Errorf("failed to encode allowed dirs: %w", err)
+	}
+	return withTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE name = ?", t.Name).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check token name: %w", err)
+		}
+		if exists > 0 {
+			return ErrTokenExists
+		}
+		if _, err := tx.Exec("INSERT INTO api_tokens ("+tokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			t.ID, t.Name, t.Hash, t.Scope, string(dirs), t.ExpiresAt, t.CreatedAt, t.LastUsedAt); err != nil {
+			return fmt.Errorf("failed to insert token: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListTokens returns every stored token, oldest first
+func ListTokens() ([]APIToken, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query("SELECT " + tokenColumns + " FROM api_tokens ORDER BY created_at, name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tokens: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []APIToken
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *t)
+	}
+	return result, rows.Err()
+}
+
+// GetTokenByHash returns the token with the given secret hash, or nil if none matches
+func GetTokenByHash(hash string) (*APIToken, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	t, err := scanToken(db.QueryRow("SELECT "+tokenColumns+" FROM api_tokens WHERE hash = ?", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// TouchToken records that a token was used at the given Unix time
+func TouchToken(id string, at int64) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if _, err := db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", at, id); err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	return nil
+}
+
+// RevokeToken deletes the token with the given name or ID and reports whether one existed
+func RevokeToken(nameOrID string) (bool, error) {
+	db := getDBHelper()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+	res, err := db.Exec("DELETE FROM api_tokens WHERE name = ? OR id = ?", nameOrID, nameOrID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanToken(row rowScanner) (*APIToken, error) {
+	var t APIToken
+	var dirs sql.NullString
+	var expires, created, used sql.NullInt64
+	if err := row.Scan(&t.ID, &t.Name, &t.Hash, &t.Scope, &dirs, &expires, &created, &used); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan token: %w", err)
+	}
+	if dirs.Valid && dirs.String != "" {
+		if err := json.Unmarshal([]byte(dirs.String), &t.AllowedDirs); err != nil {
+			return nil, fmt.Errorf("failed to decode allowed dirs: %w", err)
+		}
+	}
+	t.ExpiresAt, t.CreatedAt, t.LastUsedAt = expires.Int64, created.Int64, used.Int64
+	return &t, nil
+}
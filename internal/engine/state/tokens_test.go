@@ -0,0 +1,47 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestTokens_CreateLookupRevoke(t *testing.T) {
+	tempDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	defer CloseDB()
+
+	tok := APIToken{ID: "t1", Name: "extension", Hash: "h1", Scope: "add", AllowedDirs: []string{"/srv/dl"}, ExpiresAt: 2000, CreatedAt: 1000}
+	if err := CreateToken(tok); err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if err := CreateToken(APIToken{ID: "t2", Name: "extension", Hash: "h2", Scope: "read"}); !errors.Is(err, ErrTokenExists) {
+		t.Errorf("Expected ErrTokenExists, got %v", err)
+	}
+
+	got, err := GetTokenByHash("h1")
+	if err != nil || got == nil {
+		t.Fatalf("GetTokenByHash failed: %v", err)
+	}
+	if got.Name != "extension" || got.Scope != "add" || len(got.AllowedDirs) != 1 || got.AllowedDirs[0] != "/srv/dl" || got.ExpiresAt != 2000 {
+		t.Errorf("Token not round-tripped: %+v", got)
+	}
+	if missing, err := GetTokenByHash("nope"); err != nil || missing != nil {
+		t.Errorf("Expected no token, got %+v (%v)", missing, err)
+	}
+
+	if err := TouchToken("t1", 1500); err != nil {
+		t.Fatalf("TouchToken failed: %v", err)
+	}
+	list, err := ListTokens()
+	if err != nil || len(list) != 1 || list[0].LastUsedAt != 1500 {
+		t.Fatalf("Unexpected token list %+v (%v)", list, err)
+	}
+
+	if ok, err := RevokeToken("extension"); err != nil || !ok {
+		t.Errorf("RevokeToken by name failed: %v %v", ok, err)
+	}
+	if ok, _ := RevokeToken("t1"); ok {
+		t.Error("Revoking twice should report no token")
+	}
+}
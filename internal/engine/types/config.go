@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -64,6 +65,59 @@ type DownloadConfig struct {
 	Runtime    *RuntimeConfig    // Dynamic settings from user config
 	Mirrors    []string          // List of mirror URLs (including primary)
 	Headers    map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
+
+	RangeStart  int64 // First byte of the resource to fetch (partial downloads)
+	RangeLength int64 // Bytes to fetch from RangeStart; 0 means through the end of the resource
+
+	Seeds        []string // Local files or directories whose matching blocks are reused (delta updates)
+	DeltaControl string   // .zsync or block hash list URL/path; defaults to URL + ".zsync"
+
+	Checksum       string // Expected digest as "<algorithm>=<hex>", verified once the download completes
+	MaxConnections int    // Caps the connections for this download below max_connections_per_host; 0 for no cap
+
+	IfModified bool // Skip the download when the local file is still current, and otherwise replace it in place
+}
+
+// DownloadOptions holds optional per-download parameters that most callers leave unset
+type DownloadOptions struct {
+	RangeStart  int64 // First byte of the resource to fetch
+	RangeLength int64 // Bytes to fetch from RangeStart; 0 means through the end of the resource
+
+	Seeds        []string // Local files or directories to reuse blocks from
+	DeltaControl string   // Control file describing the target's blocks (URL or path)
+
+	Checksum       string // Expected digest as "<algorithm>=<hex>", e.g. "sha-256=..."
+	MaxConnections int    // Most connections to open for this download; 0 uses the settings
+
+	IfModified bool // Only download when the remote file differs from the local copy, replacing it
+}
+
+// IsPartial reports whether only a slice of the remote resource was requested
+func (c *DownloadConfig) IsPartial() bool {
+	return c.RangeStart > 0 || c.RangeLength > 0
+}
+
+// ResolveRange clamps the requested span to a resource of fileSize bytes and returns
+// where it starts and how many bytes it covers. Full downloads return (0, fileSize).
+func (c *DownloadConfig) ResolveRange(fileSize int64) (start int64, length int64, err error) {
+	if !c.IsPartial() {
+		return 0, fileSize, nil
+	}
+	if c.RangeStart < 0 || c.RangeLength < 0 {
+		return 0, 0, fmt.Errorf("%w: negative range", ErrRangeNotSatisfiable)
+	}
+	if fileSize <= 0 {
+		return 0, 0, fmt.Errorf("%w: server did not report the file size", ErrRangeNotSatisfiable)
+	}
+	if c.RangeStart >= fileSize {
+		return 0, 0, fmt.Errorf("%w: start %d is beyond the end of the file (%d bytes)", ErrRangeNotSatisfiable, c.RangeStart, fileSize)
+	}
+
+	length = fileSize - c.RangeStart
+	if c.RangeLength > 0 && c.RangeLength < length {
+		length = c.RangeLength
+	}
+	return c.RangeStart, length, nil
 }
 
 // RuntimeConfig holds dynamic settings that can override defaults
@@ -80,6 +134,7 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	MinFreeDiskSpace      int64 // Bytes to keep free on the destination filesystem (0 disables)
 }
 
 // GetUserAgent returns the configured user agent or the default
@@ -165,3 +220,11 @@ func (r *RuntimeConfig) GetSpeedEmaAlpha() float64 {
 	}
 	return r.SpeedEmaAlpha
 }
+
+// GetMinFreeDiskSpace returns the configured free space floor in bytes (0 when disabled)
+func (r *RuntimeConfig) GetMinFreeDiskSpace() int64 {
+	if r == nil || r.MinFreeDiskSpace < 0 {
+		return 0
+	}
+	return r.MinFreeDiskSpace
+}
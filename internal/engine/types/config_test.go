@@ -1,6 +1,7 @@
 package types
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -193,3 +194,45 @@ func TestDownloadConfig_Fields(t *testing.T) {
 		t.Error("Runtime not set correctly")
 	}
 }
+
+func TestDownloadConfig_ResolveRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		start      int64
+		length     int64
+		fileSize   int64
+		wantStart  int64
+		wantLength int64
+		wantErr    bool
+	}{
+		{"full download", 0, 0, 1000, 0, 1000, false},
+		{"full download unknown size", 0, 0, 0, 0, 0, false},
+		{"head", 0, 100, 1000, 0, 100, false},
+		{"head larger than file", 0, 5000, 1000, 0, 1000, false},
+		{"middle slice", 200, 300, 1000, 200, 300, false},
+		{"open-ended", 900, 0, 1000, 900, 100, false},
+		{"slice clamped at end", 900, 500, 1000, 900, 100, false},
+		{"start past end", 1000, 10, 1000, 0, 0, true},
+		{"unknown size", 10, 10, 0, 0, 0, true},
+		{"negative length", 10, -1, 1000, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DownloadConfig{RangeStart: tt.start, RangeLength: tt.length}
+			start, length, err := cfg.ResolveRange(tt.fileSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrRangeNotSatisfiable) {
+					t.Errorf("Expected ErrRangeNotSatisfiable, got %v", err)
+				}
+				return
+			}
+			if start != tt.wantStart || length != tt.wantLength {
+				t.Errorf("ResolveRange = (%d, %d), want (%d, %d)", start, length, tt.wantStart, tt.wantLength)
+			}
+		})
+	}
+}
@@ -1,8 +1,23 @@
 package types
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Common errors
 var (
-	ErrPaused = errors.New("download paused")
+	ErrPaused                = errors.New("download paused")
+	ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+	ErrRangeNotSatisfiable   = errors.New("requested range not satisfiable")
+	ErrRangeNotSupported     = errors.New("server does not support range requests")
 )
+
+// HTTPStatusError reports a response whose status code the downloader can't use
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
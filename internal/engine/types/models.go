@@ -21,12 +21,24 @@ type DownloadState struct {
 	Elapsed    int64    `json:"elapsed"`    // Elapsed time in nanoseconds
 	Mirrors    []string `json:"mirrors,omitempty"`
 
+	Headers map[string]string `json:"headers,omitempty"` // Custom HTTP headers sent with every request
+
 	// Bitmap state
 	ChunkBitmap     []byte `json:"chunk_bitmap,omitempty"`
 	ActualChunkSize int64  `json:"actual_chunk_size,omitempty"`
 
 	// Integrity verification
 	FileHash string `json:"file_hash,omitempty"` // SHA-256 hash of the .surge file at pause time
+
+	// Partial downloads: the slice of the remote resource this file holds
+	RangeStart  int64 `json:"range_start,omitempty"`
+	RangeLength int64 `json:"range_length,omitempty"`
+
+	// Per-download options that a resume must keep
+	Checksum       string `json:"checksum,omitempty"`        // Expected digest as "<algorithm>=<hex>"
+	MaxConnections int    `json:"max_connections,omitempty"` // Connection cap; 0 for none
+	IfModified     bool   `json:"if_modified,omitempty"`     // Replaces an existing file when complete
+	DeltaControl   string `json:"delta_control,omitempty"`   // Control file of a delta update, to verify the result
 }
 
 // DownloadEntry represents a download in the master list
@@ -43,6 +55,9 @@ type DownloadEntry struct {
 	TimeTaken   int64    `json:"time_taken"`   // Duration in milliseconds (for completed)
 	AvgSpeed    float64  `json:"avg_speed"`    // Average speed in bytes/sec (for completed)
 	Mirrors     []string `json:"mirrors,omitempty"`
+
+	ETag         string `json:"etag,omitempty"`          // Server ETag of the completed file
+	LastModified string `json:"last_modified,omitempty"` // Server Last-Modified of the completed file
 }
 
 // MasterList holds all tracked downloads
@@ -36,6 +36,8 @@ type ProgressState struct {
 	ActualChunkSize int64   // Size of each actual chunk in bytes
 	BitmapWidth     int     // Number of chunks tracked
 
+	streamOffset atomic.Int64 // 1 + offset a stream reader is waiting on (0 = none)
+
 	mu sync.Mutex // Protects TotalSize, StartTime, SessionStartBytes, SavedElapsed, Mirrors
 }
 
@@ -487,3 +489,48 @@ func (ps *ProgressState) GetBitmap() ([]byte, int, int64, int64, []int64) {
 
 	return result, ps.BitmapWidth, ps.TotalSize, ps.ActualChunkSize, progressResult
 }
+
+// ContiguousAvailable returns the end (exclusive) of the run of fully downloaded
+// chunks starting at offset. It returns offset itself if that byte isn't on disk yet.
+func (ps *ProgressState) ContiguousAvailable(offset int64) int64 {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.Done.Load() {
+		return ps.TotalSize
+	}
+	if ps.ActualChunkSize <= 0 || len(ps.ChunkBitmap) == 0 || offset < 0 {
+		return offset
+	}
+
+	idx := int(offset / ps.ActualChunkSize)
+	for idx < ps.BitmapWidth && ps.getChunkState(idx) == ChunkCompleted {
+		idx++
+	}
+
+	end := int64(idx) * ps.ActualChunkSize
+	if end > ps.TotalSize {
+		end = ps.TotalSize
+	}
+	if end < offset {
+		return offset
+	}
+	return end
+}
+
+// RequestStreamOffset asks the downloader to fetch the bytes at offset next
+func (ps *ProgressState) RequestStreamOffset(offset int64) {
+	if offset < 0 {
+		return
+	}
+	ps.streamOffset.Store(offset + 1)
+}
+
+// TakeStreamOffset returns and clears the pending stream offset request, if any
+func (ps *ProgressState) TakeStreamOffset() (int64, bool) {
+	v := ps.streamOffset.Swap(0)
+	if v == 0 {
+		return 0, false
+	}
+	return v - 1, true
+}
@@ -192,3 +192,56 @@ func TestProgressState_ElapsedCalculation(t *testing.T) {
 		t.Errorf("TotalElapsed = %v, want ~7s", totalElapsed)
 	}
 }
+
+func TestProgressState_ContiguousAvailable(t *testing.T) {
+	ps := NewProgressState("stream", 1000)
+	ps.InitBitmap(1000, 100)
+
+	if got := ps.ContiguousAvailable(0); got != 0 {
+		t.Errorf("ContiguousAvailable(0) before any data = %d, want 0", got)
+	}
+
+	ps.UpdateChunkStatus(0, 300, ChunkCompleted)
+	ps.UpdateChunkStatus(300, 50, ChunkCompleted) // Partial chunk doesn't count
+	ps.UpdateChunkStatus(500, 100, ChunkCompleted)
+
+	tests := []struct {
+		offset int64
+		want   int64
+	}{
+		{0, 300},
+		{150, 300},
+		{300, 300},
+		{400, 400},
+		{500, 600},
+	}
+	for _, tt := range tests {
+		if got := ps.ContiguousAvailable(tt.offset); got != tt.want {
+			t.Errorf("ContiguousAvailable(%d) = %d, want %d", tt.offset, got, tt.want)
+		}
+	}
+
+	ps.Done.Store(true)
+	if got := ps.ContiguousAvailable(400); got != 1000 {
+		t.Errorf("ContiguousAvailable after Done = %d, want 1000", got)
+	}
+}
+
+func TestProgressState_StreamOffset(t *testing.T) {
+	ps := NewProgressState("stream", 1000)
+
+	if _, ok := ps.TakeStreamOffset(); ok {
+		t.Error("Expected no pending stream offset")
+	}
+
+	ps.RequestStreamOffset(0)
+	ps.RequestStreamOffset(512)
+
+	offset, ok := ps.TakeStreamOffset()
+	if !ok || offset != 512 {
+		t.Errorf("TakeStreamOffset = (%d, %v), want (512, true)", offset, ok)
+	}
+	if _, ok := ps.TakeStreamOffset(); ok {
+		t.Error("Expected stream offset to be cleared after take")
+	}
+}
@@ -70,7 +70,7 @@ func (s DownloadStatus) RenderIcon() string {
 
 // DetermineStatus determines the DownloadStatus based on download state
 // This centralizes the status determination logic that was duplicated in view.go and list.go
-func DetermineStatus(done bool, paused bool, hasError bool, speed float64, downloaded int64) DownloadStatus {
+func DetermineStatus(done bool, paused bool, queued bool, hasError bool, speed float64, downloaded int64) DownloadStatus {
 	switch {
 	case hasError:
 		return StatusError
@@ -78,7 +78,7 @@ func DetermineStatus(done bool, paused bool, hasError bool, speed float64, downl
 		return StatusComplete
 	case paused:
 		return StatusPaused
-	case speed == 0 && downloaded == 0:
+	case queued, speed == 0 && downloaded == 0:
 		return StatusQueued
 	default:
 		return StatusDownloading
@@ -32,7 +32,7 @@ func (i DownloadItem) Description() string {
 		// Custom "Pausing..." style using existing colors
 		styledStatus = lipgloss.NewStyle().Foreground(colors.StatePaused).Render("⏸ Pausing...")
 	} else {
-		styledStatus = components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded).Render()
+		styledStatus = components.DetermineStatus(d.done, d.paused, d.queued, d.err != nil, d.Speed, d.Downloaded).Render()
 	}
 
 	// Build progress info
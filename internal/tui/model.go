@@ -15,6 +15,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/surge-downloader/surge/internal/batch"
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/engine/types"
@@ -70,6 +71,7 @@ type DownloadModel struct {
 	paused        bool
 	pausing       bool // UI state: transitioning to pause
 	pendingResume bool // UI state: waiting for async resume
+	queued        bool // Waiting for the server to start it
 }
 
 type RootModel struct {
@@ -104,7 +106,8 @@ type RootModel struct {
 	pendingFilename string   // Filename pending confirmation
 	pendingMirrors  []string // Mirrors pending confirmation
 	pendingHeaders  map[string]string
-	duplicateInfo   string // Info about the duplicate
+	pendingOptions  types.DownloadOptions // Per-download options (e.g. byte range) pending confirmation
+	duplicateInfo   string                // Info about the duplicate
 
 	// Graph Data
 	SpeedHistory           []float64 // Stores the last ~60 ticks of speed data
@@ -133,8 +136,8 @@ type RootModel struct {
 	searchQuery  string          // Current search query
 
 	// Batch import
-	pendingBatchURLs []string // URLs pending batch import
-	batchFilePath    string   // Path to the batch file
+	pendingBatch []batch.Entry // Downloads pending batch import
+	batchSource  string        // Batch file path or URL pattern
 
 	// Keybindings
 	keys KeyMap
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -257,6 +258,9 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["clipboard_monitor"] = m.Settings.General.ClipboardMonitor
 		values["theme"] = m.Settings.General.Theme
 		values["log_retention_count"] = m.Settings.General.LogRetentionCount
+		values["min_free_disk_space"] = m.Settings.General.MinFreeDiskSpace
+		values["persist_events"] = m.Settings.General.PersistEvents
+		values["allowed_download_dirs"] = m.Settings.General.AllowedDownloadDirs
 
 	case "Network":
 		values["max_connections_per_host"] = m.Settings.Network.MaxConnectionsPerHost
@@ -306,6 +310,8 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 	switch key {
 	case "default_download_dir":
 		m.Settings.General.DefaultDownloadDir = value
+	case "allowed_download_dirs":
+		m.Settings.General.AllowedDownloadDirs = parseSettingList(value)
 	case "warn_on_duplicate":
 		m.Settings.General.WarnOnDuplicate = !m.Settings.General.WarnOnDuplicate
 	case "extension_prompt":
@@ -316,6 +322,8 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 		m.Settings.General.SkipUpdateCheck = !m.Settings.General.SkipUpdateCheck
 	case "clipboard_monitor":
 		m.Settings.General.ClipboardMonitor = !m.Settings.General.ClipboardMonitor
+	case "persist_events":
+		m.Settings.General.PersistEvents = !m.Settings.General.PersistEvents
 
 	case "theme":
 		var theme int
@@ -348,6 +356,14 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 			}
 			m.Settings.General.LogRetentionCount = v
 		}
+	case "min_free_disk_space":
+		// Parse as MB and convert to bytes
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			if v < 0 {
+				v = 0
+			}
+			m.Settings.General.MinFreeDiskSpace = int64(v * 1024 * 1024)
+		}
 	}
 	return nil
 }
@@ -495,7 +511,7 @@ func (m RootModel) getSettingsCount() int {
 func (m RootModel) getSettingUnit() string {
 	key := m.getCurrentSettingKey()
 	switch key {
-	case "min_chunk_size":
+	case "min_chunk_size", "min_free_disk_space":
 		return " MB"
 	case "worker_buffer_size":
 		return " KB"
@@ -513,7 +529,7 @@ func (m RootModel) getSettingUnit() string {
 // formatSettingValueForEdit returns a plain value without units for editing
 func formatSettingValueForEdit(value interface{}, typ, key string) string {
 	switch key {
-	case "min_chunk_size":
+	case "min_chunk_size", "min_free_disk_space":
 		if v, ok := value.(int64); ok {
 			mb := float64(v) / (1024 * 1024)
 			return fmt.Sprintf("%.1f", mb)
@@ -531,6 +547,12 @@ func formatSettingValueForEdit(value interface{}, typ, key string) string {
 		}
 	}
 
+	if typ == "list" {
+		if v, ok := value.([]string); ok {
+			return strings.Join(v, string(filepath.ListSeparator))
+		}
+	}
+
 	if key == "theme" {
 		if v, ok := value.(int); ok {
 			switch v {
@@ -580,6 +602,17 @@ func formatSettingValue(value interface{}, typ string) string {
 		if v, ok := value.(float64); ok {
 			return fmt.Sprintf("%.2f", v)
 		}
+	case "list":
+		if v, ok := value.([]string); ok {
+			if len(v) == 0 {
+				return "(any)"
+			}
+			s := strings.Join(v, ", ")
+			if len(s) > 30 {
+				return s[:27] + "..."
+			}
+			return s
+		}
 	case "string":
 		if s, ok := value.(string); ok {
 			if s == "" {
@@ -626,6 +659,12 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.General.Theme = defaults.General.Theme
 		case "log_retention_count":
 			m.Settings.General.LogRetentionCount = defaults.General.LogRetentionCount
+		case "min_free_disk_space":
+			m.Settings.General.MinFreeDiskSpace = defaults.General.MinFreeDiskSpace
+		case "persist_events":
+			m.Settings.General.PersistEvents = defaults.General.PersistEvents
+		case "allowed_download_dirs":
+			m.Settings.General.AllowedDownloadDirs = defaults.General.AllowedDownloadDirs
 		}
 
 	case "Network":
@@ -660,3 +699,14 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 		}
 	}
 }
+
+// parseSettingList splits a list setting typed as one line on the OS list separator
+func parseSettingList(value string) []string {
+	var items []string
+	for _, item := range filepath.SplitList(value) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
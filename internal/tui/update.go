@@ -1,7 +1,7 @@
 package tui
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/batch"
 	"github.com/surge-downloader/surge/internal/clipboard"
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/engine/events"
@@ -68,43 +69,29 @@ func openFile(path string) error {
 	return cmd.Start()
 }
 
-// readURLsFromFile reads URLs from a file, one per line (skips empty lines, comments, and duplicates)
-func readURLsFromFile(filepath string) ([]string, error) {
-	file, err := os.Open(filepath)
+// readBatchFile reads the downloads of a batch file, skipping duplicate URLs
+func readBatchFile(path string) ([]batch.Entry, error) {
+	parsed, err := batch.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			utils.Debug("Error closing file: %v", err)
-		}
-	}()
 
-	var urls []string
+	var entries []batch.Entry
 	seen := make(map[string]bool)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line != "" && !strings.HasPrefix(line, "#") {
-			// Normalize URL for duplicate detection
-			normalized := strings.TrimRight(line, "/")
-			if !seen[normalized] {
-				seen[normalized] = true
-				urls = append(urls, line)
-			}
+	for _, e := range parsed {
+		// Normalize URL for duplicate detection
+		normalized := strings.TrimRight(e.URL, "/")
+		if !seen[normalized] {
+			seen[normalized] = true
+			entries = append(entries, e)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	if len(urls) == 0 {
+	if len(entries) == 0 {
 		return nil, fmt.Errorf("no URLs found in file")
 	}
 
-	return urls, nil
+	return entries, nil
 }
 
 // addLogEntry adds a log entry to the log viewport
@@ -136,6 +123,55 @@ func (m *RootModel) removeDownloadByID(id string) bool {
 	return false
 }
 
+// applySnapshot replaces the download list with the daemon's full state, keeping
+// existing models (and their progress history) for downloads that still exist
+func (m *RootModel) applySnapshot(statuses []types.DownloadStatus) {
+	existing := make(map[string]*DownloadModel, len(m.downloads))
+	for _, d := range m.downloads {
+		existing[d.ID] = d
+	}
+
+	downloads := make([]*DownloadModel, 0, len(statuses))
+	for _, s := range statuses {
+		d := existing[s.ID]
+		if d == nil {
+			d = NewDownloadModel(s.ID, s.URL, s.Filename, s.TotalSize)
+		}
+		d.Total = s.TotalSize
+		d.Downloaded = s.Downloaded
+		if s.DestPath != "" {
+			d.Destination = s.DestPath
+		}
+		d.done, d.err = false, nil
+		d.paused, d.pausing, d.pendingResume, d.queued = false, false, false, false
+
+		switch s.Status {
+		case "completed":
+			d.done = true
+			d.Downloaded = d.Total
+			d.progress.SetPercent(1.0)
+		case "error":
+			d.done = true
+			msg := s.Error
+			if msg == "" {
+				msg = "download failed"
+			}
+			d.err = errors.New(msg)
+		case "paused":
+			d.paused = true
+		case "queued":
+			d.queued = true
+		case "pausing":
+			d.pausing = true
+		}
+		if s.Status != "completed" && s.TotalSize > 0 {
+			d.progress.SetPercent(s.Progress / 100.0)
+		}
+		downloads = append(downloads, d)
+	}
+	m.downloads = downloads
+}
+
 // checkForDuplicate checks if a compatible download already exists
 func (m RootModel) checkForDuplicate(url string) *DownloadModel {
 	if !m.Settings.General.WarnOnDuplicate {
@@ -157,6 +193,11 @@ func (m RootModel) checkForDuplicate(url string) *DownloadModel {
 
 // startDownload initiates a new download
 func (m RootModel) startDownload(url string, mirrors []string, headers map[string]string, path, filename, id string) (RootModel, tea.Cmd) {
+	return m.startDownloadWithOptions(url, mirrors, headers, path, filename, id, types.DownloadOptions{})
+}
+
+// startDownloadWithOptions initiates a new download with per-download options
+func (m RootModel) startDownloadWithOptions(url string, mirrors []string, headers map[string]string, path, filename, id string, opts types.DownloadOptions) (RootModel, tea.Cmd) {
 	if m.Service == nil {
 		m.addLogEntry(LogStyleError.Render("✖ Service unavailable"))
 		return m, nil
@@ -175,7 +216,7 @@ func (m RootModel) startDownload(url string, mirrors []string, headers map[strin
 	// We rely on the event stream to update the UI, OR we add it optimistically.
 	// Optimistic addition gives better UX.
 
-	newID, err := m.Service.Add(url, path, finalFilename, mirrors, headers)
+	newID, err := m.Service.AddWithOptions(url, path, finalFilename, mirrors, headers, opts)
 	if err != nil {
 		m.addLogEntry(LogStyleError.Render("✖ Failed to add download: " + err.Error()))
 		return m, nil
@@ -236,6 +277,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingURL = msg.URL
 			m.pendingMirrors = msg.Mirrors
 			m.pendingHeaders = msg.Headers
+			m.pendingOptions = msg.Options
 			m.pendingPath = path
 			m.pendingFilename = msg.Filename
 			m.duplicateInfo = duplicate.Filename
@@ -247,13 +289,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingURL = msg.URL
 			m.pendingMirrors = msg.Mirrors
 			m.pendingHeaders = msg.Headers
+			m.pendingOptions = msg.Options
 			m.pendingPath = path
 			m.pendingFilename = msg.Filename
 			m.state = ExtensionConfirmationState
 			return m, nil
 		}
 
-		return m.startDownload(msg.URL, msg.Mirrors, msg.Headers, path, msg.Filename, msg.ID)
+		return m.startDownloadWithOptions(msg.URL, msg.Mirrors, msg.Headers, path, msg.Filename, msg.ID, msg.Options)
 
 	case events.DownloadStartedMsg:
 		found := false
@@ -267,6 +310,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				d.paused = false
 				d.pausing = false
 				d.pendingResume = false
+				d.queued = false
 				// Update progress bar
 				if d.Total > 0 {
 					d.progress.SetPercent(0)
@@ -322,7 +366,11 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if msg.Elapsed.Seconds() > 0 {
 					speed = float64(d.Total) / msg.Elapsed.Seconds()
 				}
-				m.addLogEntry(LogStyleComplete.Render(fmt.Sprintf("✔ Done: %s (%.2f MB/s)", d.Filename, speed/Megabyte)))
+				if msg.NotModified {
+					m.addLogEntry(LogStyleComplete.Render("✔ Unchanged: " + d.Filename))
+				} else {
+					m.addLogEntry(LogStyleComplete.Render(fmt.Sprintf("✔ Done: %s (%.2f MB/s)", d.Filename, speed/Megabyte)))
+				}
 				break
 			}
 		}
@@ -347,9 +395,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				d.paused = true
 				d.pausing = false
 				d.pendingResume = false
+				d.queued = false
 				d.Downloaded = msg.Downloaded
 				d.Speed = 0
-				m.addLogEntry(LogStylePaused.Render("⏸ Paused: " + d.Filename))
+				if msg.Reason != "" {
+					m.addLogEntry(LogStylePaused.Render("⏸ Paused: " + d.Filename + " (" + msg.Reason + ")"))
+				} else {
+					m.addLogEntry(LogStylePaused.Render("⏸ Paused: " + d.Filename))
+				}
 				break
 			}
 		}
@@ -362,6 +415,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				d.paused = false
 				d.pausing = false
 				d.pendingResume = false
+				d.queued = false
 				m.addLogEntry(LogStyleStarted.Render("▶ Resumed: " + d.Filename))
 				break
 			}
@@ -395,6 +449,12 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Batch(cmds...)
 
+	case events.SnapshotMsg:
+		m.applySnapshot(msg.Downloads)
+		m.addLogEntry(LogStyleStarted.Render("↻ Resynced with server"))
+		m.UpdateListItems()
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -458,8 +518,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Check if a file was selected
 			if didSelect, path := m.filepicker.DidSelectFile(msg); didSelect {
-				// Read URLs from file
-				urls, err := readURLsFromFile(path)
+				// Read downloads from file
+				entries, err := readBatchFile(path)
 				if err != nil {
 					m.addLogEntry(LogStyleError.Render("✖ Failed to read batch file: " + err.Error()))
 					// Reset filepicker and return
@@ -469,9 +529,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
-				// Store pending URLs and show confirmation
-				m.pendingBatchURLs = urls
-				m.batchFilePath = path
+				// Store pending downloads and show confirmation
+				m.pendingBatch = entries
+				m.batchSource = path
 
 				// Reset filepicker to directory mode
 				m.filepicker.FileAllowed = false
@@ -829,11 +889,34 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				filename := m.inputs[3].Value()
 
+				// A URL pattern queues one download per URL it generates, after a preview
+				if batch.HasPattern(inputVal) {
+					entry := batch.ParseURIs(inputVal)
+					entry.Mirrors = append(entry.Mirrors, batch.ParseURIs(mirrorsVal).Mirrors...)
+					entry.Dir = utils.EnsureAbsPath(path)
+					entry.Filename = filename
+					entries, err := entry.Finish()
+					if err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Invalid URL pattern: " + err.Error()))
+						return m, nil
+					}
+
+					m.pendingBatch = entries
+					m.batchSource = inputVal
+					m.inputs[0].SetValue("")
+					m.inputs[1].SetValue("")
+					m.inputs[2].SetValue(path) // Keep path
+					m.inputs[3].SetValue("")
+					m.state = BatchConfirmState
+					return m, nil
+				}
+
 				// Check for duplicate URL
 				if d := m.checkForDuplicate(url); d != nil {
 					m.pendingURL = url
 					m.pendingMirrors = mirrors
 					m.pendingHeaders = nil
+					m.pendingOptions = types.DownloadOptions{}
 					m.pendingPath = path
 					m.pendingFilename = filename
 					m.duplicateInfo = d.Filename
@@ -959,7 +1042,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if key.Matches(msg, m.keys.Duplicate.Continue) {
 				// Continue anyway - startDownload handles unique filename generation
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingFilename, "")
+				return m.startDownloadWithOptions(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingFilename, "", m.pendingOptions)
 			}
 			if key.Matches(msg, m.keys.Duplicate.Cancel) {
 				// Cancel - don't add
@@ -991,7 +1074,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// No duplicate (or warning disabled) - add to queue
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, nil, m.pendingHeaders, m.pendingPath, m.pendingFilename, "")
+				return m.startDownloadWithOptions(m.pendingURL, nil, m.pendingHeaders, m.pendingPath, m.pendingFilename, "", m.pendingOptions)
 			}
 			if key.Matches(msg, m.keys.Extension.No) {
 				// Cancelled
@@ -1029,8 +1112,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Check if a file was selected
 			if didSelect, path := m.filepicker.DidSelectFile(msg); didSelect {
-				// Read URLs from file
-				urls, err := readURLsFromFile(path)
+				// Read downloads from file
+				entries, err := readBatchFile(path)
 				if err != nil {
 					m.addLogEntry(LogStyleError.Render("✖ Failed to read batch file: " + err.Error()))
 					// Reset filepicker and return
@@ -1041,9 +1124,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
-				// Store pending URLs and show confirmation
-				m.pendingBatchURLs = urls
-				m.batchFilePath = path
+				// Store pending downloads and show confirmation
+				m.pendingBatch = entries
+				m.batchSource = path
 
 				// Reset filepicker to directory mode
 				m.filepicker.FileAllowed = false
@@ -1066,13 +1149,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				added := 0
 				skipped := 0
-				for _, url := range m.pendingBatchURLs {
+				for _, e := range m.pendingBatch {
 					// Skip duplicate URLs
-					if m.checkForDuplicate(url) != nil {
+					if m.checkForDuplicate(e.URL) != nil {
 						skipped++
 						continue
 					}
-					m, _ = m.startDownload(url, nil, nil, path, "", "")
+					m, _ = m.startDownloadWithOptions(e.URL, e.Mirrors, e.Headers, e.OutputDir(path), e.Filename, "", e.Options)
 					added++
 				}
 
@@ -1081,14 +1164,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⬇ Added %d downloads from batch", added)))
 				}
-				m.pendingBatchURLs = nil
-				m.batchFilePath = ""
+				m.pendingBatch = nil
+				m.batchSource = ""
 				m.state = DashboardState
 				return m, nil
 			}
 			if key.Matches(msg, m.keys.BatchConfirm.Cancel) {
-				m.pendingBatchURLs = nil
-				m.batchFilePath = ""
+				m.pendingBatch = nil
+				m.batchSource = ""
 				m.state = DashboardState
 				return m, nil
 			}
@@ -13,6 +13,7 @@ import (
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/tui/components"
 )
 
 var errTest = errors.New("test error")
@@ -322,6 +323,46 @@ func TestUpdate_DownloadRemoved_NoOpWhenUnknownID(t *testing.T) {
 	}
 }
 
+func TestUpdate_SnapshotReconcilesDownloads(t *testing.T) {
+	kept := NewDownloadModel("id-1", "http://example.com/a", "a", 100)
+	kept.Speed = 42
+	gone := NewDownloadModel("id-2", "http://example.com/b", "b", 100)
+	m := RootModel{
+		downloads:   []*DownloadModel{kept, gone},
+		list:        NewDownloadList(80, 20),
+		logViewport: viewport.New(40, 5),
+	}
+	m.UpdateListItems()
+
+	updated, _ := m.Update(events.SnapshotMsg{Downloads: []types.DownloadStatus{
+		{ID: "id-1", Filename: "a", TotalSize: 100, Downloaded: 100, Status: "completed"},
+		{ID: "id-3", URL: "http://example.com/c", Filename: "c", TotalSize: 50, Downloaded: 10, Status: "paused"},
+		{ID: "id-4", Filename: "d", Status: "error", Error: "boom"},
+		{ID: "id-5", Filename: "e", TotalSize: 50, Downloaded: 20, Status: "queued"},
+	}})
+	m2 := updated.(RootModel)
+
+	if len(m2.downloads) != 4 {
+		t.Fatalf("expected 4 downloads after snapshot, got %d", len(m2.downloads))
+	}
+	if m2.downloads[0] != kept || !kept.done || kept.Downloaded != 100 || kept.Speed != 42 {
+		t.Errorf("existing download not updated in place: %+v", kept)
+	}
+	if d := m2.downloads[1]; d.ID != "id-3" || !d.paused || d.done || d.Downloaded != 10 {
+		t.Errorf("new paused download not added: %+v", d)
+	}
+	if d := m2.downloads[2]; !d.done || d.err == nil || d.err.Error() != "boom" {
+		t.Errorf("errored download not marked: %+v", d)
+	}
+	d := m2.downloads[3]
+	if !d.queued || d.paused {
+		t.Errorf("queued download not marked queued: %+v", d)
+	}
+	if got := components.DetermineStatus(d.done, d.paused, d.queued, d.err != nil, d.Speed, d.Downloaded); got != components.StatusQueued {
+		t.Errorf("queued download with progress shown as %s", got.Label())
+	}
+}
+
 func TestGenerateUniqueFilename_EmptyFilename(t *testing.T) {
 	m := &RootModel{}
 	got := m.generateUniqueFilename("/tmp", "")
@@ -402,3 +443,37 @@ func TestUpdate_DownloadRequestMsg(t *testing.T) {
 		t.Errorf("Expected no prompt state, got %v", newRoot.state)
 	}
 }
+
+func TestUpdate_URLPatternOpensBatchConfirm(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	ch := make(chan any, 100)
+	pool := download.NewWorkerPool(ch, 1)
+	m := InitialRootModel(1700, "test-version", core.NewLocalDownloadServiceWithInput(pool, ch), true)
+
+	outDir := t.TempDir()
+	m.state = InputState
+	m.focusedInput = 3
+	m.inputs[0].SetValue("https://example.com/part[08-10].bin")
+	m.inputs[2].SetValue(outDir)
+	m.inputs[3].SetValue("shard-#1.bin")
+
+	newM, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	newRoot := newM.(RootModel)
+
+	if newRoot.state != BatchConfirmState {
+		t.Fatalf("Expected BatchConfirmState, got %v", newRoot.state)
+	}
+	if len(newRoot.pendingBatch) != 3 {
+		t.Fatalf("Expected 3 pending downloads, got %d", len(newRoot.pendingBatch))
+	}
+	last := newRoot.pendingBatch[2]
+	if last.URL != "https://example.com/part10.bin" || last.Filename != "shard-10.bin" || last.OutputDir("") != outDir {
+		t.Errorf("Unexpected pending download: %+v", last)
+	}
+
+	preview := batchPreview(newRoot.pendingBatch, 2, 50)
+	want := []string{"...", "part10.bin → shard-10.bin"}
+	if len(preview) != len(want) || preview[0] != want[0] || preview[1] != want[1] {
+		t.Errorf("batchPreview = %q, want %q", preview, want)
+	}
+}
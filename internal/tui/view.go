@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/batch"
 	"github.com/surge-downloader/surge/internal/tui/components"
 	"github.com/surge-downloader/surge/internal/utils"
 
@@ -162,16 +163,17 @@ func (m RootModel) View() string {
 	}
 
 	if m.state == BatchConfirmState {
-		urlCount := len(m.pendingBatchURLs)
+		urlCount := len(m.pendingBatch)
+		preview := batchPreview(m.pendingBatch, 5, 50)
 		modal := components.ConfirmationModal{
 			Title:       "Batch Import",
 			Message:     fmt.Sprintf("Add %d downloads?", urlCount),
-			Detail:      truncateString(m.batchFilePath, 50),
+			Detail:      strings.Join(append([]string{truncateString(m.batchSource, 50), ""}, preview...), "\n"),
 			Keys:        m.keys.BatchConfirm,
 			Help:        m.help,
 			BorderColor: ColorNeonCyan,
 			Width:       60,
-			Height:      10,
+			Height:      11 + len(preview),
 		}
 		box := modal.RenderWithBtopBox(renderBtopBox, PaneTitleStyle)
 		return m.renderModalWithOverlay(box)
@@ -815,6 +817,9 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 			speedStr = "N/A"
 		}
 		etaStr = "Done"
+	} else if d.queued {
+		speedStr = "Queued"
+		etaStr = "∞"
 	} else if d.paused || d.Speed == 0 {
 		speedStr = "Paused"
 		etaStr = "∞"
@@ -927,7 +932,7 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 }
 
 func getDownloadStatus(d *DownloadModel) string {
-	status := components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded)
+	status := components.DetermineStatus(d.done, d.paused, d.queued, d.err != nil, d.Speed, d.Downloaded)
 	return status.Render()
 }
 
@@ -958,6 +963,32 @@ func (m RootModel) ComputeViewStats() ViewStats {
 	return stats
 }
 
+// batchPreview lists up to max downloads of a batch, each as the last part of
+// its URL and its output name if one is set. A longer batch shows its first
+// downloads, then "...", then its last.
+func batchPreview(entries []batch.Entry, max, width int) []string {
+	line := func(e batch.Entry) string {
+		s := e.URL
+		if i := strings.LastIndex(strings.TrimRight(s, "/"), "/"); i >= 0 && i < len(s)-1 {
+			s = s[i+1:]
+		}
+		if e.Filename != "" {
+			s += " → " + e.Filename
+		}
+		return truncateString(s, width)
+	}
+
+	var lines []string
+	for i, e := range entries {
+		if len(entries) > max && i == max-2 {
+			lines = append(lines, "...", line(entries[len(entries)-1]))
+			break
+		}
+		lines = append(lines, line(e))
+	}
+	return lines
+}
+
 func truncateString(s string, i int) string {
 	if i <= 0 {
 		return ""
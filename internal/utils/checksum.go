@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when a downloaded file does not match its expected checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checksumHashes maps aria2's checksum algorithm names to their hashes
+var checksumHashes = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha-1":   sha1.New,
+	"sha-224": sha256.New224,
+	"sha-256": sha256.New,
+	"sha-384": sha512.New384,
+	"sha-512": sha512.New,
+}
+
+// ParseChecksum splits an aria2-style checksum such as "sha-256=<hex digest>"
+// into its algorithm and lowercase digest, checking both.
+func ParseChecksum(spec string) (string, string, error) {
+	algo, digest, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	algo = strings.ToLower(strings.TrimSpace(algo))
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if !ok || digest == "" {
+		return "", "", fmt.Errorf("invalid checksum %q: expected <algorithm>=<hex digest>", spec)
+	}
+	newHash, known := checksumHashes[algo]
+	if !known {
+		return "", "", fmt.Errorf("unsupported checksum algorithm %q: use md5, sha-1, sha-224, sha-256, sha-384 or sha-512", algo)
+	}
+	if raw, err := hex.DecodeString(digest); err != nil || len(raw) != newHash().Size() {
+		return "", "", fmt.Errorf("invalid %s digest %q", algo, digest)
+	}
+	return algo, digest, nil
+}
+
+// VerifyChecksum hashes the file at path and compares it with spec
+func VerifyChecksum(path, spec string) error {
+	algo, want, err := ParseChecksum(spec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for checksum: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := checksumHashes[algo]()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("%w: %s is %s, expected %s", ErrChecksumMismatch, algo, got, want)
+	}
+	return nil
+}
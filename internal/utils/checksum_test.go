@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		spec    string
+		algo    string
+		wantErr bool
+	}{
+		{"sha-256=2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824", "sha-256", false},
+		{"md5=5d41402abc4b2a76b9719d911017c592", "md5", false},
+		{" SHA-1 = aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d ", "sha-1", false},
+		{"sha-256", "", true},
+		{"crc32=3610a686", "", true},
+		{"sha-256=abcd", "", true},
+		{"md5=zz41402abc4b2a76b9719d911017c592", "", true},
+	}
+	for _, tt := range tests {
+		algo, digest, err := ParseChecksum(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChecksum(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (algo != tt.algo || digest != strings.ToLower(digest)) {
+			t.Errorf("ParseChecksum(%q) = %q, %q", tt.spec, algo, digest)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyChecksum(path, "sha-256=2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"); err != nil {
+		t.Errorf("Expected a match, got %v", err)
+	}
+	err := VerifyChecksum(path, "md5=00000000000000000000000000000000")
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// existingAncestor walks up from path until it finds an entry that exists.
+// Destination files usually don't exist yet when free space is checked, but
+// their parent directory (or one of its ancestors) lives on the same filesystem.
+func existingAncestor(path string) string {
+	path = EnsureAbsPath(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAvailableDiskSpace_NonExistentPath(t *testing.T) {
+	dir := t.TempDir()
+
+	avail, err := AvailableDiskSpace(filepath.Join(dir, "missing", "nested", "file.bin"))
+	if err != nil {
+		t.Fatalf("AvailableDiskSpace failed: %v", err)
+	}
+	if avail <= 0 {
+		t.Fatalf("expected positive free space, got %d", avail)
+	}
+}
+
+func TestAllocatedSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, make([]byte, 64*1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	allocated, err := AllocatedSize(path)
+	if err != nil {
+		t.Fatalf("AllocatedSize failed: %v", err)
+	}
+	if allocated < 64*1024 {
+		t.Fatalf("expected at least 64KB allocated, got %d", allocated)
+	}
+
+	if _, err := AllocatedSize(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestExistingAncestor(t *testing.T) {
+	dir := t.TempDir()
+	got := existingAncestor(filepath.Join(dir, "a", "b", "c.txt"))
+	if got != dir {
+		t.Fatalf("expected %s, got %s", dir, got)
+	}
+}
+
+func TestFilesystemID_SameDir(t *testing.T) {
+	dir := t.TempDir()
+	a, err := FilesystemID(filepath.Join(dir, "a.bin"))
+	if err != nil {
+		t.Fatalf("FilesystemID failed: %v", err)
+	}
+	b, err := FilesystemID(filepath.Join(dir, "sub", "b.bin"))
+	if err != nil {
+		t.Fatalf("FilesystemID failed: %v", err)
+	}
+	if a != b {
+		t.Fatalf("expected same filesystem id, got %q and %q", a, b)
+	}
+}
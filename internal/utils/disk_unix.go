@@ -0,0 +1,39 @@
+//go:build !windows
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// AvailableDiskSpace returns the number of bytes available to the current user
+// on the filesystem containing path. path does not need to exist yet.
+func AvailableDiskSpace(path string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(existingAncestor(path), &st); err != nil {
+		return 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// AllocatedSize returns the number of bytes actually allocated on disk for the file at path.
+// Preallocated .surge files are sparse on most filesystems, so this is usually far
+// smaller than their apparent size until the download fills them in.
+func AllocatedSize(path string) (int64, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Blocks) * 512, nil
+}
+
+// FilesystemID returns an identifier that is equal for paths on the same filesystem.
+func FilesystemID(path string) (string, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(existingAncestor(path), &st); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", uint64(st.Dev)), nil
+}
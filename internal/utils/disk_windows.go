@@ -0,0 +1,41 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// AvailableDiskSpace returns the number of bytes available to the current user
+// on the volume containing path. path does not need to exist yet.
+func AvailableDiskSpace(path string) (int64, error) {
+	dir, err := windows.UTF16PtrFromString(existingAncestor(path))
+	if err != nil {
+		return 0, err
+	}
+	var freeToCaller, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &freeToCaller, &total, &totalFree); err != nil {
+		return 0, fmt.Errorf("GetDiskFreeSpaceEx %s: %w", path, err)
+	}
+	return int64(freeToCaller), nil
+}
+
+// AllocatedSize returns the number of bytes allocated on disk for the file at path.
+// NTFS allocates the full length on Truncate, so the apparent size is used.
+func AllocatedSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// FilesystemID returns an identifier that is equal for paths on the same volume.
+func FilesystemID(path string) (string, error) {
+	return strings.ToUpper(filepath.VolumeName(EnsureAbsPath(path))), nil
+}
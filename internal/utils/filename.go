@@ -17,22 +17,40 @@ import (
 	"github.com/vfaronov/httpheader"
 )
 
+// Where DetermineFilenameSource found a filename
+const (
+	FilenameFromHeader  = "content-disposition"
+	FilenameFromQuery   = "query"
+	FilenameFromPath    = "path"
+	FilenameFromZip     = "zip-entry"
+	FilenameFromDefault = "default"
+)
+
 // DetermineFilename extracts the filename from a URL and HTTP response,
 // applying various heuristics. It returns the determined filename,
 // a new io.Reader that includes any sniffed header bytes, and an error.
 func DetermineFilename(rawurl string, resp *http.Response, verbose bool) (string, io.Reader, error) {
+	filename, _, body, err := DetermineFilenameSource(rawurl, resp, verbose)
+	return filename, body, err
+}
+
+// DetermineFilenameSource is DetermineFilename that also reports where the
+// name came from, as one of the FilenameFrom constants
+func DetermineFilenameSource(rawurl string, resp *http.Response, verbose bool) (string, string, io.Reader, error) {
 	parsed, err := url.Parse(rawurl)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 
 	// Changing flow to determine candidate filename first
 
 	var candidate string
+	source := FilenameFromPath
 
 	// 1. Content-Disposition
 	if _, name, err := httpheader.ContentDisposition(resp.Header); err == nil && name != "" {
 		candidate = name
+		source = FilenameFromHeader
 		if verbose {
 			fmt.Fprintf(os.Stderr, "Filename from Content-Disposition: %s\n", candidate)
 		}
@@ -43,11 +61,13 @@ func DetermineFilename(rawurl string, resp *http.Response, verbose bool) (string
 		q := parsed.Query()
 		if name := q.Get("filename"); name != "" {
 			candidate = name
+			source = FilenameFromQuery
 			if verbose {
 				fmt.Fprintf(os.Stderr, "Filename from query param 'filename': %s\n", candidate)
 			}
 		} else if name := q.Get("file"); name != "" {
 			candidate = name
+			source = FilenameFromQuery
 			if verbose {
 				fmt.Fprintf(os.Stderr, "Filename from query param 'file': %s\n", candidate)
 			}
@@ -67,7 +87,7 @@ func DetermineFilename(rawurl string, resp *http.Response, verbose bool) (string
 		if rerr == io.ErrUnexpectedEOF || rerr == io.EOF {
 			header = header[:n]
 		} else {
-			return "", nil, fmt.Errorf("reading header: %w", rerr)
+			return "", "", nil, fmt.Errorf("reading header: %w", rerr)
 		}
 	} else {
 		header = header[:n]
@@ -92,6 +112,7 @@ func DetermineFilename(rawurl string, resp *http.Response, verbose bool) (string
 			zipName := string(header[start:end])
 			if zipName != "" {
 				filename = filepath.Base(zipName)
+				source = FilenameFromZip
 				if verbose {
 					fmt.Fprintln(os.Stderr, "ZIP internal filename:", zipName)
 				}
@@ -112,12 +133,13 @@ func DetermineFilename(rawurl string, resp *http.Response, verbose bool) (string
 
 	if filename == "" || filename == "." || filename == "/" {
 		filename = "download.bin"
+		source = FilenameFromDefault
 		if verbose {
 			fmt.Fprintln(os.Stderr, "Falling back to default filename: download.bin")
 		}
 	}
 
-	return filename, body, nil
+	return filename, source, body, nil
 }
 
 var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
@@ -142,3 +142,28 @@ func TestDetermineFilename_PriorityOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineFilenameSource(t *testing.T) {
+	tests := []struct {
+		url    string
+		header http.Header
+		name   string
+		source string
+	}{
+		{"https://example.com/a/file.iso", http.Header{}, "file.iso", FilenameFromPath},
+		{"https://example.com/get?filename=report.pdf", http.Header{}, "report.pdf", FilenameFromQuery},
+		{"https://example.com/get?id=1", http.Header{"Content-Disposition": {`attachment; filename="data.csv"`}}, "data.csv", FilenameFromHeader},
+		{"", http.Header{}, "download.bin", FilenameFromDefault},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: tt.header, Body: io.NopCloser(bytes.NewReader([]byte("plain text")))}
+		name, source, _, err := DetermineFilenameSource(tt.url, resp, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.url, err)
+		}
+		if name != tt.name || source != tt.source {
+			t.Errorf("%s: got %q from %s, want %q from %s", tt.url, name, source, tt.name, tt.source)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"path/filepath"
+	"strings"
 )
 
 // EnsureAbsPath takes a clean path and forces it to be absolute.
@@ -16,3 +17,28 @@ func EnsureAbsPath(path string) string {
 	}
 	return path
 }
+
+// ResolvePath returns path made absolute with symlinks resolved. Components
+// that do not exist yet are kept as given on top of the deepest existing
+// ancestor, so a directory about to be created resolves where it will land.
+func ResolvePath(path string) string {
+	path = EnsureAbsPath(path)
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+	}
+}
+
+// PathWithin reports whether path is root or inside it. Both are compared
+// lexically, so resolve them first when symlinks matter.
+func PathWithin(path, root string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
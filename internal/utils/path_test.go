@@ -48,3 +48,55 @@ func TestEnsureAbsPath(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvePath(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(base, "real")
+	if err := os.Mkdir(real, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(base, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"existing dir", real, real},
+		{"symlink", link, real},
+		{"missing child of symlink", filepath.Join(link, "a", "b"), filepath.Join(real, "a", "b")},
+		{"missing child", filepath.Join(base, "new"), filepath.Join(base, "new")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolvePath(tt.in); got != tt.want {
+				t.Errorf("ResolvePath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathWithin(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "srv", "downloads")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{root, true},
+		{filepath.Join(root, "movies"), true},
+		{filepath.Join(root, "..", "other"), false},
+		{root + "-evil", false},
+		{filepath.Join(string(filepath.Separator), "etc"), false},
+	}
+	for _, tt := range tests {
+		if got := PathWithin(tt.path, root); got != tt.want {
+			t.Errorf("PathWithin(%q, %q) = %v, want %v", tt.path, root, got, tt.want)
+		}
+	}
+}
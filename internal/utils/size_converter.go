@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 // ConvertBytesToHumanReadable converts a given number of bytes into a human-readable format (e.g., KB, MB, GB).
@@ -20,3 +22,50 @@ func ConvertBytesToHumanReadable(bytes int64) string {
 	pre := "KMGTPE"[exp-1]
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/math.Pow(unit, float64(exp)), pre)
 }
+
+// sizeUnits maps accepted size suffixes to their multiplier (binary, matching ConvertBytesToHumanReadable)
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a human-readable size such as "512", "100MB" or "1.5G" into bytes.
+// Units are case-insensitive and binary (1 KB = 1024 bytes).
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+
+	bytes := value * float64(unit)
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(bytes), nil
+}
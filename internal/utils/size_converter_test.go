@@ -84,3 +84,37 @@ func TestConvertBytesToHumanReadable_BoundaryValues(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"100B", 100, false},
+		{"4k", 4 * 1024, false},
+		{"100MB", 100 * 1024 * 1024, false},
+		{"100 MiB", 100 * 1024 * 1024, false},
+		{"1.5G", int64(1.5 * 1024 * 1024 * 1024), false},
+		{"2TB", 2 * 1024 * 1024 * 1024 * 1024, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-5", 0, true},
+		{"10XB", 0, true},
+		{"1.2.3", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) err = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}